@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/service"
+	"gotask/task4/storage"
+)
+
+// runCommand 执行命令行子命令（执行完退出，不启动服务器）：
+//
+//	import -file blog.zip -author admin [-map wp_login=username,...] [-format markdown|wxr] [-dry-run]
+//	export -out backup.zip
+//	restore -file backup.zip
+//	promote -user alice -role admin
+func runCommand(db *gorm.DB, args []string) error {
+	switch args[0] {
+	case "import":
+		return runImport(db, args[1:])
+	case "export":
+		return runExport(db, args[1:])
+	case "restore":
+		return runRestore(db, args[1:])
+	case "promote":
+		return runPromote(db, args[1:])
+	}
+	return fmt.Errorf("未知命令: %s", args[0])
+}
+
+// runImport 导入 Markdown 压缩包或 WordPress WXR 文件，输出导入报告
+func runImport(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "导入文件（Markdown zip 压缩包或 WXR）")
+	author := fs.String("author", "", "未映射的来源作者使用的作者（用户名）")
+	authorMap := fs.String("map", "", "作者映射，逗号分隔的 来源登录名=本站用户名")
+	format := fs.String("format", "", "导入格式：markdown/wxr（默认按文件内容识别）")
+	dryRun := fs.Bool("dry-run", false, "试运行，只输出报告不写入")
+	fs.Parse(args)
+	if *file == "" || *author == "" {
+		fs.Usage()
+		return fmt.Errorf("缺少 -file 或 -author 参数")
+	}
+
+	var user model.User
+	if err := db.Where("username = ?", *author).First(&user).Error; err != nil {
+		return fmt.Errorf("作者 %s 不存在", *author)
+	}
+	var pairs []string
+	if *authorMap != "" {
+		pairs = strings.Split(*authorMap, ",")
+	}
+	authors, err := service.ParseAuthorMap(pairs)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	report, err := service.NewImportService(db).Import(service.Operator{UserID: user.ID, IP: "cli"}, data, service.ImportOptions{
+		Format:          *format,
+		DryRun:          *dryRun,
+		DefaultAuthorID: user.ID,
+		AuthorMap:       authors,
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
+
+// runExport 导出站点完整备份（所有用户、文章、评论及其关联数据和附件），输出备份清单
+func runExport(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "", "备份文件路径（zip）")
+	fs.Parse(args)
+	if *out == "" {
+		fs.Usage()
+		return fmt.Errorf("缺少 -out 参数")
+	}
+
+	store, err := storage.New(config.Cfg.StorageConfig)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	manifest, err := service.NewBackupService(db, store).Export(context.Background(), f)
+	if err != nil {
+		f.Close()
+		os.Remove(*out)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return printJSON(manifest)
+}
+
+// runRestore 把备份恢复到空数据库（保留原有的ID和时间戳），输出备份清单
+func runRestore(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := fs.String("file", "", "备份文件路径（由 export 命令生成）")
+	fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("缺少 -file 参数")
+	}
+
+	store, err := storage.New(config.Cfg.StorageConfig)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	manifest, err := service.NewBackupService(db, store).Restore(context.Background(), f, info.Size())
+	if err != nil {
+		return err
+	}
+	return printJSON(manifest)
+}
+
+// runPromote 修改用户角色（部署后设置第一个管理员），操作记入审计日志
+func runPromote(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("promote", flag.ExitOnError)
+	username := fs.String("user", "", "用户名")
+	role := fs.String("role", model.RoleAdmin, "角色：user/moderator/admin")
+	fs.Parse(args)
+	if *username == "" {
+		fs.Usage()
+		return fmt.Errorf("缺少 -user 参数")
+	}
+
+	adminService := service.NewAdminService(db, service.NewSessionService(db))
+	if err := adminService.PromoteByUsername(*username, *role); err != nil {
+		return err
+	}
+	fmt.Printf("已将用户 %s 的角色设置为 %s\n", *username, *role)
+	return nil
+}
+
+// printJSON 以缩进格式输出到标准输出
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -10,10 +11,24 @@ import (
 
 // 配置结构体（与 YAML 文件结构对应）
 type Config struct {
-	ServerConfig ServerConfig `mapstructure:"server"`
-	DBConfig     DBConfig     `mapstructure:"database"`
-	JWTConfig    JWTConfig    `mapstructure:"jwt"`
-	LogLevel     string       `mapstructure:"logLevel"` // 日志级别：debug/info/warn/error
+	ServerConfig     ServerConfig     `mapstructure:"server"`
+	DBConfig         DBConfig         `mapstructure:"database"`
+	JWTConfig        JWTConfig        `mapstructure:"jwt"`
+	OIDCConfig       OIDCConfig       `mapstructure:"oidc"`
+	AccountConfig    AccountConfig    `mapstructure:"account"`
+	AdminConfig      AdminConfig      `mapstructure:"admin"`
+	ModerationConfig ModerationConfig `mapstructure:"moderation"`
+	StreamConfig     StreamConfig     `mapstructure:"stream"`
+	CollabConfig     CollabConfig     `mapstructure:"collab"`
+	WebhookConfig    WebhookConfig    `mapstructure:"webhook"`
+	OutboxConfig     OutboxConfig     `mapstructure:"outbox"`
+	StorageConfig    StorageConfig    `mapstructure:"storage"`
+	ImageConfig      ImageConfig      `mapstructure:"image"`
+	RelatedConfig    RelatedConfig    `mapstructure:"related"`
+	ImportConfig     ImportConfig     `mapstructure:"import"`
+	SitemapConfig    SitemapConfig    `mapstructure:"sitemap"`
+	StatsConfig      StatsConfig      `mapstructure:"stats"`
+	LogLevel         string           `mapstructure:"logLevel"` // 日志级别：debug/info/warn/error
 }
 
 // 服务器配置
@@ -36,9 +51,140 @@ type DBConfig struct {
 
 // JWT 配置
 type JWTConfig struct {
-	SecretKey      string        `mapstructure:"secretKey"`
-	ExpirationTime string        `mapstructure:"expirationTime"`
-	ExpireDuration time.Duration // 解析后的时间（内部使用）
+	SecretKey         string        `mapstructure:"secretKey"`         // HS256 签名密钥
+	ExpirationTime    string        `mapstructure:"expirationTime"`    // 令牌有效期（支持 h/m/s）
+	SigningMethod     string        `mapstructure:"signingMethod"`     // 签名算法：HS256/RS256/EdDSA
+	KeyDir            string        `mapstructure:"keyDir"`            // 非对称密钥存放目录（PEM 文件）
+	RotationInterval  time.Duration `mapstructure:"rotationInterval"`  // 签名密钥轮换周期
+	KeyReloadInterval time.Duration `mapstructure:"keyReloadInterval"` // 重新加载密钥目录的间隔（新密钥提前发布的时间依此计算）
+	ExpireDuration    time.Duration // 解析后的时间（内部使用）
+}
+
+// OIDC 登录配置（公司统一身份认证）
+type OIDCConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Issuer       string   `mapstructure:"issuer"` // 身份提供方地址（用于发现 /.well-known/openid-configuration）
+	ClientID     string   `mapstructure:"clientId"`
+	ClientSecret string   `mapstructure:"clientSecret"`
+	RedirectURL  string   `mapstructure:"redirectUrl"` // 回调地址，需与身份提供方登记的一致
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// 账号配置
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `mapstructure:"deletionGracePeriod"` // 注销宽限期（期间可撤销）
+}
+
+// 管理后台配置
+type AdminConfig struct {
+	ImpersonationTTL time.Duration `mapstructure:"impersonationTTL"` // 代登录 token 有效期
+	PasswordResetTTL time.Duration `mapstructure:"passwordResetTTL"` // 强制重置密码令牌有效期
+}
+
+// 评论审核规则配置（命中任一规则的评论进入待审核队列）
+type ModerationConfig struct {
+	MaxLinks        int           `mapstructure:"maxLinks"`        // 单条评论允许的最大链接数
+	BlockedWords    []string      `mapstructure:"blockedWords"`    // 屏蔽词（不区分大小写）
+	NewAccountAge   time.Duration `mapstructure:"newAccountAge"`   // 注册时间短于该值视为新账号，新账号评论含链接即待审核
+	DuplicateWindow time.Duration `mapstructure:"duplicateWindow"` // 同一用户在该时间内发布相同内容视为重复
+	ReportThreshold int           `mapstructure:"reportThreshold"` // 内容被不同用户举报达到该次数后自动隐藏
+}
+
+// 实时推送（SSE）配置
+type StreamConfig struct {
+	BufferSize        int           `mapstructure:"bufferSize"`        // 每个订阅者的待发送事件缓冲数，写满时断开慢连接
+	HistorySize       int           `mapstructure:"historySize"`       // 保留的最近事件数（断线重连时按 Last-Event-ID 补发）
+	HeartbeatInterval time.Duration `mapstructure:"heartbeatInterval"` // 心跳间隔（防止代理断开空闲连接）
+}
+
+// 实时协作（WebSocket）配置
+type CollabConfig struct {
+	SendBuffer     int           `mapstructure:"sendBuffer"`     // 每个连接的待发送消息缓冲数，写满时断开慢连接
+	MaxMessageSize int64         `mapstructure:"maxMessageSize"` // 客户端单条消息最大字节数
+	PingInterval   time.Duration `mapstructure:"pingInterval"`   // 心跳间隔，超过两个间隔未收到 pong 视为断线
+	AllowedOrigins []string      `mapstructure:"allowedOrigins"` // 允许跨域连接的来源（为空时只允许同源）
+}
+
+// Webhook 投递配置
+type WebhookConfig struct {
+	Timeout        time.Duration `mapstructure:"timeout"`        // 单次请求超时
+	MaxAttempts    int           `mapstructure:"maxAttempts"`    // 最大投递次数（含首次），超过后标记为失败
+	RetryBaseDelay time.Duration `mapstructure:"retryBaseDelay"` // 首次重试间隔，之后按指数退避
+	RetryMaxDelay  time.Duration `mapstructure:"retryMaxDelay"`  // 重试间隔上限
+	PollInterval   time.Duration `mapstructure:"pollInterval"`   // 投递队列轮询间隔
+	BatchSize      int           `mapstructure:"batchSize"`      // 每次轮询最多投递的条数
+
+	AllowPrivateNetworks bool `mapstructure:"allowPrivateNetworks"` // 允许投递到内网地址（仅用于本地联调）
+}
+
+// 事务发件箱转发配置
+type OutboxConfig struct {
+	PollInterval   time.Duration `mapstructure:"pollInterval"`   // 轮询间隔
+	BatchSize      int           `mapstructure:"batchSize"`      // 每次轮询最多转发的事件数
+	RetryBaseDelay time.Duration `mapstructure:"retryBaseDelay"` // 订阅者处理失败后的首次重试间隔，之后按指数退避
+	RetryMaxDelay  time.Duration `mapstructure:"retryMaxDelay"`  // 重试间隔上限
+	MaxAttempts    int           `mapstructure:"maxAttempts"`    // 最多转发次数，全部失败后标记为失败，等待管理员重新投递
+	Retention      time.Duration `mapstructure:"retention"`      // 已转发事件的保留时间
+}
+
+// 附件存储配置
+type StorageConfig struct {
+	Driver       string        `mapstructure:"driver"`       // 存储类型：local/s3
+	LocalDir     string        `mapstructure:"localDir"`     // 本地存储目录（local 使用）
+	MaxSize      int64         `mapstructure:"maxSize"`      // 单个文件最大字节数
+	AllowedTypes []string      `mapstructure:"allowedTypes"` // 允许的文件类型（按内容识别的 MIME 类型）
+	OrphanTTL    time.Duration `mapstructure:"orphanTTL"`    // 上传后超过该时间仍未关联文章的附件会被清理
+	S3           S3Config      `mapstructure:"s3"`
+}
+
+// S3 兼容对象存储配置
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`  // 服务地址（如 https://s3.amazonaws.com、http://127.0.0.1:9000）
+	Region    string `mapstructure:"region"`    // 区域
+	Bucket    string `mapstructure:"bucket"`    // 存储桶
+	AccessKey string `mapstructure:"accessKey"` // 访问密钥ID
+	SecretKey string `mapstructure:"secretKey"` // 访问密钥
+	PathStyle bool   `mapstructure:"pathStyle"` // 使用路径风格地址（MinIO 等需要开启）
+}
+
+// 图片缩略图配置
+type ImageConfig struct {
+	Widths      []int    `mapstructure:"widths"`      // 生成的缩略图宽度（按宽度等比缩放，不放大）
+	Formats     []string `mapstructure:"formats"`     // 缩略图格式：jpeg/png/webp（WebP 为无损编码）
+	Quality     int      `mapstructure:"quality"`     // JPEG 质量（1-100）
+	Workers     int      `mapstructure:"workers"`     // 后台生成缩略图的并发数
+	QueueSize   int      `mapstructure:"queueSize"`   // 待生成队列长度（队列满时跳过，访问时再按需生成）
+	MaxPixels   int      `mapstructure:"maxPixels"`   // 允许处理的最大像素数（防止解压炸弹）
+	AvatarSizes []int    `mapstructure:"avatarSizes"` // 头像尺寸（正方形边长），第一个为默认尺寸
+}
+
+// 相关文章推荐配置
+type RelatedConfig struct {
+	Limit        int           `mapstructure:"limit"`        // 每篇文章推荐的相关文章数
+	TTL          time.Duration `mapstructure:"ttl"`          // 缓存有效期（到期后由后台任务重新计算，使新文章也能被推荐）
+	CorpusSize   int           `mapstructure:"corpusSize"`   // 计算正文相似度时参与比较的最近文章数
+	PollInterval time.Duration `mapstructure:"pollInterval"` // 后台重新计算过期缓存的间隔
+	BatchSize    int           `mapstructure:"batchSize"`    // 每次最多重新计算的文章数
+}
+
+// 批量导入配置
+type ImportConfig struct {
+	MaxSize         int64 `mapstructure:"maxSize"`         // 上传的导入文件最大字节数（命令行导入不限制）
+	MaxUncompressed int64 `mapstructure:"maxUncompressed"` // 压缩包解压后的总字节数上限（命令行导入同样限制）
+}
+
+// 站点地图配置
+type SitemapConfig struct {
+	BaseURL      string        `mapstructure:"baseUrl"`      // 站点地址（sitemap 中的地址必须是完整 URL）
+	PostURL      string        `mapstructure:"postUrl"`      // 文章页面路径，%d 为文章ID
+	AuthorURL    string        `mapstructure:"authorUrl"`    // 作者主页路径，%d 为用户ID
+	ChunkSize    int           `mapstructure:"chunkSize"`    // 每个 sitemap 文件的最大地址数（协议上限 50000）
+	PollInterval time.Duration `mapstructure:"pollInterval"` // 重新生成过期分片的间隔
+}
+
+// 浏览统计配置
+type StatsConfig struct {
+	ViewWindow time.Duration `mapstructure:"viewWindow"` // 同一访客在该时间内重复浏览同一文章只计一次
 }
 
 // 全局配置实例
@@ -47,7 +193,7 @@ var Cfg Config
 func Init() error {
 	// 读取 YAML 文件内容
 	viper.SetConfigFile("task4/config.yaml")
-	viper.SetConfigType("yaml")   // 配置文件类型
+	viper.SetConfigType("yaml") // 配置文件类型
 	if err := viper.ReadInConfig(); err != nil {
 		return err
 	}
@@ -83,16 +229,257 @@ func Init() error {
 		// 为空设置默认值 20
 		Cfg.DBConfig.MaxOpenConns = 20
 	}
-	if Cfg.JWTConfig.SecretKey == "" {
-		return errors.New("JWT 密钥不能为空")
+	if Cfg.JWTConfig.SigningMethod == "" {
+		// 为空设置默认值 HS256（兼容旧配置）
+		Cfg.JWTConfig.SigningMethod = "HS256"
+	}
+	switch Cfg.JWTConfig.SigningMethod {
+	case "HS256":
+		if Cfg.JWTConfig.SecretKey == "" {
+			return errors.New("JWT 密钥不能为空")
+		}
+	case "RS256", "EdDSA":
+		if Cfg.JWTConfig.KeyDir == "" {
+			// 为空设置默认值 task4/keys
+			Cfg.JWTConfig.KeyDir = "task4/keys"
+		}
+		if Cfg.JWTConfig.RotationInterval == 0 {
+			// 为空设置默认值 30天
+			Cfg.JWTConfig.RotationInterval = 720 * time.Hour
+		}
+	default:
+		return fmt.Errorf("不支持的 JWT 签名算法: %s", Cfg.JWTConfig.SigningMethod)
+	}
+	if Cfg.JWTConfig.KeyReloadInterval == 0 {
+		// 为空设置默认值 10分钟
+		Cfg.JWTConfig.KeyReloadInterval = 10 * time.Minute
 	}
 	if Cfg.JWTConfig.ExpirationTime == "" {
 		// 为空设置默认值 24小时
 		Cfg.JWTConfig.ExpirationTime = "24h"
 	}
+	if Cfg.OIDCConfig.Enabled {
+		if Cfg.OIDCConfig.Issuer == "" || Cfg.OIDCConfig.ClientID == "" || Cfg.OIDCConfig.RedirectURL == "" {
+			return errors.New("OIDC issuer/clientId/redirectUrl 不能为空")
+		}
+		if len(Cfg.OIDCConfig.Scopes) == 0 {
+			Cfg.OIDCConfig.Scopes = []string{"openid", "profile", "email"}
+		}
+	}
+	if Cfg.AccountConfig.DeletionGracePeriod == 0 {
+		// 为空设置默认值 30天
+		Cfg.AccountConfig.DeletionGracePeriod = 720 * time.Hour
+	}
+	if Cfg.AdminConfig.ImpersonationTTL == 0 {
+		// 为空设置默认值 1小时
+		Cfg.AdminConfig.ImpersonationTTL = time.Hour
+	}
+	if Cfg.AdminConfig.PasswordResetTTL == 0 {
+		// 为空设置默认值 3天
+		Cfg.AdminConfig.PasswordResetTTL = 72 * time.Hour
+	}
+	if Cfg.ModerationConfig.MaxLinks == 0 {
+		// 为空设置默认值 2
+		Cfg.ModerationConfig.MaxLinks = 2
+	}
+	if Cfg.ModerationConfig.NewAccountAge == 0 {
+		// 为空设置默认值 24小时
+		Cfg.ModerationConfig.NewAccountAge = 24 * time.Hour
+	}
+	if Cfg.ModerationConfig.DuplicateWindow == 0 {
+		// 为空设置默认值 24小时
+		Cfg.ModerationConfig.DuplicateWindow = 24 * time.Hour
+	}
+	if Cfg.ModerationConfig.ReportThreshold == 0 {
+		// 为空设置默认值 5
+		Cfg.ModerationConfig.ReportThreshold = 5
+	}
+	if Cfg.StreamConfig.BufferSize == 0 {
+		// 为空设置默认值 64
+		Cfg.StreamConfig.BufferSize = 64
+	}
+	if Cfg.StreamConfig.HistorySize == 0 {
+		// 为空设置默认值 1000
+		Cfg.StreamConfig.HistorySize = 1000
+	}
+	if Cfg.StreamConfig.HeartbeatInterval == 0 {
+		// 为空设置默认值 15秒
+		Cfg.StreamConfig.HeartbeatInterval = 15 * time.Second
+	}
+	if Cfg.CollabConfig.SendBuffer == 0 {
+		// 为空设置默认值 32
+		Cfg.CollabConfig.SendBuffer = 32
+	}
+	if Cfg.CollabConfig.MaxMessageSize == 0 {
+		// 为空设置默认值 16KB
+		Cfg.CollabConfig.MaxMessageSize = 16 << 10
+	}
+	if Cfg.CollabConfig.PingInterval == 0 {
+		// 为空设置默认值 30秒
+		Cfg.CollabConfig.PingInterval = 30 * time.Second
+	}
+	if Cfg.WebhookConfig.Timeout == 0 {
+		// 为空设置默认值 10秒
+		Cfg.WebhookConfig.Timeout = 10 * time.Second
+	}
+	if Cfg.WebhookConfig.MaxAttempts == 0 {
+		// 为空设置默认值 8
+		Cfg.WebhookConfig.MaxAttempts = 8
+	}
+	if Cfg.WebhookConfig.RetryBaseDelay == 0 {
+		// 为空设置默认值 30秒
+		Cfg.WebhookConfig.RetryBaseDelay = 30 * time.Second
+	}
+	if Cfg.WebhookConfig.RetryMaxDelay == 0 {
+		// 为空设置默认值 6小时
+		Cfg.WebhookConfig.RetryMaxDelay = 6 * time.Hour
+	}
+	if Cfg.WebhookConfig.PollInterval == 0 {
+		// 为空设置默认值 5秒
+		Cfg.WebhookConfig.PollInterval = 5 * time.Second
+	}
+	if Cfg.WebhookConfig.BatchSize == 0 {
+		// 为空设置默认值 50
+		Cfg.WebhookConfig.BatchSize = 50
+	}
+	if Cfg.OutboxConfig.PollInterval == 0 {
+		// 为空设置默认值 1秒
+		Cfg.OutboxConfig.PollInterval = time.Second
+	}
+	if Cfg.OutboxConfig.BatchSize == 0 {
+		// 为空设置默认值 100
+		Cfg.OutboxConfig.BatchSize = 100
+	}
+	if Cfg.OutboxConfig.RetryBaseDelay == 0 {
+		// 为空设置默认值 5秒
+		Cfg.OutboxConfig.RetryBaseDelay = 5 * time.Second
+	}
+	if Cfg.OutboxConfig.RetryMaxDelay == 0 {
+		// 为空设置默认值 10分钟
+		Cfg.OutboxConfig.RetryMaxDelay = 10 * time.Minute
+	}
+	if Cfg.OutboxConfig.MaxAttempts == 0 {
+		// 为空设置默认值 10
+		Cfg.OutboxConfig.MaxAttempts = 10
+	}
+	if Cfg.OutboxConfig.Retention == 0 {
+		// 为空设置默认值 7天
+		Cfg.OutboxConfig.Retention = 168 * time.Hour
+	}
+	if Cfg.StorageConfig.Driver == "" {
+		// 为空设置默认值 local
+		Cfg.StorageConfig.Driver = "local"
+	}
+	if Cfg.StorageConfig.LocalDir == "" {
+		// 为空设置默认值 task4/uploads
+		Cfg.StorageConfig.LocalDir = "task4/uploads"
+	}
+	if Cfg.StorageConfig.MaxSize == 0 {
+		// 为空设置默认值 10MB
+		Cfg.StorageConfig.MaxSize = 10 << 20
+	}
+	if len(Cfg.StorageConfig.AllowedTypes) == 0 {
+		Cfg.StorageConfig.AllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}
+	}
+	if Cfg.StorageConfig.OrphanTTL == 0 {
+		// 为空设置默认值 24小时
+		Cfg.StorageConfig.OrphanTTL = 24 * time.Hour
+	}
+	if Cfg.StorageConfig.Driver == "s3" {
+		if Cfg.StorageConfig.S3.Endpoint == "" || Cfg.StorageConfig.S3.Bucket == "" {
+			return errors.New("S3 endpoint/bucket 不能为空")
+		}
+		if Cfg.StorageConfig.S3.Region == "" {
+			// 为空设置默认值 us-east-1
+			Cfg.StorageConfig.S3.Region = "us-east-1"
+		}
+	}
+	if len(Cfg.ImageConfig.Widths) == 0 {
+		Cfg.ImageConfig.Widths = []int{160, 480, 1024}
+	}
+	if len(Cfg.ImageConfig.Formats) == 0 {
+		Cfg.ImageConfig.Formats = []string{"jpeg", "webp"}
+	}
+	for _, format := range Cfg.ImageConfig.Formats {
+		if format != "jpeg" && format != "png" && format != "webp" {
+			return fmt.Errorf("不支持的缩略图格式: %s（只支持 jpeg/png/webp）", format)
+		}
+	}
+	if Cfg.ImageConfig.Quality == 0 {
+		// 为空设置默认值 82
+		Cfg.ImageConfig.Quality = 82
+	}
+	if Cfg.ImageConfig.Workers == 0 {
+		// 为空设置默认值 2
+		Cfg.ImageConfig.Workers = 2
+	}
+	if Cfg.ImageConfig.QueueSize == 0 {
+		// 为空设置默认值 100
+		Cfg.ImageConfig.QueueSize = 100
+	}
+	if Cfg.ImageConfig.MaxPixels == 0 {
+		// 为空设置默认值 4000万像素
+		Cfg.ImageConfig.MaxPixels = 40_000_000
+	}
+	if len(Cfg.ImageConfig.AvatarSizes) == 0 {
+		Cfg.ImageConfig.AvatarSizes = []int{80, 40, 200}
+	}
+	if Cfg.RelatedConfig.Limit == 0 {
+		// 为空设置默认值 5
+		Cfg.RelatedConfig.Limit = 5
+	}
+	if Cfg.RelatedConfig.TTL == 0 {
+		// 为空设置默认值 24小时
+		Cfg.RelatedConfig.TTL = 24 * time.Hour
+	}
+	if Cfg.RelatedConfig.CorpusSize == 0 {
+		// 为空设置默认值 500
+		Cfg.RelatedConfig.CorpusSize = 500
+	}
+	if Cfg.RelatedConfig.PollInterval == 0 {
+		// 为空设置默认值 1分钟
+		Cfg.RelatedConfig.PollInterval = time.Minute
+	}
+	if Cfg.RelatedConfig.BatchSize == 0 {
+		// 为空设置默认值 50
+		Cfg.RelatedConfig.BatchSize = 50
+	}
+	if Cfg.ImportConfig.MaxSize == 0 {
+		// 为空设置默认值 100MB
+		Cfg.ImportConfig.MaxSize = 100 << 20
+	}
+	if Cfg.ImportConfig.MaxUncompressed == 0 {
+		// 为空设置默认值 500MB
+		Cfg.ImportConfig.MaxUncompressed = 500 << 20
+	}
+	if Cfg.SitemapConfig.PostURL == "" {
+		// 为空设置默认值 /posts/%d
+		Cfg.SitemapConfig.PostURL = "/posts/%d"
+	}
+	if Cfg.SitemapConfig.AuthorURL == "" {
+		// 为空设置默认值 /users/%d
+		Cfg.SitemapConfig.AuthorURL = "/users/%d"
+	}
+	if Cfg.SitemapConfig.ChunkSize <= 0 || Cfg.SitemapConfig.ChunkSize > 50000 {
+		// 为空或超过协议上限时设置为 50000
+		Cfg.SitemapConfig.ChunkSize = 50000
+	}
+	if Cfg.SitemapConfig.PollInterval == 0 {
+		// 为空设置默认值 1分钟
+		Cfg.SitemapConfig.PollInterval = time.Minute
+	}
+	if Cfg.StatsConfig.ViewWindow == 0 {
+		// 为空设置默认值 30分钟
+		Cfg.StatsConfig.ViewWindow = 30 * time.Minute
+	}
 	if Cfg.ServerConfig.Port == "" {
 		Cfg.ServerConfig.Port = ":8080"
 	}
+	if Cfg.SitemapConfig.BaseURL == "" {
+		// 为空时使用本机地址（正式部署需配置为对外的域名）
+		Cfg.SitemapConfig.BaseURL = "http://127.0.0.1" + Cfg.ServerConfig.Port
+	}
+	Cfg.SitemapConfig.BaseURL = strings.TrimRight(Cfg.SitemapConfig.BaseURL, "/")
 
 	Cfg.DBConfig.DSN = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		Cfg.DBConfig.User,
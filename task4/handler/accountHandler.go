@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// AccountHandler 账号数据导出与注销控制器
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// Export 导出个人数据（需登录，format=zip 时下载压缩包）
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	export, err := h.accountService.Export(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	if c.Query("format") != "zip" {
+		c.JSON(http.StatusOK, util.Success(export))
+		return
+	}
+
+	// 压缩包中按类别拆分为多个 JSON 文件
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"identities.json", export.Identities},
+		{"sessions.json", export.Sessions},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"following.json", export.Following},
+		{"reports.json", export.Reports},
+		{"likes.json", export.Likes},
+		{"notifications.json", export.Notifications},
+		{"webhooks.json", export.Webhooks},
+		{"attachments.json", export.Attachments},
+		{"series.json", export.Series},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+			return
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	filename := fmt.Sprintf("export-%s-%s.zip", export.Profile.Username, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// DeleteAccountRequest 注销账号请求
+type DeleteAccountRequest struct {
+	PostAction string `json:"postAction" binding:"required,oneof=delete reassign"` // 文章处理方式：delete 删除 / reassign 保留并匿名
+}
+
+// Delete 申请注销账号（宽限期后执行）
+func (h *AccountHandler) Delete(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	deletion, err := h.accountService.RequestDeletion(userID.(uint), req.PostAction)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(deletion))
+}
+
+// CancelDeletion 撤销注销申请
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	if err := h.accountService.CancelDeletion(userID.(uint)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
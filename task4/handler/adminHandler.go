@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// AdminHandler 管理后台控制器（需管理员角色）
+type AdminHandler struct {
+	adminService *service.AdminService
+}
+
+func NewAdminHandler(adminService *service.AdminService) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+// SearchUsersRequest 用户搜索参数
+type SearchUsersRequest struct {
+	Keyword string `form:"keyword"`
+	Status  string `form:"status" binding:"omitempty,oneof=active suspended banned"`
+	Role    string `form:"role" binding:"omitempty,oneof=user moderator admin"`
+}
+
+// SearchUsers 分页搜索用户
+func (h *AdminHandler) SearchUsers(c *gin.Context) {
+	var req SearchUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	param := bindPageParam(c)
+
+	query := service.UserQuery{Keyword: req.Keyword, Status: req.Status, Role: req.Role}
+	pageResult, err := h.adminService.SearchUsers(query, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// SuspendRequest 临时封禁请求
+type SuspendRequest struct {
+	Duration string `json:"duration" binding:"required"` // 封禁时长（支持 h/m/s，如 72h）
+	Reason   string `json:"reason" binding:"required,max=255"`
+}
+
+// Suspend 临时封禁用户
+func (h *AdminHandler) Suspend(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req SuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusOK, util.ErrorParam("duration 格式错误"))
+		return
+	}
+
+	if err := h.adminService.Suspend(operator(c), userID, duration, req.Reason); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// BanRequest 永久封禁请求
+type BanRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// Ban 永久封禁用户
+func (h *AdminHandler) Ban(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req BanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.adminService.Ban(operator(c), userID, req.Reason); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Unban 解除封禁
+func (h *AdminHandler) Unban(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.Unban(operator(c), userID); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// SetRoleRequest 修改角色请求
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
+// SetRole 修改用户角色
+func (h *AdminHandler) SetRole(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.adminService.SetRole(operator(c), userID, req.Role); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// ForcePasswordReset 强制重置密码，返回一次性重置令牌
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.adminService.ForcePasswordReset(operator(c), userID)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(gin.H{"resetToken": token}))
+}
+
+// ImpersonateRequest 代登录请求
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"` // 代登录原因（写入审计日志）
+}
+
+// Impersonate 签发代登录 token
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	token, err := h.adminService.Impersonate(operator(c), userID, req.Reason, clientInfo(c, "impersonation"))
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(token))
+}
+
+// AuditLogRequest 审计日志查询参数
+type AuditLogRequest struct {
+	TargetType string `form:"targetType"`
+	TargetID   uint   `form:"targetId"`
+}
+
+// ListAuditLogs 分页查询审计日志
+func (h *AdminHandler) ListAuditLogs(c *gin.Context) {
+	var req AuditLogRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	param := bindPageParam(c)
+
+	pageResult, err := h.adminService.ListAuditLogs(req.TargetType, req.TargetID, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// bindPageParam 绑定分页参数，验证失败时使用默认值（page=1，pageSize=10）
+func bindPageParam(c *gin.Context) util.PageParam {
+	var param util.PageParam
+	if err := c.ShouldBindQuery(&param); err != nil {
+		param = util.PageParam{Page: 1, PageSize: 10}
+	}
+	return param
+}
+
+// parseUserID 解析路径中的用户ID，失败时直接返回参数错误
+func parseUserID(c *gin.Context) (uint, bool) {
+	return parseParamID(c, "id")
+}
+
+// parseParamID 解析路径中的ID参数，格式错误时直接返回参数错误
+func parseParamID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInvalidParam))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// hasRole 当前用户是否为指定角色之一（代登录 token 一律视为不具备，与 RequireRole 一致）
+func hasRole(c *gin.Context, roles ...string) bool {
+	if _, impersonated := c.Get("impersonatorID"); impersonated {
+		return false
+	}
+	role := c.GetString("userRole")
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// operator 当前操作人（用于审计日志）
+func operator(c *gin.Context) service.Operator {
+	userID, _ := c.Get("userID")
+	return service.Operator{UserID: userID.(uint), IP: c.ClientIP()}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/config"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// AttachmentHandler 附件控制器
+type AttachmentHandler struct {
+	attachmentService *service.AttachmentService
+}
+
+func NewAttachmentHandler(attachmentService *service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{attachmentService: attachmentService}
+}
+
+// Upload 上传附件（需登录，multipart 表单字段 file），返回的附件ID在发布或修改文章时关联
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	// 限制请求体大小（预留 1MB 给 multipart 的其他内容）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.Cfg.StorageConfig.MaxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(c.Request.Context(), userID.(uint), header.Filename, file, header.Size)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(attachment))
+}
+
+// Download 下载附件（公开），图片直接展示，其他类型作为下载文件
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	attachment, rc, err := h.attachmentService.Open(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, util.ErrorWithMsg(err.Error()))
+		return
+	}
+	defer rc.Close()
+
+	disposition := "inline"
+	if !strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, rc, map[string]string{
+		"Content-Disposition":    fmt.Sprintf("%s; filename=%s", disposition, strconv.Quote(attachment.Filename)),
+		"Cache-Control":          "public, max-age=31536000, immutable", // key 随机生成且内容不变
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// Variant 获取图片缩略图（公开），如 /attachments/<key>/w480.jpg，可用宽度和格式见 image 配置
+func (h *AttachmentHandler) Variant(c *gin.Context) {
+	rc, contentType, err := h.attachmentService.OpenVariant(c.Request.Context(), c.Param("key"), c.Param("variant"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, util.ErrorWithMsg(err.Error()))
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, -1, contentType, rc, map[string]string{
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"image"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/config"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// AvatarHandler 头像控制器
+type AvatarHandler struct {
+	avatarService *service.AvatarService
+}
+
+func NewAvatarHandler(avatarService *service.AvatarService) *AvatarHandler {
+	return &AvatarHandler{avatarService: avatarService}
+}
+
+// AvatarCropRequest 头像裁剪区域（相对于原图左上角的正方形，不传 size 时取居中的最大正方形）
+type AvatarCropRequest struct {
+	X    int `form:"x" binding:"min=0"`
+	Y    int `form:"y" binding:"min=0"`
+	Size int `form:"size" binding:"omitempty,min=1"` // 正方形边长
+}
+
+// Upload 上传头像（需登录，multipart 表单字段 file，可选裁剪参数 x、y、size）
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.Cfg.StorageConfig.MaxSize+1<<20)
+	var req AvatarCropRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	var crop *image.Rectangle
+	if req.Size > 0 {
+		rect := image.Rect(req.X, req.Y, req.X+req.Size, req.Y+req.Size)
+		crop = &rect
+	}
+	url, err := h.avatarService.Upload(c.Request.Context(), userID.(uint), file, header.Size, crop)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(gin.H{"avatar": url}))
+}
+
+// Remove 删除头像，恢复为默认头像（需登录）
+func (h *AvatarHandler) Remove(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	if err := h.avatarService.Remove(c.Request.Context(), userID.(uint)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Get 获取用户头像（公开），size 为边长（见 image.avatarSizes 配置）
+func (h *AvatarHandler) Get(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	size, _ := strconv.Atoi(c.Query("size"))
+
+	rc, contentType, err := h.avatarService.Open(c.Request.Context(), userID, size)
+	if err != nil {
+		c.JSON(http.StatusNotFound, util.ErrorWithMsg(err.Error()))
+		return
+	}
+	defer rc.Close()
+
+	// 带版本号的地址内容不变，可长期缓存；不带版本号的地址在更换头像后会变化
+	cacheControl := "public, max-age=300"
+	if c.Query("v") != "" {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, rc, map[string]string{
+		"Cache-Control":          cacheControl,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// CategoryHandler 文章分类控制器
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{categoryService: categoryService}
+}
+
+// CategoryRequest 创建或修改分类请求
+type CategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	ParentID *uint  `json:"parentId"` // 父分类（不传表示顶级分类）
+	Sort     int    `json:"sort"`     // 同级排序（升序）
+}
+
+// Tree 查询分类树（公开）
+func (h *CategoryHandler) Tree(c *gin.Context) {
+	categories, err := h.categoryService.Tree()
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(categories))
+}
+
+// Posts 分页查询分类下的文章，包含子孙分类的文章（公开）
+func (h *CategoryHandler) Posts(c *gin.Context) {
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	param := bindPageParam(c)
+
+	pageResult, err := h.categoryService.PagePosts(id, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// Create 创建分类（管理员）
+func (h *CategoryHandler) Create(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	category, err := h.categoryService.Create(operator(c), service.CategoryInput(req))
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(category))
+}
+
+// Update 修改分类（管理员）
+func (h *CategoryHandler) Update(c *gin.Context) {
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.categoryService.Update(operator(c), id, service.CategoryInput(req)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Delete 删除分类（管理员），分类下的文章变为未分类
+func (h *CategoryHandler) Delete(c *gin.Context) {
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.categoryService.Delete(operator(c), id); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
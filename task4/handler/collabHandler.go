@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// 写消息超时时间
+const collabWriteWait = 10 * time.Second
+
+// 同一连接发送"正在输入"的最小间隔
+const collabTypingInterval = time.Second
+
+// CollabHandler 文章页实时协作（WebSocket）控制器
+type CollabHandler struct {
+	broker         service.CollabBroker
+	postService    *service.PostService
+	commentService *service.CommentService
+	upgrader       websocket.Upgrader
+}
+
+func NewCollabHandler(broker service.CollabBroker, postService *service.PostService, commentService *service.CommentService) *CollabHandler {
+	return &CollabHandler{
+		broker:         broker,
+		postService:    postService,
+		commentService: commentService,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin,
+		},
+	}
+}
+
+// CollabRequest 客户端发送的协作消息
+type CollabRequest struct {
+	Type      string `json:"type"`      // typing / comment.edit
+	CommentID uint   `json:"commentId"` // comment.edit：评论ID
+	Content   string `json:"content"`   // comment.edit：新内容
+}
+
+// Connect 加入文章的实时协作房间（需登录）：广播在线用户、正在输入和评论修改
+func (h *CollabHandler) Connect(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	if _, err := h.postService.GetByID(uint(postID)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade 已返回错误响应
+	}
+
+	room := service.PostTopic(uint(postID))
+	member := service.NewCollabMember(service.CollabUser{
+		ID:       userID.(uint),
+		Username: c.GetString("userName"),
+		Avatar:   model.AvatarURL(userID.(uint), ""),
+	}, config.Cfg.CollabConfig.SendBuffer)
+	h.broker.Join(room, member)
+	defer h.broker.Leave(room, member)
+
+	go writePump(conn, member)
+	h.readPump(conn, member, room, uint(postID))
+}
+
+// readPump 读取客户端消息，连接断开或成员被移出时返回
+func (h *CollabHandler) readPump(conn *websocket.Conn, member *service.CollabMember, room string, postID uint) {
+	defer conn.Close()
+
+	pongWait := 2 * config.Cfg.CollabConfig.PingInterval
+	conn.SetReadLimit(config.Cfg.CollabConfig.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	var lastTyping time.Time
+	for {
+		var req CollabRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		switch req.Type {
+		case service.CollabTyping:
+			if time.Since(lastTyping) < collabTypingInterval {
+				continue
+			}
+			lastTyping = time.Now()
+			h.broker.Broadcast(room, service.CollabMessage{Type: service.CollabTyping, User: &member.User}, member)
+		case service.CollabCommentEdit:
+			if req.CommentID == 0 || req.Content == "" {
+				member.Deliver(service.CollabMessage{Type: service.CollabError, Msg: util.ErrInvalidParam.Msg})
+				continue
+			}
+			comment, err := h.commentService.Update(postID, req.CommentID, member.User.ID, req.Content)
+			if err != nil {
+				member.Deliver(service.CollabMessage{Type: service.CollabError, Msg: err.Error()})
+				continue
+			}
+			msg := service.CollabMessage{Type: service.CollabCommentUpdated, User: &member.User, Comment: comment}
+			if comment.Status == model.CommentApproved {
+				h.broker.Broadcast(room, msg, nil)
+			} else {
+				member.Deliver(msg) // 修改后进入待审核，只通知本人
+			}
+		default:
+			member.Deliver(service.CollabMessage{Type: service.CollabError, Msg: "不支持的消息类型"})
+		}
+	}
+}
+
+// writePump 发送队列中的消息并定时发送心跳；成员被移出时发送关闭帧并断开连接
+func writePump(conn *websocket.Conn, member *service.CollabMember) {
+	ticker := time.NewTicker(config.Cfg.CollabConfig.PingInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-member.Send:
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-member.Done():
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+			return
+		}
+	}
+}
+
+// checkOrigin 校验握手来源：同源或在配置的允许列表中
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // 非浏览器客户端
+	}
+	for _, allowed := range config.Cfg.CollabConfig.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
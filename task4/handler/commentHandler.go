@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gotask/task4/model"
 	"gotask/task4/service"
 	"gotask/task4/util"
 )
@@ -20,21 +21,22 @@ func NewCommentHandler(commentService *service.CommentService) *CommentHandler {
 
 // CreateCommentRequest 创建评论请求
 type CreateCommentRequest struct {
-	PostID uint `json:"post_id" binding:"required"`
-	Content string `json:"content" binding:"required"`
+	PostID   uint   `json:"post_id" binding:"required"`
+	ParentID *uint  `json:"parent_id"` // 回复的评论ID（可选）
+	Content  string `json:"content" binding:"required"`
 }
 
 // Create 创建评论（需登录）
 func (h *CommentHandler) Create(c *gin.Context) {
 	userID, _ := c.Get("userID")
-	
+
 	var req CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
 		return
 	}
 
-	comment, err := h.commentService.Create(req.Content, req.PostID, userID.(uint))
+	comment, err := h.commentService.Create(req.Content, req.PostID, userID.(uint), req.ParentID)
 	if err != nil {
 		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
 		return
@@ -60,3 +62,59 @@ func (h *CommentHandler) ListByPost(c *gin.Context) {
 
 	c.JSON(http.StatusOK, util.Success(comments))
 }
+
+// ModerationQueueRequest 审核队列查询参数
+type ModerationQueueRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending rejected"` // 默认 pending
+}
+
+// ModerationQueue 查看待审核评论（审核员查看全部，作者查看自己文章下的）
+func (h *CommentHandler) ModerationQueue(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req ModerationQueueRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	if req.Status == "" {
+		req.Status = model.CommentPending
+	}
+	param := bindPageParam(c)
+
+	pageResult, err := h.commentService.ModerationQueue(userID.(uint), isModerator(c), req.Status, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// ModerateRequest 批量审核请求
+type ModerateRequest struct {
+	IDs    []uint `json:"ids" binding:"required,min=1,max=100"`
+	Action string `json:"action" binding:"required,oneof=approve reject"`
+}
+
+// Moderate 批量通过/拒绝评论
+func (h *CommentHandler) Moderate(c *gin.Context) {
+	var req ModerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	affected, err := h.commentService.Moderate(operator(c), isModerator(c), req.IDs, req.Action)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(gin.H{"affected": affected}))
+}
+
+// isModerator 当前用户是否为审核员或管理员（代登录时不具备审核权限）
+func isModerator(c *gin.Context) bool {
+	return hasRole(c, model.RoleModerator, model.RoleAdmin)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// FollowHandler 关注控制器
+type FollowHandler struct {
+	followService *service.FollowService
+}
+
+func NewFollowHandler(followService *service.FollowService) *FollowHandler {
+	return &FollowHandler{followService: followService}
+}
+
+// Follow 关注用户（需登录）
+func (h *FollowHandler) Follow(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	followeeID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.Follow(userID.(uint), followeeID); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Unfollow 取消关注（需登录）
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	followeeID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.Unfollow(userID.(uint), followeeID); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Followers 查看用户的粉丝列表（公开）
+func (h *FollowHandler) Followers(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	param := bindPageParam(c)
+
+	pageResult, err := h.followService.Followers(userID, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/config"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// ImportHandler 批量导入控制器（需管理员角色）
+type ImportHandler struct {
+	importService *service.ImportService
+}
+
+func NewImportHandler(importService *service.ImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+// ImportRequest 导入参数（multipart 表单，文件字段 file）
+type ImportRequest struct {
+	Format    string   `form:"format" binding:"omitempty,oneof=markdown wxr"` // 导入格式（不传时按文件内容识别）
+	DryRun    bool     `form:"dryRun"`                                        // 试运行，只返回报告不写入
+	AuthorMap []string `form:"authorMap"`                                     // 作者映射，每项为 来源登录名=本站用户名（可重复）
+}
+
+// Import 导入 Markdown 压缩包或 WordPress WXR 文件，未在 authorMap 中映射的来源作者的文章作者为当前管理员
+func (h *ImportHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.Cfg.ImportConfig.MaxSize+1<<20)
+	var req ImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	if header.Size > config.Cfg.ImportConfig.MaxSize {
+		c.JSON(http.StatusOK, util.Error(util.ErrFileTooLarge))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	authorMap, err := service.ParseAuthorMap(req.AuthorMap)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	op := operator(c)
+	report, err := h.importService.Import(op, data, service.ImportOptions{
+		Format:          req.Format,
+		DryRun:          req.DryRun,
+		DefaultAuthorID: op.UserID,
+		AuthorMap:       authorMap,
+	})
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(report))
+}
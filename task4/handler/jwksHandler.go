@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/util"
+)
+
+// JWKS 发布验签公钥（/.well-known/jwks.json，供其他内部服务校验本服务签发的令牌）
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(util.JWKSMaxAge.Seconds())))
+	c.JSON(http.StatusOK, util.JWKS())
+}
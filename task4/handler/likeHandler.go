@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// LikeHandler 点赞控制器
+type LikeHandler struct {
+	likeService *service.LikeService
+}
+
+func NewLikeHandler(likeService *service.LikeService) *LikeHandler {
+	return &LikeHandler{likeService: likeService}
+}
+
+// LikeRequest 点赞/取消点赞请求
+type LikeRequest struct {
+	TargetType string `json:"targetType" binding:"required,oneof=post comment"`
+	TargetID   uint   `json:"targetId" binding:"required"`
+}
+
+// Like 点赞文章或评论（需登录）
+func (h *LikeHandler) Like(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req LikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.likeService.Like(userID.(uint), req.TargetType, req.TargetID); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Unlike 取消点赞（需登录）
+func (h *LikeHandler) Unlike(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req LikeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.likeService.Unlike(userID.(uint), req.TargetType, req.TargetID); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
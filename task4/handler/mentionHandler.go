@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// MentionHandler 提及控制器
+type MentionHandler struct {
+	mentionService *service.MentionService
+}
+
+func NewMentionHandler(mentionService *service.MentionService) *MentionHandler {
+	return &MentionHandler{mentionService: mentionService}
+}
+
+// List 分页查看提及我的文章和评论（需登录）
+func (h *MentionHandler) List(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	param := bindPageParam(c)
+
+	pageResult, err := h.mentionService.ListByUser(userID.(uint), param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
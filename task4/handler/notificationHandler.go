@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// NotificationHandler 站内通知控制器
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// ListNotificationRequest 通知列表查询参数
+type ListNotificationRequest struct {
+	Unread bool `form:"unread"` // 只看未读
+}
+
+// List 分页查看我的通知（需登录）
+func (h *NotificationHandler) List(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req ListNotificationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	param := bindPageParam(c)
+
+	pageResult, err := h.notificationService.List(userID.(uint), req.Unread, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// MarkReadRequest 标记已读请求
+type MarkReadRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// MarkRead 将指定通知标记为已读（需登录）
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	affected, err := h.notificationService.MarkRead(userID.(uint), req.IDs)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(gin.H{"affected": affected}))
+}
+
+// MarkAllRead 将全部通知标记为已读（需登录）
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	affected, err := h.notificationService.MarkAllRead(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(gin.H{"affected": affected}))
+}
+
+// UnreadCount 查询未读通知数（需登录）
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	count, err := h.notificationService.UnreadCount(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(gin.H{"count": count}))
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// 保存 state 的 cookie，把登录流程绑定到发起登录的浏览器
+const (
+	oauthStateCookie = "oauth_state"
+	oauthCookiePath  = "/api/v1/oauth"
+)
+
+// OAuthHandler 外部身份登录控制器
+type OAuthHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOAuthHandler(oidcService *service.OIDCService) *OAuthHandler {
+	return &OAuthHandler{oidcService: oidcService}
+}
+
+// Login 跳转到身份提供方登录页
+func (h *OAuthHandler) Login(c *gin.Context) {
+	if !h.oidcService.Enabled() {
+		c.JSON(http.StatusOK, util.Error(util.NewErrno(util.ErrOAuthFailed, "未开启")))
+		return
+	}
+
+	authURL, state, err := h.oidcService.AuthURL(c.Request.Context(), 0)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	setStateCookie(c, state, int(service.OIDCLoginTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Link 已登录用户发起第三方账号绑定，返回授权地址由前端跳转（需登录，代登录时不可用）
+func (h *OAuthHandler) Link(c *gin.Context) {
+	if !h.oidcService.Enabled() {
+		c.JSON(http.StatusOK, util.Error(util.NewErrno(util.ErrOAuthFailed, "未开启")))
+		return
+	}
+	if _, impersonated := c.Get("impersonatorID"); impersonated {
+		c.JSON(http.StatusOK, util.Error(util.ErrNoPermission))
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	authURL, state, err := h.oidcService.AuthURL(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	setStateCookie(c, state, int(service.OIDCLoginTTL.Seconds()))
+	c.JSON(http.StatusOK, util.Success(gin.H{"url": authURL}))
+}
+
+// CallbackRequest 身份提供方回调参数
+type CallbackRequest struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// Callback 身份提供方回调，登录成功返回本站 token
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	if !h.oidcService.Enabled() {
+		c.JSON(http.StatusOK, util.Error(util.NewErrno(util.ErrOAuthFailed, "未开启")))
+		return
+	}
+
+	var req CallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	cookieState, _ := c.Cookie(oauthStateCookie)
+	setStateCookie(c, "", -1) // state 只能使用一次，回调后立即清除
+	token, err := h.oidcService.Login(c.Request.Context(), req.Code, req.State, cookieState, clientInfo(c, ""))
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(token))
+}
+
+// setStateCookie 写入（maxAge < 0 时清除）state cookie：仅回调路径可见，脚本不可读，
+// SameSite=Lax 保证从身份提供方跳转回来的顶层导航能带上
+func setStateCookie(c *gin.Context, state string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, maxAge, oauthCookiePath, "", c.Request.TLS != nil, true)
+}
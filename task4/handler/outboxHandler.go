@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// OutboxHandler 发件箱管理控制器（需管理员角色）
+type OutboxHandler struct {
+	relay *service.OutboxRelay
+}
+
+func NewOutboxHandler(relay *service.OutboxRelay) *OutboxHandler {
+	return &OutboxHandler{relay: relay}
+}
+
+// ListFailed 分页查询转发失败的事件
+func (h *OutboxHandler) ListFailed(c *gin.Context) {
+	param := bindPageParam(c)
+
+	pageResult, err := h.relay.ListFailed(param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// Replay 重新投递转发失败的事件
+func (h *OutboxHandler) Replay(c *gin.Context) {
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.relay.Replay(operator(c), id); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
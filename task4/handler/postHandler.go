@@ -11,17 +11,21 @@ import (
 
 // PostHandler 文章控制器
 type PostHandler struct {
-	postService *service.PostService
+	postService    *service.PostService
+	relatedService *service.RelatedService
 }
 
-func NewPostHandler(postService *service.PostService) *PostHandler {
-	return &PostHandler{postService: postService}
+func NewPostHandler(postService *service.PostService, relatedService *service.RelatedService) *PostHandler {
+	return &PostHandler{postService: postService, relatedService: relatedService}
 }
 
 // CreatePostRequest 创建文章请求
 type CreatePostRequest struct {
-	Title   string `json:"title" binding:"required,min=1,max=200"`
-	Content string `json:"content" binding:"required"`
+	Title         string   `json:"title" binding:"required,min=1,max=200"`
+	Content       string   `json:"content" binding:"required"`
+	CategoryID    *uint    `json:"categoryId"`                              // 分类（可选）
+	Tags          []string `json:"tags" binding:"max=10,dive,min=1,max=30"` // 标签（可选）
+	AttachmentIDs []uint   `json:"attachmentIds" binding:"max=50,unique"`   // 关联的附件（先通过上传接口上传）
 }
 
 // Create 创建文章（需登录）
@@ -34,15 +38,20 @@ func (h *PostHandler) Create(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.Create(req.Title, req.Content, userID.(uint))
+	post, err := h.postService.Create(req.Title, req.Content, userID.(uint), req.CategoryID, req.Tags, req.AttachmentIDs)
 	if err != nil {
-		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
 		return
 	}
 
 	c.JSON(http.StatusOK, util.Success(post))
 }
 
+// PostSortRequest 文章列表排序参数
+type PostSortRequest struct {
+	Sort string `form:"sort" binding:"omitempty,oneof=latest hot top_day top_week top_month comments"` // 排序方式（默认 latest）
+}
+
 // List 分页查询文章列表（公开接口）
 func (h *PostHandler) Page(c *gin.Context) {
 	// 绑定分页参数（默认page=1，pageSize=10）
@@ -51,9 +60,14 @@ func (h *PostHandler) Page(c *gin.Context) {
 		// 参数验证失败时，设置默认值
 		param = util.PageParam{Page: 1, PageSize: 10}
 	}
+	var sortReq PostSortRequest
+	if err := c.ShouldBindQuery(&sortReq); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
 
 	// 调用服务层分页查询
-	pageResult, err := h.postService.Page(param.Page, param.PageSize)
+	pageResult, err := h.postService.Page(param.Page, param.PageSize, sortReq.Sort)
 	if err != nil {
 		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
 		return
@@ -94,15 +108,35 @@ func (h *PostHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
 		return
 	}
+	h.postService.RecordView(post.ID, c.ClientIP()) // 浏览计数失败不影响查看
 
 	c.JSON(http.StatusOK, util.Success(post))
 }
 
+// Related 查看相关文章（公开，不需要权限）
+func (h *PostHandler) Related(c *gin.Context) {
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	posts, err := h.relatedService.List(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(posts))
+}
+
 // UpdatePostRequest 修改文章请求
 type UpdatePostRequest struct {
-	ID      uint   `json:"id" binding:"required"`
-	Title   string `json:"title" binding:"omitempty,min=1,max=200"`
-	Content string `json:"content" binding:"omitempty"`
+	ID            uint     `json:"id" binding:"required"`
+	Title         string   `json:"title" binding:"omitempty,min=1,max=200"`
+	Content       string   `json:"content" binding:"omitempty"`
+	CategoryID    *uint    `json:"categoryId"`                                        // 分类（不传表示不修改，0 表示取消分类）
+	Tags          []string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=30"` // 文章的全部标签（不传表示不修改）
+	AttachmentIDs []uint   `json:"attachmentIds" binding:"omitempty,max=50,unique"`   // 文章的全部附件（不传表示不修改）
 }
 
 // Update 修改文章（仅作者）
@@ -115,7 +149,38 @@ func (h *PostHandler) Update(c *gin.Context) {
 		return
 	}
 
-	if err := h.postService.Update(req.ID, req.Title, req.Content, userID.(uint)); err != nil {
+	if err := h.postService.Update(req.ID, req.Title, req.Content, userID.(uint), req.CategoryID, req.Tags, req.AttachmentIDs); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// CommentSettingsRequest 修改文章评论设置请求
+type CommentSettingsRequest struct {
+	ID              uint    `json:"id" binding:"required"`
+	Status          *string `json:"status" binding:"omitempty,oneof=open closed followers"` // 评论状态
+	AutoCloseDays   *int    `json:"autoCloseDays" binding:"omitempty,min=0"`                // 发布N天后自动关闭评论（0表示不自动关闭）
+	RequireApproval *bool   `json:"requireApproval"`                                        // 评论需审核后公开
+}
+
+// UpdateCommentSettings 修改文章评论设置（仅作者）
+func (h *PostHandler) UpdateCommentSettings(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req CommentSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	settings := service.CommentSettings{
+		Status:          req.Status,
+		AutoCloseDays:   req.AutoCloseDays,
+		RequireApproval: req.RequireApproval,
+	}
+	if err := h.postService.UpdateCommentSettings(req.ID, userID.(uint), settings); err != nil {
 		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
 		return
 	}
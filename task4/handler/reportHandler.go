@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/model"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// ReportHandler 内容举报控制器
+type ReportHandler struct {
+	reportService *service.ReportService
+}
+
+func NewReportHandler(reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// CreateReportRequest 举报请求
+type CreateReportRequest struct {
+	TargetType string `json:"targetType" binding:"required,oneof=post comment"`
+	TargetID   uint   `json:"targetId" binding:"required"`
+	Reason     string `json:"reason" binding:"required,oneof=spam abuse hate sexual illegal other"`
+	Detail     string `json:"detail" binding:"max=500"`
+}
+
+// Create 举报文章或评论（需登录）
+func (h *ReportHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	report, err := h.reportService.Create(userID.(uint), req.TargetType, req.TargetID, req.Reason, req.Detail)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(report))
+}
+
+// ReportQueueRequest 举报队列查询参数
+type ReportQueueRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=open resolved dismissed"` // 默认 open
+}
+
+// Queue 按内容聚合的举报队列（审核员）
+func (h *ReportHandler) Queue(c *gin.Context) {
+	var req ReportQueueRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	if req.Status == "" {
+		req.Status = model.ReportOpen
+	}
+	param := bindPageParam(c)
+
+	pageResult, err := h.reportService.Queue(req.Status, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// ReportTargetRequest 举报对象参数
+type ReportTargetRequest struct {
+	TargetType string `form:"targetType" json:"targetType" binding:"required,oneof=post comment"`
+	TargetID   uint   `form:"targetId" json:"targetId" binding:"required"`
+}
+
+// Detail 查看某个内容的举报明细（审核员）
+func (h *ReportHandler) Detail(c *gin.Context) {
+	var req ReportTargetRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	reports, err := h.reportService.ListByTarget(req.TargetType, req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(reports))
+}
+
+// HandleReportRequest 处理举报请求
+type HandleReportRequest struct {
+	ReportTargetRequest
+	Note string `json:"note" binding:"max=500"` // 处理备注（写入审计日志）
+}
+
+// Resolve 确认违规，内容保持隐藏（审核员）
+func (h *ReportHandler) Resolve(c *gin.Context) {
+	var req HandleReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.reportService.Resolve(operator(c), req.TargetType, req.TargetID, req.Note); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Dismiss 驳回举报，内容恢复展示（审核员）
+func (h *ReportHandler) Dismiss(c *gin.Context) {
+	var req HandleReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.reportService.Dismiss(operator(c), req.TargetType, req.TargetID, req.Note); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// SeriesHandler 文章系列控制器
+type SeriesHandler struct {
+	seriesService *service.SeriesService
+}
+
+func NewSeriesHandler(seriesService *service.SeriesService) *SeriesHandler {
+	return &SeriesHandler{seriesService: seriesService}
+}
+
+// SeriesRequest 创建或修改系列请求
+type SeriesRequest struct {
+	Title       string `json:"title" binding:"required,min=1,max=200"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+// SeriesPostsRequest 设置系列文章请求
+type SeriesPostsRequest struct {
+	PostIDs []uint `json:"postIds" binding:"max=200,unique"` // 系列中的全部文章（按顺序）
+}
+
+// Create 创建系列（需登录）
+func (h *SeriesHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	series, err := h.seriesService.Create(userID.(uint), req.Title, req.Description)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(series))
+}
+
+// Update 修改系列（仅作者）
+func (h *SeriesHandler) Update(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	var req SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.seriesService.Update(id, userID.(uint), req.Title, req.Description); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Delete 删除系列（仅作者），系列中的文章保留
+func (h *SeriesHandler) Delete(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.seriesService.Delete(id, userID.(uint)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// SetPosts 按顺序设置系列中的文章（仅作者）
+func (h *SeriesHandler) SetPosts(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	var req SeriesPostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.seriesService.SetPosts(id, userID.(uint), req.PostIDs); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Get 查看系列及其中的文章（公开）
+func (h *SeriesHandler) Get(c *gin.Context) {
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	series, err := h.seriesService.Get(id)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(series))
+}
+
+// ListByUser 查看用户的系列（公开）
+func (h *SeriesHandler) ListByUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.seriesService.ListByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(series))
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// SessionHandler 登录会话控制器
+type SessionHandler struct {
+	sessionService *service.SessionService
+}
+
+func NewSessionHandler(sessionService *service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// List 查看当前用户的登录设备（需登录）
+func (h *SessionHandler) List(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	tokenID := c.GetString("tokenID")
+
+	sessions, err := h.sessionService.ListByUser(userID.(uint), tokenID)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(sessions))
+}
+
+// Revoke 踢下线指定设备（仅本人）
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInvalidParam))
+		return
+	}
+
+	if err := h.sessionService.Revoke(uint(id), userID.(uint)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Logout 退出登录（吊销当前会话）
+func (h *SessionHandler) Logout(c *gin.Context) {
+	if err := h.sessionService.RevokeByTokenID(c.GetString("tokenID")); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// clientInfo 从请求中提取登录客户端信息
+func clientInfo(c *gin.Context, device string) service.ClientInfo {
+	return service.ClientInfo{
+		Device:    device,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// SitemapHandler 站点地图控制器
+type SitemapHandler struct {
+	sitemapService *service.SitemapService
+}
+
+func NewSitemapHandler(sitemapService *service.SitemapService) *SitemapHandler {
+	return &SitemapHandler{sitemapService: sitemapService}
+}
+
+// Index sitemap 索引（公开，供搜索引擎抓取）
+func (h *SitemapHandler) Index(c *gin.Context) {
+	data, err := h.sitemapService.Index(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+}
+
+// File sitemap 文件（公开），如 /sitemaps/posts-0.xml；抓取方按状态码判断，文件不存在时返回 404，其他错误返回 500
+func (h *SitemapHandler) File(c *gin.Context) {
+	data, err := h.sitemapService.File(c.Request.Context(), c.Param("file"))
+	if errors.Is(err, util.ErrSitemapNotExist) {
+		c.JSON(http.StatusNotFound, util.Error(util.ErrSitemapNotExist))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// StatsHandler 作者统计控制器
+type StatsHandler struct {
+	statsService *service.StatsService
+}
+
+func NewStatsHandler(statsService *service.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: statsService}
+}
+
+// StatsRequest 统计查询参数
+type StatsRequest struct {
+	Days   int  `form:"days" binding:"omitempty,min=1,max=365"` // 统计最近多少天（默认30天）
+	PostID uint `form:"postId"`                                 // 返回该文章的每日统计（可选）
+}
+
+// Mine 查询当前用户文章的浏览、点赞、评论和粉丝统计（需登录）
+func (h *StatsHandler) Mine(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req StatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 30
+	}
+
+	stats, err := h.statsService.AuthorStats(userID.(uint), req.Days, req.PostID)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(stats))
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"gotask/task4/config"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// StreamHandler 实时推送（SSE）控制器
+type StreamHandler struct {
+	hub         *service.EventHub
+	postService *service.PostService
+}
+
+func NewStreamHandler(hub *service.EventHub, postService *service.PostService) *StreamHandler {
+	return &StreamHandler{hub: hub, postService: postService}
+}
+
+// StreamRequest 订阅参数
+type StreamRequest struct {
+	PostID      uint   `form:"postId"`      // 同时订阅该文章的新评论（可选）
+	LastEventID string `form:"lastEventId"` // 断线续传的起点（优先使用 Last-Event-ID 请求头）
+}
+
+// Stream 通过 SSE 推送当前用户的新通知，以及指定文章的新评论（需登录）；
+// 断线期间的事件无法补发时先推送 reset 事件，客户端应重新拉取通知和评论
+func (h *StreamHandler) Stream(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req StreamRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		req.LastEventID = id
+	}
+
+	topics := []string{service.UserTopic(userID.(uint))}
+	if req.PostID > 0 {
+		if _, err := h.postService.GetByID(req.PostID); err != nil {
+			c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+			return
+		}
+		topics = append(topics, service.PostTopic(req.PostID))
+	}
+
+	sub := h.hub.Subscribe(topics, req.LastEventID)
+	defer h.hub.Unsubscribe(sub)
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(config.Cfg.StreamConfig.HeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done(): // 客户端断开
+			return
+		case <-sub.Done(): // 服务器关闭或客户端消费过慢
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case ev := <-sub.C:
+			if err := sse.Encode(c.Writer, sse.Event{
+				Id:    ev.ID,
+				Event: ev.Type,
+				Data:  ev.Data,
+			}); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -44,6 +44,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Device   string `json:"device" binding:"omitempty,max=100"` // 设备名称（可选，默认根据 User-Agent 识别）
 }
 
 // Login 用户登录
@@ -54,7 +55,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.userService.Login(req.Username, req.Password)
+	token, err := h.userService.Login(req.Username, req.Password, clientInfo(c, req.Device))
 	if err != nil {
 		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
 		return
@@ -62,3 +63,25 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, util.Success(token))
 }
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`                 // 管理员下发的重置令牌
+	Password string `json:"password" binding:"required,min=6,max=20"` // 新密码
+}
+
+// ResetPassword 使用重置令牌设置新密码
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.userService.ResetPassword(req.Token, req.Password); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
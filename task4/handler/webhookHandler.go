@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/model"
+	"gotask/task4/service"
+	"gotask/task4/util"
+)
+
+// WebhookHandler Webhook 控制器
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// CreateWebhookRequest 注册 Webhook 请求
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=500,startswith=http"` // 回调地址（非管理员只能使用 https）
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=* post.created post.updated comment.created"`
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// Create 注册 Webhook，只接收自己文章上的事件（需登录）
+func (h *WebhookHandler) Create(c *gin.Context) {
+	h.create(c, false)
+}
+
+// CreateGlobal 注册接收全站事件的 Webhook（管理员）
+func (h *WebhookHandler) CreateGlobal(c *gin.Context) {
+	h.create(c, true)
+}
+
+func (h *WebhookHandler) create(c *gin.Context, global bool) {
+	userID, _ := c.Get("userID")
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	admin := hasRole(c, model.RoleAdmin)
+	webhook, secret, err := h.webhookService.Create(userID.(uint), admin, global, req.URL, req.Events, req.Description)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	// 签名密钥只在创建时返回一次
+	c.JSON(http.StatusOK, util.Success(gin.H{"webhook": webhook, "secret": secret}))
+}
+
+// List 查看我注册的 Webhook（需登录）
+func (h *WebhookHandler) List(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	webhooks, err := h.webhookService.ListByUser(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, util.Error(util.ErrInternalError))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(webhooks))
+}
+
+// SetActiveRequest 启用/停用 Webhook 请求
+type SetActiveRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
+
+// SetActive 启用或停用 Webhook（需登录）
+func (h *WebhookHandler) SetActive(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	var req SetActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, util.ErrorParam(err.Error()))
+		return
+	}
+
+	if err := h.webhookService.SetActive(id, userID.(uint), *req.Active); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Delete 删除 Webhook（需登录）
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(id, userID.(uint)); err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(nil))
+}
+
+// Deliveries 分页查看 Webhook 的投递记录（需登录，响应内容只对管理员可见）
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	param := bindPageParam(c)
+
+	admin := hasRole(c, model.RoleAdmin)
+	pageResult, err := h.webhookService.Deliveries(id, userID.(uint), admin, param.Page, param.PageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(pageResult))
+}
+
+// Redeliver 以原请求体重新投递（需登录）
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(id, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, util.ErrorWithMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.Success(delivery))
+}
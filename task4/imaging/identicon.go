@@ -0,0 +1,64 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"math"
+)
+
+// identicon 网格大小（左右对称，只有左侧 3 列由哈希决定）
+const identiconGrid = 5
+
+// Identicon 根据 seed 生成确定性的默认头像：size×size，5×5 左右对称色块
+func Identicon(seed []byte, size int) *image.NRGBA {
+	sum := sha256.Sum256(seed)
+	fg := hslColor(float64(int(sum[0])<<8|int(sum[1]))/65536*360, 0.45+float64(sum[2])/255*0.2, 0.45+float64(sum[3])/255*0.15)
+	bg := color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	padding := size / 10
+	cell := float64(size-2*padding) / identiconGrid
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetNRGBA(x, y, bg)
+			col := int(float64(x-padding) / cell)
+			row := int(float64(y-padding) / cell)
+			if x < padding || y < padding || col >= identiconGrid || row >= identiconGrid {
+				continue
+			}
+			if col > identiconGrid/2 {
+				col = identiconGrid - 1 - col
+			}
+			// 每个色块用哈希的一位决定是否填充
+			bit := row*(identiconGrid/2+1) + col
+			if sum[4+bit/8]>>(bit%8)&1 == 1 {
+				img.SetNRGBA(x, y, fg)
+			}
+		}
+	}
+	return img
+}
+
+// hslColor HSL 转 RGB（h: 0-360，s/l: 0-1）
+func hslColor(h, s, l float64) color.NRGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.NRGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((b + m) * 255), A: 255}
+}
@@ -0,0 +1,69 @@
+package imaging
+
+import "testing"
+
+func TestIdenticon(t *testing.T) {
+	bg := [4]uint8{240, 240, 240, 255}
+	tests := []struct {
+		seed string
+		size int
+	}{
+		{"1", 40},
+		{"2", 80},
+		{"12345", 200},
+		{"7", 7}, // 尺寸小于网格时不能越界
+	}
+	for _, tt := range tests {
+		t.Run(tt.seed, func(t *testing.T) {
+			img := Identicon([]byte(tt.seed), tt.size)
+			if b := img.Bounds(); b.Dx() != tt.size || b.Dy() != tt.size {
+				t.Fatalf("bounds = %v, want %dx%d", b, tt.size, tt.size)
+			}
+			if again := Identicon([]byte(tt.seed), tt.size); string(again.Pix) != string(img.Pix) {
+				t.Fatal("same seed generated a different image")
+			}
+
+			padding := tt.size / 10
+			fg := 0
+			for y := 0; y < tt.size; y++ {
+				for x := 0; x < tt.size; x++ {
+					c := img.NRGBAAt(x, y)
+					px := [4]uint8{c.R, c.G, c.B, c.A}
+					if c.A != 255 {
+						t.Fatalf("pixel (%d,%d) is not opaque: %v", x, y, c)
+					}
+					// 边距只有背景色
+					if (x < padding || y < padding || x >= tt.size-padding || y >= tt.size-padding) && px != bg {
+						t.Fatalf("padding pixel (%d,%d) = %v, want background", x, y, c)
+					}
+					if px != bg {
+						fg++
+					}
+				}
+			}
+			if tt.size < 40 {
+				return
+			}
+			if fg == 0 {
+				t.Fatal("identicon has no foreground blocks")
+			}
+
+			// 色块按网格左右对称
+			cell := float64(tt.size-2*padding) / identiconGrid
+			for row := 0; row < identiconGrid; row++ {
+				for col := 0; col < identiconGrid/2; col++ {
+					y := padding + int((float64(row)+0.5)*cell)
+					left := padding + int((float64(col)+0.5)*cell)
+					right := padding + int((float64(identiconGrid-1-col)+0.5)*cell)
+					if img.NRGBAAt(left, y) != img.NRGBAAt(right, y) {
+						t.Fatalf("block (%d,%d) is not mirrored", row, col)
+					}
+				}
+			}
+		})
+	}
+
+	if string(Identicon([]byte("1"), 80).Pix) == string(Identicon([]byte("2"), 80).Pix) {
+		t.Fatal("different seeds generated the same image")
+	}
+}
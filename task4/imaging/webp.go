@@ -0,0 +1,596 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// WebP 无损编码（VP8L）：减绿变换 + 按 16x16 分块选择预测模式，残差用 LZ77 和 Huffman 编码（不使用颜色缓存）。
+// 体积大于有损编码，但不依赖 libwebp，浏览器都能解码。
+// 自行实现的原因：golang.org/x/image/webp 只有解码器，现有的编码库都通过 cgo 调用 libwebp（无法静态编译和交叉编译）；
+// 这里只实现 VP8L 的必要子集，输出由 webp_test.go 用 x/image/webp 解码后逐像素校验
+
+const (
+	webpMaxSize         = 1 << 14 // VP8L 的宽高上限
+	webpPredictorBits   = 4       // 预测模式分块大小（2^4 = 16 像素）
+	webpMaxCodeLength   = 15      // Huffman 码长上限
+	webpMaxCodeLenCode  = 7       // 码长编码的码长上限
+	webpGreenAlphabet   = 256 + 24
+	webpLiteralAlphabet = 256
+	webpDistAlphabet    = 40
+)
+
+// 码长编码的码长在码流中的写入顺序
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// 参与选择的预测模式：L、T、Average2(L, T)、Select(L, T, TL)（都不依赖右上角像素）
+var webpPredictorModes = [...]int{1, 2, 7, 11}
+
+// encodeWebP 以无损 WebP 编码图片（输出不包含任何元数据）
+func encodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width == 0 || height == 0 || width > webpMaxSize || height > webpMaxSize {
+		return errors.New("imaging: webp size out of range")
+	}
+	rgba, ok := img.(*image.NRGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) {
+		rgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	}
+
+	// 转为 ARGB 并做减绿变换
+	pix := make([]uint32, width*height)
+	opaque := true
+	for y := 0; y < height; y++ {
+		row := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < width; x++ {
+			r, g, bl, a := row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]
+			if a != 0xff {
+				opaque = false
+			}
+			pix[y*width+x] = uint32(a)<<24 | uint32(r-g)<<16 | uint32(g)<<8 | uint32(bl-g)
+		}
+	}
+	modes, residuals := webpPredict(pix, width, height)
+
+	bw := &webpBitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if opaque {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+	}
+	bw.write(0, 3) // 版本
+	bw.write(1, 1) // 变换：减绿
+	bw.write(2, 2)
+	bw.write(1, 1) // 变换：预测
+	bw.write(0, 2)
+	bw.write(webpPredictorBits-2, 3)
+	tile := 1 << webpPredictorBits
+	webpWriteImage(bw, modes, (width+tile-1)/tile, false)
+	bw.write(0, 1) // 变换结束
+	webpWriteImage(bw, residuals, width, true)
+	data := bw.bytes()
+
+	chunk := len(data) + len(data)&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+chunk))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if len(data)&1 == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// webpPredict 为每个分块选择残差最小的预测模式，返回模式图（模式写在绿色通道）和残差
+func webpPredict(pix []uint32, width, height int) ([]uint32, []uint32) {
+	tile := 1 << webpPredictorBits
+	tilesX, tilesY := (width+tile-1)/tile, (height+tile-1)/tile
+	modes := make([]uint32, tilesX*tilesY)
+	residuals := make([]uint32, len(pix))
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx*tile, ty*tile
+			x1, y1 := minInt(x0+tile, width), minInt(y0+tile, height)
+			best, bestCost := webpPredictorModes[0], -1
+			for _, mode := range webpPredictorModes {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += webpResidualCost(webpSub(pix[y*width+x], webpPredictPixel(pix, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = 0xff000000 | uint32(best)<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					residuals[y*width+x] = webpSub(pix[y*width+x], webpPredictPixel(pix, width, x, y, best))
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+// webpPredictPixel 按预测模式计算像素的预测值（首行、首列的规则与模式无关）
+func webpPredictPixel(pix []uint32, width, x, y, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return pix[x-1]
+	case x == 0:
+		return pix[(y-1)*width]
+	}
+	l, t, tl := pix[y*width+x-1], pix[(y-1)*width+x], pix[(y-1)*width+x-1]
+	switch mode {
+	case 1:
+		return l
+	case 2:
+		return t
+	case 7:
+		return webpAverage2(l, t)
+	}
+	// Select：与解码器一致，左侧和上方中离 L+T-TL 更近的一个
+	var pl, pt int
+	for shift := uint(0); shift < 32; shift += 8 {
+		cl, ct, ctl := int(l>>shift&0xff), int(t>>shift&0xff), int(tl>>shift&0xff)
+		pl += absInt(ctl - ct)
+		pt += absInt(ctl - cl)
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+// webpAverage2 按通道取平均
+func webpAverage2(a, b uint32) uint32 {
+	return ((a^b)&0xfefefefe)>>1 + a&b
+}
+
+// webpSub 按通道相减（模 256）
+func webpSub(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+	return out
+}
+
+// webpResidualCost 残差的代价（各通道按有符号数取绝对值之和）
+func webpResidualCost(r uint32) int {
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		cost += absInt(int(int8(r >> shift)))
+	}
+	return cost
+}
+
+// webpWriteImage 写入熵编码图像（LZ77 引用之前的像素，不使用颜色缓存，主图像不使用分区 Huffman 码）
+func webpWriteImage(bw *webpBitWriter, pix []uint32, width int, main bool) {
+	bw.write(0, 1) // 颜色缓存
+	if main {
+		bw.write(0, 1) // 分区 Huffman 码
+	}
+	tokens := webpBackwardRefs(pix, width)
+	green := make([]int, webpGreenAlphabet)
+	red := make([]int, webpLiteralAlphabet)
+	blue := make([]int, webpLiteralAlphabet)
+	alpha := make([]int, webpLiteralAlphabet)
+	dist := make([]int, webpDistAlphabet)
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[t.argb>>8&0xff]++
+			red[t.argb>>16&0xff]++
+			blue[t.argb&0xff]++
+			alpha[t.argb>>24]++
+			continue
+		}
+		prefix, _, _ := webpPrefixEncode(t.length)
+		green[256+prefix]++
+		prefix, _, _ = webpPrefixEncode(t.distCode)
+		dist[prefix]++
+	}
+	codes := [5]*webpHuffman{
+		webpWritePrefixCode(bw, green),
+		webpWritePrefixCode(bw, red),
+		webpWritePrefixCode(bw, blue),
+		webpWritePrefixCode(bw, alpha),
+		webpWritePrefixCode(bw, dist),
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].write(bw, int(t.argb>>8&0xff))
+			codes[1].write(bw, int(t.argb>>16&0xff))
+			codes[2].write(bw, int(t.argb&0xff))
+			codes[3].write(bw, int(t.argb>>24))
+			continue
+		}
+		prefix, extraBits, extra := webpPrefixEncode(t.length)
+		codes[0].write(bw, 256+prefix)
+		bw.write(extra, extraBits)
+		prefix, extraBits, extra = webpPrefixEncode(t.distCode)
+		codes[4].write(bw, prefix)
+		bw.write(extra, extraBits)
+	}
+}
+
+// webpToken 熵编码的单元：字面像素（length 为 0）或向前引用（长度和距离码）
+type webpToken struct {
+	argb     uint32
+	length   int
+	distCode int
+}
+
+const (
+	webpMinMatch   = 3
+	webpMaxMatch   = 4096
+	webpWindow     = 1 << 19 // 引用距离上限（距离码的取值范围约为 2^20）
+	webpHashBits   = 16
+	webpChainLimit = 32 // 每个位置最多比较的候选数
+)
+
+// webpBackwardRefs 贪心查找向前引用：候选为上一行同一位置和哈希链上最近的位置
+func webpBackwardRefs(pix []uint32, width int) []webpToken {
+	distCodes := webpDistanceCodes(width)
+	head := make([]int32, 1<<webpHashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, len(pix))
+	hash := func(i int) uint32 {
+		return (pix[i]*0x1e35a7bd + pix[i+1]*0x9e3779b1 + pix[i+2]) * 0x1e35a7bd >> (32 - webpHashBits)
+	}
+	insert := func(i int) {
+		if i+webpMinMatch <= len(pix) {
+			h := hash(i)
+			prev[i] = head[h]
+			head[h] = int32(i)
+		}
+	}
+	matchLen := func(i, j int) int {
+		n := 0
+		for i+n < len(pix) && n < webpMaxMatch && pix[i+n] == pix[j+n] {
+			n++
+		}
+		return n
+	}
+
+	tokens := make([]webpToken, 0, len(pix)/2)
+	for i := 0; i < len(pix); {
+		bestLen, bestDist := 0, 0
+		if i+webpMinMatch <= len(pix) {
+			if i >= width {
+				bestLen, bestDist = matchLen(i, i-width), width
+			}
+			for j, n := head[hash(i)], 0; j >= 0 && n < webpChainLimit && i-int(j) <= webpWindow; j, n = prev[j], n+1 {
+				if l := matchLen(i, int(j)); l > bestLen {
+					bestLen, bestDist = l, i-int(j)
+				}
+			}
+		}
+		if bestLen < webpMinMatch {
+			tokens = append(tokens, webpToken{argb: pix[i]})
+			insert(i)
+			i++
+			continue
+		}
+		code, ok := distCodes[bestDist]
+		if !ok {
+			code = bestDist + 120
+		}
+		tokens = append(tokens, webpToken{length: bestLen, distCode: code})
+		for k := 0; k < bestLen; k++ {
+			insert(i + k)
+		}
+		i += bestLen
+	}
+	return tokens
+}
+
+// webpDistanceMap 邻近像素的距离码表（与解码器一致，按二维偏移编码，1-120 对应左侧和上方附近的像素）
+var webpDistanceMap = [120]uint8{
+	0x18, 0x07, 0x17, 0x19, 0x28, 0x06, 0x27, 0x29, 0x16, 0x1a,
+	0x26, 0x2a, 0x38, 0x05, 0x37, 0x39, 0x15, 0x1b, 0x36, 0x3a,
+	0x25, 0x2b, 0x48, 0x04, 0x47, 0x49, 0x14, 0x1c, 0x35, 0x3b,
+	0x46, 0x4a, 0x24, 0x2c, 0x58, 0x45, 0x4b, 0x34, 0x3c, 0x03,
+	0x57, 0x59, 0x13, 0x1d, 0x56, 0x5a, 0x23, 0x2d, 0x44, 0x4c,
+	0x55, 0x5b, 0x33, 0x3d, 0x68, 0x02, 0x67, 0x69, 0x12, 0x1e,
+	0x66, 0x6a, 0x22, 0x2e, 0x54, 0x5c, 0x43, 0x4d, 0x65, 0x6b,
+	0x32, 0x3e, 0x78, 0x01, 0x77, 0x79, 0x53, 0x5d, 0x11, 0x1f,
+	0x64, 0x6c, 0x42, 0x4e, 0x76, 0x7a, 0x21, 0x2f, 0x75, 0x7b,
+	0x31, 0x3f, 0x63, 0x6d, 0x52, 0x5e, 0x00, 0x74, 0x7c, 0x41,
+	0x4f, 0x10, 0x20, 0x62, 0x6e, 0x30, 0x73, 0x7d, 0x51, 0x5f,
+	0x40, 0x72, 0x7e, 0x61, 0x6f, 0x50, 0x71, 0x7f, 0x60, 0x70,
+}
+
+// webpDistanceCodes 按图片宽度计算距离到最短距离码的映射
+func webpDistanceCodes(width int) map[int]int {
+	codes := make(map[int]int, len(webpDistanceMap))
+	for i := len(webpDistanceMap) - 1; i >= 0; i-- {
+		c := int(webpDistanceMap[i])
+		d := (c>>4)*width + 8 - c&0xf
+		if d < 1 {
+			d = 1
+		}
+		codes[d] = i + 1
+	}
+	return codes
+}
+
+// webpPrefixEncode 长度和距离码的前缀编码：返回前缀码、附加位数和附加位的值（v 从 1 开始）
+func webpPrefixEncode(v int) (int, uint, uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	high := 31
+	for d>>uint(high) == 0 {
+		high--
+	}
+	second := d >> uint(high-1) & 1
+	extraBits := uint(high - 1)
+	return 2*high + second, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// webpHuffman 前缀码（single 表示只有一个符号，写入时不占位）
+type webpHuffman struct {
+	lengths []int
+	codes   []uint32
+	single  bool
+}
+
+func (h *webpHuffman) write(bw *webpBitWriter, symbol int) {
+	if !h.single {
+		bw.write(h.codes[symbol], uint(h.lengths[symbol]))
+	}
+}
+
+// webpWritePrefixCode 按符号频率写入前缀码：不超过两个符号（且都小于 256）时用简单码，否则用普通码
+func webpWritePrefixCode(bw *webpBitWriter, counts []int) *webpHuffman {
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		h := &webpHuffman{lengths: make([]int, len(counts)), codes: make([]uint32, len(counts)), single: len(used) == 1}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			h.lengths[used[0]], h.lengths[used[1]] = 1, 1
+			h.codes[used[1]] = 1
+		}
+		return h
+	}
+
+	h := webpNewHuffman(counts, webpMaxCodeLength)
+	lengthCounts := make([]int, 19)
+	for _, length := range h.lengths {
+		lengthCounts[length]++
+	}
+	lengthCode := webpNewHuffman(lengthCounts, webpMaxCodeLenCode)
+	n := 4
+	for i, symbol := range webpCodeLengthOrder {
+		if lengthCode.lengths[symbol] > 0 && i+1 > n {
+			n = i + 1
+		}
+	}
+	bw.write(0, 1)
+	bw.write(uint32(n-4), 4)
+	for _, symbol := range webpCodeLengthOrder[:n] {
+		bw.write(uint32(lengthCode.lengths[symbol]), 3)
+	}
+	bw.write(0, 1) // 写入全部符号的码长
+	for _, length := range h.lengths {
+		lengthCode.write(bw, length)
+	}
+	return h
+}
+
+// webpNewHuffman 按频率构造码长不超过 maxLength 的规范 Huffman 码（码已按写入顺序反转）
+func webpNewHuffman(counts []int, maxLength int) *webpHuffman {
+	h := &webpHuffman{lengths: make([]int, len(counts)), codes: make([]uint32, len(counts))}
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 1 {
+		h.lengths[used[0]] = 1
+		h.single = true
+		return h
+	}
+	// 码长超过上限时抬高低频符号的频率后重新构造
+	for floor := 1; ; floor *= 2 {
+		weights := make([]int, len(used))
+		for i, symbol := range used {
+			weights[i] = maxInt(counts[symbol], floor)
+		}
+		depths := huffmanDepths(weights)
+		longest := 0
+		for _, d := range depths {
+			longest = maxInt(longest, d)
+		}
+		if longest <= maxLength {
+			for i, symbol := range used {
+				h.lengths[symbol] = depths[i]
+			}
+			break
+		}
+	}
+
+	// 规范码：按码长、符号顺序依次分配
+	var lengthCount [webpMaxCodeLength + 1]uint32
+	for _, length := range h.lengths {
+		lengthCount[length]++
+	}
+	lengthCount[0] = 0
+	var next [webpMaxCodeLength + 1]uint32
+	code := uint32(0)
+	for length := 1; length <= webpMaxCodeLength; length++ {
+		code = (code + lengthCount[length-1]) << 1
+		next[length] = code
+	}
+	for symbol, length := range h.lengths {
+		if length > 0 {
+			h.codes[symbol] = reverseBits(next[length], length)
+			next[length]++
+		}
+	}
+	return h
+}
+
+// huffmanDepths 计算 Huffman 树中各叶子的深度
+func huffmanDepths(weights []int) []int {
+	type node struct {
+		weight      int
+		left, right int // 子节点下标（叶子为 -1）
+	}
+	nodes := make([]node, len(weights), 2*len(weights))
+	queue := make([]int, len(weights))
+	for i, w := range weights {
+		nodes[i] = node{weight: w, left: -1, right: -1}
+		queue[i] = i
+	}
+	for len(queue) > 1 {
+		sort.SliceStable(queue, func(i, j int) bool { return nodes[queue[i]].weight < nodes[queue[j]].weight })
+		a, b := queue[0], queue[1]
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, left: a, right: b})
+		queue = append(queue[2:], len(nodes)-1)
+	}
+	depths := make([]int, len(weights))
+	var walk func(i, depth int)
+	walk = func(i, depth int) {
+		if nodes[i].left < 0 {
+			depths[i] = depth
+			return
+		}
+		walk(nodes[i].left, depth+1)
+		walk(nodes[i].right, depth+1)
+	}
+	walk(queue[0], 0)
+	return depths
+}
+
+// reverseBits 反转 code 的低 n 位（VP8L 按位从低到高读取码字）
+func reverseBits(code uint32, n int) uint32 {
+	var out uint32
+	for i := 0; i < n; i++ {
+		out = out<<1 | code>>uint(i)&1
+	}
+	return out
+}
+
+// webpBitWriter 按 VP8L 的位序（低位在前）写入
+type webpBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *webpBitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *webpBitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
+
+// StripWebPMetadata 无损去除 WebP 中的 EXIF 和 XMP 块（同时清除 VP8X 中对应的标志位）
+func StripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errors.New("imaging: not a webp")
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errors.New("imaging: malformed webp")
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + size + size&1
+		if end > len(data) {
+			if i+8+size != len(data) { // 只容忍末尾缺少填充字节
+				return nil, errors.New("imaging: malformed webp")
+			}
+			end = len(data)
+		}
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= 0x08 | 0x04 // EXIF、XMP 标志
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
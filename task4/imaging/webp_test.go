@@ -0,0 +1,118 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} }},
+		{"solid", 40, 20, func(x, y int) color.NRGBA { return color.NRGBA{200, 100, 50, 255} }},
+		{"two colors", 33, 17, func(x, y int) color.NRGBA {
+			if (x+y)%2 == 0 {
+				return color.NRGBA{0, 0, 0, 255}
+			}
+			return color.NRGBA{255, 255, 255, 255}
+		}},
+		{"gradient", 100, 75, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 2), uint8(y * 3), uint8(x + y), 255}
+		}},
+		{"transparent", 50, 31, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 5), 80, uint8(y * 7), uint8(x * y)}
+		}},
+		{"noise", 64, 64, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+			var buf bytes.Buffer
+			if err := Encode(&buf, src, "webp", 0); err != nil {
+				t.Fatal(err)
+			}
+			got, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Bounds() != src.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), src.Bounds())
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					if c := color.NRGBAModel.Convert(got.At(x, y)); c != src.NRGBAAt(x, y) {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, c, src.NRGBAAt(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := Encode(&encoded, image.NewNRGBA(image.Rect(0, 0, 4, 4)), "webp", 0); err != nil {
+		t.Fatal(err)
+	}
+	vp8l := encoded.Bytes()[12:]
+
+	chunk := func(fourcc string, data []byte) []byte {
+		out := append([]byte(fourcc), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(out[4:], uint32(len(data)))
+		out = append(out, data...)
+		if len(data)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04   // EXIF、XMP
+	vp8x[4], vp8x[7] = 3, 3 // 画布宽高减一（4x4）
+	var body []byte
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, vp8l...)
+	body = append(body, chunk("EXIF", []byte("Exif\x00\x00GPS"))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	file := append([]byte("RIFF\x00\x00\x00\x00WEBP"), body...)
+	binary.LittleEndian.PutUint32(file[4:], uint32(len(file)-8))
+
+	stripped, err := StripWebPMetadata(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fourcc := range []string{"EXIF", "XMP ", "GPS"} {
+		if bytes.Contains(stripped, []byte(fourcc)) {
+			t.Errorf("stripped file still contains %q", fourcc)
+		}
+	}
+	if flags := stripped[20]; flags != 0 {
+		t.Errorf("VP8X flags = %#x, want 0", flags)
+	}
+	if size := binary.LittleEndian.Uint32(stripped[4:]); int(size) != len(stripped)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(stripped)-8)
+	}
+	if _, err := webp.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatalf("decode stripped: %v", err)
+	}
+
+	if _, err := StripWebPMetadata([]byte("not a webp file")); err == nil {
+		t.Error("StripWebPMetadata accepted invalid data")
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"gotask/task4/model"
 	"gotask/task4/router"
 	"gotask/task4/service"
+	"gotask/task4/storage"
+	"gotask/task4/util"
 )
 
 func main() {
@@ -34,6 +36,20 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// 初始化 JWT 签名密钥，并定时检查轮换
+	if err := util.InitKeyring(); err != nil {
+		logger.Fatal("初始化JWT密钥失败", zap.Error(err))
+	}
+	go func() {
+		ticker := time.NewTicker(config.Cfg.JWTConfig.KeyReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := util.RotateKeysIfDue(); err != nil {
+				logger.Error("JWT密钥轮换失败", zap.Error(err))
+			}
+		}
+	}()
+
 	// 3. 初始化数据库（GORM）
 	db, err := initDB()
 	if err != nil {
@@ -44,24 +60,195 @@ func main() {
 		sqlDB.Close()
 	}()
 
+	// 命令行子命令（如 import），执行完退出，不启动服务器
+	if len(os.Args) > 1 {
+		if err := runCommand(db, os.Args[1:]); err != nil {
+			logger.Fatal("执行命令失败", zap.String("command", os.Args[1]), zap.Error(err))
+		}
+		return
+	}
+
 	// 4. 初始化服务和控制器
-	userService := service.NewUserService(db)
+	eventHub := service.NewEventHub(config.Cfg.StreamConfig.BufferSize, config.Cfg.StreamConfig.HistorySize)
+	service.SetEventHub(eventHub)
+	collabBroker := service.NewMemoryCollabBroker()
+	sessionService := service.NewSessionService(db)
+	userService := service.NewUserService(db, sessionService)
 	postService := service.NewPostService(db)
 	commentService := service.NewCommentService(db)
+	oidcService := service.NewOIDCService(db, sessionService)
+	store, err := storage.New(config.Cfg.StorageConfig)
+	if err != nil {
+		logger.Fatal("初始化附件存储失败", zap.Error(err))
+	}
+	avatarService := service.NewAvatarService(db, store)
+	accountService := service.NewAccountService(db, sessionService, avatarService)
+	adminService := service.NewAdminService(db, sessionService)
+	followService := service.NewFollowService(db)
+	reportService := service.NewReportService(db)
+	mentionService := service.NewMentionService(db)
+	notificationService := service.NewNotificationService(db)
+	likeService := service.NewLikeService(db)
+	webhookService := service.NewWebhookService(db)
+	imageService := service.NewImageService(db, store)
+	imageService.Start(func(attachmentID uint, err error) {
+		logger.Error("生成缩略图失败", zap.Uint("attachmentId", attachmentID), zap.Error(err))
+	})
+	attachmentService := service.NewAttachmentService(db, store, imageService)
+	categoryService := service.NewCategoryService(db)
+	seriesService := service.NewSeriesService(db)
+	relatedService := service.NewRelatedService(db)
+	statsService := service.NewStatsService(db)
+	importService := service.NewImportService(db)
+	sitemapService := service.NewSitemapService(db)
+
+	// 发件箱事件的订阅者（站内通知、Webhook、相关文章缓存、站点地图；后续的搜索索引等也在此注册）
+	outboxRelay := service.NewOutboxRelay(db)
+	outboxRelay.Subscribe("notification", notificationService.HandleCommentCreated, model.EventCommentCreated)
+	outboxRelay.Subscribe("webhook", webhookService.HandleEvent)
+	outboxRelay.Subscribe("related", relatedService.HandlePostChanged, model.EventPostCreated, model.EventPostUpdated)
+	outboxRelay.Subscribe("sitemap", sitemapService.HandlePostChanged, model.EventPostCreated, model.EventPostUpdated)
 
 	userHandler := handler.NewUserHandler(userService)
-	postHandler := handler.NewPostHandler(postService)
+	postHandler := handler.NewPostHandler(postService, relatedService)
 	commentHandler := handler.NewCommentHandler(commentService)
+	oauthHandler := handler.NewOAuthHandler(oidcService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	adminHandler := handler.NewAdminHandler(adminService)
+	followHandler := handler.NewFollowHandler(followService)
+	reportHandler := handler.NewReportHandler(reportService)
+	mentionHandler := handler.NewMentionHandler(mentionService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	likeHandler := handler.NewLikeHandler(likeService)
+	streamHandler := handler.NewStreamHandler(eventHub, postService)
+	collabHandler := handler.NewCollabHandler(collabBroker, postService, commentService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService)
+	avatarHandler := handler.NewAvatarHandler(avatarService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	seriesHandler := handler.NewSeriesHandler(seriesService)
+	statsHandler := handler.NewStatsHandler(statsService)
+	importHandler := handler.NewImportHandler(importService)
+	sitemapHandler := handler.NewSitemapHandler(sitemapService)
+	outboxHandler := handler.NewOutboxHandler(outboxRelay)
+
+	// 定时执行到期的账号注销，并清理过期的发件箱事件和未关联文章的附件
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := accountService.PurgeDueAccounts(); err != nil {
+				logger.Error("执行账号注销失败", zap.Int("purged", n), zap.Error(err))
+			} else if n > 0 {
+				logger.Info("已执行账号注销", zap.Int("purged", n))
+			}
+			if n, err := outboxRelay.CleanupPublished(); err != nil {
+				logger.Error("清理发件箱事件失败", zap.Error(err))
+			} else if n > 0 {
+				logger.Info("已清理发件箱事件", zap.Int64("deleted", n))
+			}
+			if n, err := attachmentService.CollectOrphans(context.Background()); err != nil {
+				logger.Error("清理附件失败", zap.Int("deleted", n), zap.Error(err))
+			} else if n > 0 {
+				logger.Info("已清理未关联文章的附件", zap.Int("deleted", n))
+			}
+		}
+	}()
+
+	// 定时转发发件箱中的领域事件
+	go func() {
+		ticker := time.NewTicker(config.Cfg.OutboxConfig.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := outboxRelay.RelayPending(context.Background()); err != nil {
+				logger.Error("转发发件箱事件失败", zap.Int("relayed", n), zap.Error(err))
+			} else if n > 0 {
+				logger.Debug("已转发发件箱事件", zap.Int("relayed", n))
+			}
+		}
+	}()
+
+	// 定时重新计算失效的相关文章缓存（单个后台任务，避免并发计算）
+	go func() {
+		ticker := time.NewTicker(config.Cfg.RelatedConfig.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := relatedService.RefreshStale(context.Background()); err != nil {
+				logger.Error("计算相关文章失败", zap.Int("refreshed", n), zap.Error(err))
+			} else if n > 0 {
+				logger.Debug("已计算相关文章", zap.Int("refreshed", n))
+			}
+		}
+	}()
+
+	// 定时重新生成过期的站点地图分片（启动时先生成一次）
+	go func() {
+		refresh := func() {
+			if n, err := sitemapService.RefreshStale(context.Background()); err != nil {
+				logger.Error("生成站点地图失败", zap.Int("refreshed", n), zap.Error(err))
+			} else if n > 0 {
+				logger.Debug("已生成站点地图", zap.Int("refreshed", n))
+			}
+		}
+		refresh()
+		ticker := time.NewTicker(config.Cfg.SitemapConfig.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			refresh()
+		}
+	}()
+
+	// 定时投递 Webhook（进程退出时未完成的投递会在抢占超时后重新投递）
+	go func() {
+		ticker := time.NewTicker(config.Cfg.WebhookConfig.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := webhookService.DeliverDue(context.Background()); err != nil {
+				logger.Error("投递Webhook失败", zap.Int("delivered", n), zap.Error(err))
+			} else if n > 0 {
+				logger.Debug("已投递Webhook", zap.Int("delivered", n))
+			}
+		}
+	}()
 
 	// 5. 初始化路由
 	r := gin.New() // 不使用默认中间件（自己手动添加）
-	router.Setup(r, userHandler, postHandler, commentHandler, logger)
+	router.Setup(r, router.Handlers{
+		User:         userHandler,
+		Post:         postHandler,
+		Comment:      commentHandler,
+		OAuth:        oauthHandler,
+		Session:      sessionHandler,
+		Account:      accountHandler,
+		Admin:        adminHandler,
+		Follow:       followHandler,
+		Report:       reportHandler,
+		Mention:      mentionHandler,
+		Notification: notificationHandler,
+		Like:         likeHandler,
+		Stream:       streamHandler,
+		Collab:       collabHandler,
+		Webhook:      webhookHandler,
+		Attachment:   attachmentHandler,
+		Avatar:       avatarHandler,
+		Category:     categoryHandler,
+		Series:       seriesHandler,
+		Stats:        statsHandler,
+		Import:       importHandler,
+		Sitemap:      sitemapHandler,
+		Outbox:       outboxHandler,
+	}, sessionService, logger)
 
 	// 6. 启动服务器（优雅退出）
 	srv := &http.Server{
 		Addr:    config.Cfg.ServerConfig.Port,
 		Handler: r,
 	}
+	// Shutdown 会等待所有请求结束，先关闭事件中心让 SSE 长连接退出；
+	// WebSocket 连接已被接管，不在 Shutdown 的等待范围内，需主动关闭
+	srv.RegisterOnShutdown(eventHub.Close)
+	srv.RegisterOnShutdown(collabBroker.Close)
 	go func() {
 		logger.Info("服务器启动", zap.String("port", config.Cfg.ServerConfig.Port))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -136,14 +323,55 @@ func initDB() (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(config.Cfg.DBConfig.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(config.Cfg.DBConfig.ConnMaxLifetime)
 
+	// 相关文章缓存增加唯一索引前清空旧缓存（并发计算可能写入了重复记录），由后台任务重新计算
+	if db.Migrator().HasTable(&model.RelatedPost{}) && !db.Migrator().HasIndex(&model.RelatedPost{}, "idx_related_post") {
+		if err := db.Migrator().DropTable(&model.RelatedPost{}); err != nil {
+			return nil, err
+		}
+		if err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Post{}).
+			UpdateColumn("related_computed_at", nil).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 文章表已存在但还没有计数列时（从旧版本升级），迁移后一次性回填评论数和热度
+	backfillCounters := db.Migrator().HasTable(&model.Post{}) && !db.Migrator().HasColumn(&model.Post{}, "CommentCount")
+
 	// 自动迁移表结构
 	if err := db.AutoMigrate(
 		&model.User{},
 		&model.Post{},
 		&model.Comment{},
+		&model.UserIdentity{},
+		&model.Session{},
+		&model.AccountDeletion{},
+		&model.AuditLog{},
+		&model.Follow{},
+		&model.Report{},
+		&model.Mention{},
+		&model.Notification{},
+		&model.Like{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.OutboxEvent{},
+		&model.Attachment{},
+		&model.Category{},
+		&model.Series{},
+		&model.Tag{},
+		&model.RelatedPost{},
+		&model.PostDailyStat{},
+		&model.UserDailyStat{},
+		&model.ImportRecord{},
+		&model.SitemapChunk{},
+		&model.OAuthLogin{},
 	); err != nil {
 		return nil, err
 	}
+	if backfillCounters {
+		if err := service.BackfillPostCounters(db); err != nil {
+			return nil, err
+		}
+	}
 
 	return db, nil
 }
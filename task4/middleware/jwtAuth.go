@@ -5,14 +5,19 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gotask/task4/service"
 	"gotask/task4/util"
 )
 
-// JWTAuth 验证JWT token，通过后将userID存入上下文
-func JWTAuth() gin.HandlerFunc {
+// JWTAuth 验证JWT token及其登录会话，通过后将userID存入上下文
+func JWTAuth(sessionService *service.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从Authorization头获取token（格式：Bearer <token>）
 		authHeader := c.GetHeader("Authorization")
+		// 浏览器发起 WebSocket 握手时无法设置请求头，允许通过 access_token 参数传递
+		if authHeader == "" && c.IsWebsocket() && c.Query("access_token") != "" {
+			authHeader = "Bearer " + c.Query("access_token")
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, util.ErrNoPermission)
 			c.Abort()
@@ -33,9 +38,29 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// 校验会话未被吊销、用户未被封禁，并刷新最近活跃时间
+		tokenID, _ := data["tokenId"].(string)
+		userID, _ := data["userId"].(uint)
+		session, err := sessionService.Validate(tokenID, userID, c.ClientIP())
+		if err != nil {
+			if e, ok := err.(*util.Errno); ok {
+				c.JSON(http.StatusUnauthorized, e)
+			} else {
+				c.JSON(http.StatusUnauthorized, util.ErrNoPermission)
+			}
+			c.Abort()
+			return
+		}
+
 		// 将userID, userName存入上下文，供后续 handler 使用
 		c.Set("userID", data["userId"])
-		c.Set("userName", data["userName"])
+		c.Set("userName", data["username"])
+		c.Set("sessionID", session.ID)
+		c.Set("tokenID", tokenID)
+		c.Set("userRole", session.User.Role)
+		if session.ImpersonatorID != nil {
+			c.Set("impersonatorID", *session.ImpersonatorID)
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gotask/task4/util"
+)
+
+// RequireRole 限制只有指定角色可访问（需放在 JWTAuth 之后；代登录 token 一律拒绝）
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonated := c.Get("impersonatorID"); impersonated {
+			c.JSON(http.StatusForbidden, util.ErrNoPermission)
+			c.Abort()
+			return
+		}
+		role := c.GetString("userRole")
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, util.ErrNoPermission)
+		c.Abort()
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 注销后文章的处理方式
+const (
+	DeletionPostDelete   = "delete"   // 删除文章（连同文章下的评论）
+	DeletionPostReassign = "reassign" // 保留文章，转给"已注销用户"
+)
+
+// GhostUsername 已注销用户的占位账号的用户名（接管匿名化后的评论和文章，不允许注册；
+// 被占用时加随机后缀，查找占位账号按 RoleGhost 而不是用户名）
+const GhostUsername = "deleted_user"
+
+// AccountDeletion 账号注销申请（宽限期结束后由定时任务执行）
+type AccountDeletion struct {
+	gorm.Model
+	UserID      uint      `gorm:"not null;uniqueIndex" json:"userId"` // 外键：申请注销的用户ID
+	PostAction  string    `gorm:"size:20;not null" json:"postAction"` // 文章处理方式：delete/reassign
+	ScheduledAt time.Time `gorm:"not null;index" json:"scheduledAt"`  // 计划执行时间（申请时间 + 宽限期）
+}
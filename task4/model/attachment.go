@@ -0,0 +1,16 @@
+package model
+
+import "gorm.io/gorm"
+
+// Attachment 附件（上传后未关联文章的附件超时会被清理）
+type Attachment struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index" json:"userId"`         // 上传者ID
+	PostID      *uint  `gorm:"index" json:"postId,omitempty"`        // 外键：关联的文章ID（为空表示尚未关联）
+	Key         string `gorm:"size:100;not null;unique" json:"key"`  // 存储中的文件名（随机生成，也用于访问地址）
+	Filename    string `gorm:"size:255;not null" json:"filename"`    // 原始文件名
+	ContentType string `gorm:"size:100;not null" json:"contentType"` // 按内容识别的 MIME 类型
+	Size        int64  `gorm:"not null" json:"size"`                 // 文件大小（字节）
+	Width       int    `json:"width,omitempty"`                      // 图片宽度（非图片为0）
+	Height      int    `json:"height,omitempty"`                     // 图片高度（非图片为0）
+}
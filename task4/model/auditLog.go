@@ -0,0 +1,14 @@
+package model
+
+import "gorm.io/gorm"
+
+// AuditLog 管理操作审计日志（只增不改；不与用户表建立外键，操作人注销后日志仍保留）
+type AuditLog struct {
+	gorm.Model
+	ActorID    uint   `gorm:"not null;index" json:"actorId"`                             // 操作人ID
+	Action     string `gorm:"size:50;not null;index" json:"action"`                      // 操作类型，如 user.ban
+	TargetType string `gorm:"size:50;not null;index:idx_audit_target" json:"targetType"` // 操作对象类型，如 user
+	TargetID   uint   `gorm:"not null;index:idx_audit_target" json:"targetId"`           // 操作对象ID
+	Detail     string `gorm:"type:text" json:"detail"`                                   // 操作详情（原因、参数等）
+	IP         string `gorm:"size:64" json:"ip"`                                         // 操作人IP
+}
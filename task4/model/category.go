@@ -0,0 +1,12 @@
+package model
+
+import "gorm.io/gorm"
+
+// Category 文章分类（树形结构，由管理员维护，每篇文章最多属于一个分类）
+type Category struct {
+	gorm.Model
+	Name     string     `gorm:"size:50;not null" json:"name"`    // 分类名称（同一父分类下不重复）
+	ParentID *uint      `gorm:"index" json:"parentId,omitempty"` // 父分类ID（为空表示顶级分类）
+	Sort     int        `gorm:"not null;default:0" json:"sort"`  // 排序（同级分类按该值升序）
+	Children []Category `gorm:"-" json:"children,omitempty"`     // 子分类（查询分类树时填充）
+}
@@ -2,12 +2,25 @@ package model
 
 import "gorm.io/gorm"
 
+// 评论审核状态
+const (
+	CommentApproved = "approved" // 已发布
+	CommentPending  = "pending"  // 待审核（不公开展示）
+	CommentRejected = "rejected" // 审核拒绝
+)
+
 // Comment 评论模型
 type Comment struct {
 	gorm.Model
-	Content string `gorm:"type:text;not null" json:"content"`
-	PostID  uint   `gorm:"not null" json:"postId"`                       // 外键：文章ID
-	UserID  uint   `gorm:"not null" json:"userId"`                       // 外键：评论者ID
-	User    User   `gorm:"foreignKey:UserID" json:"commenter,omitempty"` // 关联评论者
-	Post    Post   `gorm:"foreignKey:PostID" json:"post,omitempty"`      // 关联文章
+	Content          string `gorm:"type:text;not null" json:"content"`
+	PostID           uint   `gorm:"not null" json:"postId"`                                // 外键：文章ID
+	UserID           uint   `gorm:"not null" json:"userId"`                                // 外键：评论者ID
+	AuthorName       string `gorm:"size:100" json:"authorName,omitempty"`                  // 导入评论的原评论者名称（评论者未关联本站用户、归入占位账号时保留）
+	ParentID         *uint  `gorm:"index" json:"parentId,omitempty"`                       // 回复的评论ID（为空表示直接评论文章）
+	LikeCount        int    `gorm:"not null;default:0" json:"likeCount"`                   // 点赞数
+	Status           string `gorm:"size:20;not null;default:approved;index" json:"status"` // 审核状态：approved/pending/rejected
+	ModerationReason string `gorm:"size:100" json:"moderationReason,omitempty"`            // 进入待审核的原因（命中的规则）
+	Hidden           bool   `gorm:"not null;default:false;index" json:"-"`                 // 因举报被隐藏（不在公开接口中展示）
+	User             User   `gorm:"foreignKey:UserID" json:"commenter,omitempty"`          // 关联评论者
+	Post             Post   `gorm:"foreignKey:PostID" json:"post,omitempty"`               // 关联文章
 }
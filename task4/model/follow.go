@@ -0,0 +1,12 @@
+package model
+
+import "gorm.io/gorm"
+
+// Follow 关注关系（FollowerID 关注了 FolloweeID）
+type Follow struct {
+	gorm.Model
+	FollowerID uint `gorm:"not null;uniqueIndex:idx_follower_followee" json:"followerId"`       // 外键：关注者ID
+	FolloweeID uint `gorm:"not null;uniqueIndex:idx_follower_followee;index" json:"followeeId"` // 外键：被关注者ID
+	Follower   User `gorm:"foreignKey:FollowerID" json:"follower,omitempty"`
+	Followee   User `gorm:"foreignKey:FolloweeID" json:"followee,omitempty"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ImportRecord 导入记录（来源标识与本站文章/评论的对应关系，重复导入时据此跳过或更新）
+type ImportRecord struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Source     string    `gorm:"size:255;not null;uniqueIndex" json:"source"` // 来源标识，如 wxr:example.com:123、markdown:hello-world
+	TargetType string    `gorm:"size:20;not null" json:"targetType"`          // 对应内容类型：post/comment
+	TargetID   uint      `gorm:"not null" json:"targetId"`                    // 对应内容ID
+	Checksum   string    `gorm:"size:64" json:"-"`                            // 导入内容的摘要（文章内容变化时更新）
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// 点赞对象类型
+const (
+	LikeTargetPost    = "post"
+	LikeTargetComment = "comment"
+)
+
+// Like 点赞记录（取消点赞时物理删除）
+type Like struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_like_unique" json:"userId"`             // 外键：点赞用户ID
+	TargetType string    `gorm:"size:20;not null;uniqueIndex:idx_like_unique" json:"targetType"` // 点赞对象类型：post/comment
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_like_unique" json:"targetId"`           // 点赞对象ID
+	CreatedAt  time.Time `json:"createdAt"`
+}
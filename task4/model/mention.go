@@ -0,0 +1,20 @@
+package model
+
+import "gorm.io/gorm"
+
+// 提及来源类型
+const (
+	MentionSourcePost    = "post"
+	MentionSourceComment = "comment"
+)
+
+// Mention 文章或评论中对用户的 @提及
+type Mention struct {
+	gorm.Model
+	SourceType      string `gorm:"size:20;not null;uniqueIndex:idx_mention_unique" json:"sourceType"`    // 来源类型：post/comment
+	SourceID        uint   `gorm:"not null;uniqueIndex:idx_mention_unique" json:"sourceId"`              // 来源ID
+	MentionedUserID uint   `gorm:"not null;uniqueIndex:idx_mention_unique;index" json:"mentionedUserId"` // 外键：被提及的用户ID
+	AuthorID        uint   `gorm:"not null" json:"authorId"`                                             // 外键：提及人ID
+	PostID          uint   `gorm:"not null" json:"postId"`                                               // 所属文章ID（评论提及时为评论所在文章）
+	Author          User   `gorm:"foreignKey:AuthorID" json:"author,omitempty"`
+}
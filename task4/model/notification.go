@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通知类型
+const (
+	NotifyMention = "mention" // 被 @提及
+	NotifyComment = "comment" // 文章收到评论
+	NotifyReply   = "reply"   // 评论收到回复
+	NotifyLike    = "like"    // 文章或评论被点赞
+	NotifyFollow  = "follow"  // 被关注
+)
+
+// Notification 站内通知
+type Notification struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index:idx_notification_user" json:"userId"` // 外键：接收人ID
+	ActorID    uint       `gorm:"not null" json:"actorId"`                            // 触发通知的用户ID
+	Type       string     `gorm:"size:20;not null" json:"type"`                       // 通知类型
+	TargetType string     `gorm:"size:20;not null" json:"targetType"`                 // 关联对象类型：post/comment/user
+	TargetID   uint       `gorm:"not null" json:"targetId"`                           // 关联对象ID
+	PostID     uint       `json:"postId,omitempty"`                                   // 关联文章ID（便于前端跳转）
+	Excerpt    string     `gorm:"size:200" json:"excerpt"`                            // 内容摘要
+	ReadAt     *time.Time `gorm:"index:idx_notification_user" json:"readAt"`          // 已读时间（为空表示未读）
+	Actor      User       `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// OAuthLogin 进行中的外部身份登录：从跳转授权到回调期间保存 PKCE verifier 和 nonce
+// （保存在数据库中，服务重启或回调落到其他实例时仍可完成登录）
+type OAuthLogin struct {
+	ID         uint      `gorm:"primarykey"`
+	StateHash  string    `gorm:"size:64;not null;uniqueIndex"` // state 的哈希
+	Verifier   string    `gorm:"size:128;not null"`            // PKCE code_verifier
+	Nonce      string    `gorm:"size:64;not null"`             // ID Token 中应携带的 nonce
+	LinkUserID *uint     // 非空表示已登录用户发起的账号绑定
+	ExpiresAt  time.Time `gorm:"not null;index"` // 过期时间
+	CreatedAt  time.Time
+}
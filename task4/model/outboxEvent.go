@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// 领域事件类型
+const (
+	EventPostCreated    = "post.created"
+	EventPostUpdated    = "post.updated"
+	EventCommentCreated = "comment.created" // 评论公开（直接发布或审核通过）
+)
+
+// OutboxEvent 事务发件箱：与业务数据在同一事务中写入，由后台任务转发给进程内的订阅者
+type OutboxEvent struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	Type          string     `gorm:"size:50;not null" json:"type"`                           // 事件类型
+	AggregateID   uint       `gorm:"not null" json:"aggregateId"`                            // 事件对象ID（文章ID/评论ID）
+	Payload       string     `gorm:"type:text;not null" json:"payload"`                      // 事件内容（JSON）
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`                     // 转发失败次数
+	LastError     string     `gorm:"size:500" json:"lastError"`                              // 最近一次失败原因
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_pending" json:"nextAttemptAt"` // 下次转发时间
+	PublishedAt   *time.Time `gorm:"index:idx_outbox_pending" json:"publishedAt"`            // 全部订阅者处理成功的时间（为空表示待转发）
+	FailedAt      *time.Time `gorm:"index" json:"failedAt"`                                  // 达到最大转发次数后放弃的时间（需管理员重新投递）
+	CreatedAt     time.Time  `json:"createdAt"`
+}
@@ -1,12 +1,48 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 文章评论状态
+const (
+	CommentOpen          = "open"      // 允许所有登录用户评论
+	CommentClosed        = "closed"    // 关闭评论
+	CommentFollowersOnly = "followers" // 仅关注作者的用户可评论
+)
 
 // Post 文章模型
 type Post struct {
 	gorm.Model
-	Title   string `gorm:"size:200;not null" json:"title"`
-	Content string `gorm:"type:text" json:"content"`
-	UserID  uint   `gorm:"not null" json:"userId"`                    // 外键：作者ID
-	User    User   `gorm:"foreignKey:UserID" json:"author,omitempty"` // 关联作者（查询时返回）
+	Title                  string       `gorm:"size:200;not null" json:"title"`
+	Content                string       `gorm:"type:text" json:"content"`
+	UserID                 uint         `gorm:"not null" json:"userId"`                               // 外键：作者ID
+	CategoryID             *uint        `gorm:"index" json:"categoryId,omitempty"`                    // 分类ID（可为空）
+	SeriesID               *uint        `gorm:"index" json:"seriesId,omitempty"`                      // 所属系列ID（可为空）
+	SeriesPosition         int          `gorm:"not null;default:0" json:"-"`                          // 在系列中的顺序（从1开始）
+	RequireCommentApproval bool         `gorm:"not null;default:false" json:"requireCommentApproval"` // 评论需作者或审核员审核后才公开
+	CommentStatus          string       `gorm:"size:20;not null;default:open" json:"commentStatus"`   // 评论状态：open/closed/followers
+	CommentAutoCloseDays   int          `gorm:"not null;default:0" json:"commentAutoCloseDays"`       // 发布N天后自动关闭评论（0表示不自动关闭）
+	Hidden                 bool         `gorm:"not null;default:false;index" json:"-"`                // 因举报被隐藏（不在公开接口中展示）
+	LikeCount              int          `gorm:"not null;default:0" json:"likeCount"`                  // 点赞数
+	ViewCount              int          `gorm:"not null;default:0" json:"viewCount"`                  // 浏览数
+	CommentCount           int          `gorm:"not null;default:0" json:"commentCount"`               // 公开的评论数
+	HotScore               float64      `gorm:"not null;default:0;index" json:"-"`                    // 热度（互动数取对数加发布时间，计数变化时更新）
+	RelatedComputedAt      *time.Time   `json:"-"`                                                    // 相关文章的计算时间（为空表示需要重新计算）
+	User                   User         `gorm:"foreignKey:UserID" json:"author,omitempty"`            // 关联作者（查询时返回）
+	Category               *Category    `gorm:"foreignKey:CategoryID" json:"category,omitempty"`      // 关联分类（查询时返回）
+	Tags                   []Tag        `gorm:"many2many:post_tags" json:"tags,omitempty"`            // 标签
+	Attachments            []Attachment `gorm:"foreignKey:PostID" json:"attachments,omitempty"`       // 附件
+	Series                 *SeriesNav   `gorm:"-" json:"series,omitempty"`                            // 系列导航（查看文章详情时填充）
+}
+
+// CommentsClosed 评论是否已关闭（手动关闭或超过自动关闭天数）
+func (p *Post) CommentsClosed() bool {
+	if p.CommentStatus == CommentClosed {
+		return true
+	}
+	return p.CommentAutoCloseDays > 0 &&
+		time.Now().After(p.CreatedAt.AddDate(0, 0, p.CommentAutoCloseDays))
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// PostDailyStat 文章每日计数（浏览、点赞、评论按天累加，用于排行榜和作者统计）
+type PostDailyStat struct {
+	ID       uint      `gorm:"primarykey" json:"-"`
+	PostID   uint      `gorm:"not null;uniqueIndex:idx_post_day" json:"postId"`
+	Day      time.Time `gorm:"type:date;not null;uniqueIndex:idx_post_day;index" json:"day"`
+	Views    int       `gorm:"not null;default:0" json:"views"`
+	Likes    int       `gorm:"not null;default:0" json:"likes"` // 当日净增点赞数（取消点赞时扣减）
+	Comments int       `gorm:"not null;default:0" json:"comments"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// RelatedPost 相关文章缓存（按文章计算，文章修改或缓存过期后由后台任务重新计算）
+type RelatedPost struct {
+	ID        uint    `gorm:"primarykey"`
+	PostID    uint    `gorm:"not null;uniqueIndex:idx_related_post"`       // 文章ID
+	RelatedID uint    `gorm:"not null;uniqueIndex:idx_related_post;index"` // 相关文章ID
+	Score     float64 `gorm:"not null"`                                    // 相关度（0-1）
+}
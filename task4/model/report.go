@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 举报对象类型
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+)
+
+// 举报处理状态
+const (
+	ReportOpen      = "open"      // 待处理
+	ReportResolved  = "resolved"  // 确认违规，内容保持隐藏
+	ReportDismissed = "dismissed" // 驳回，内容恢复展示
+)
+
+// Report 内容举报（同一用户对同一内容只能举报一次）
+type Report struct {
+	gorm.Model
+	ReporterID uint       `gorm:"not null;uniqueIndex:idx_report_unique" json:"reporterId"`                                 // 外键：举报人ID
+	TargetType string     `gorm:"size:20;not null;uniqueIndex:idx_report_unique;index:idx_report_target" json:"targetType"` // 举报对象类型：post/comment
+	TargetID   uint       `gorm:"not null;uniqueIndex:idx_report_unique;index:idx_report_target" json:"targetId"`           // 举报对象ID
+	Reason     string     `gorm:"size:20;not null" json:"reason"`                                                           // 举报原因：spam/abuse/hate/sexual/illegal/other
+	Detail     string     `gorm:"size:500" json:"detail"`                                                                   // 补充说明
+	Status     string     `gorm:"size:20;not null;default:open;index" json:"status"`                                        // 处理状态：open/resolved/dismissed
+	HandledBy  *uint      `json:"handledBy,omitempty"`                                                                      // 处理人ID
+	HandledAt  *time.Time `json:"handledAt,omitempty"`                                                                      // 处理时间
+	Reporter   User       `gorm:"foreignKey:ReporterID" json:"reporter,omitempty"`
+}
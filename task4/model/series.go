@@ -0,0 +1,29 @@
+package model
+
+import "gorm.io/gorm"
+
+// Series 系列（作者把多篇文章按顺序组织为合集，每篇文章最多属于一个系列）
+type Series struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index" json:"userId"`               // 作者ID
+	Title       string `gorm:"size:200;not null" json:"title"`             // 系列标题
+	Description string `gorm:"type:text" json:"description"`               // 系列简介
+	User        User   `gorm:"foreignKey:UserID" json:"author,omitempty"`  // 关联作者（查询时返回）
+	Posts       []Post `gorm:"foreignKey:SeriesID" json:"posts,omitempty"` // 系列中的文章（按顺序）
+}
+
+// SeriesPostRef 系列导航中引用的文章
+type SeriesPostRef struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// SeriesNav 文章所在系列的导航信息（上一篇/下一篇）
+type SeriesNav struct {
+	ID       uint           `json:"id"`
+	Title    string         `json:"title"`
+	Position int            `json:"position"` // 当前文章是第几篇（从1开始）
+	Total    int            `json:"total"`    // 系列文章总数
+	Prev     *SeriesPostRef `json:"prev,omitempty"`
+	Next     *SeriesPostRef `json:"next,omitempty"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session 登录会话（每签发一个 token 记录一条，用于查看登录设备和踢下线）
+type Session struct {
+	gorm.Model
+	UserID         uint       `gorm:"not null;index" json:"userId"`          // 外键：用户ID
+	TokenID        string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // token 的 jti（不返回给前端）
+	Device         string     `gorm:"size:100" json:"device"`                // 设备名称
+	IP             string     `gorm:"size:64" json:"ip"`                     // 最近一次请求的 IP
+	UserAgent      string     `gorm:"size:255" json:"userAgent"`             // 登录时的 User-Agent
+	LastSeenAt     time.Time  `json:"lastSeenAt"`                            // 最近活跃时间（JWTAuth 中间件更新）
+	ExpiresAt      time.Time  `gorm:"index" json:"expiresAt"`                // 过期时间（与 token 一致）
+	RevokedAt      *time.Time `json:"revokedAt,omitempty"`                   // 吊销时间（非空表示已被踢下线）
+	ImpersonatorID *uint      `json:"impersonatorId,omitempty"`              // 代登录的管理员ID（普通登录为空）
+	Current        bool       `gorm:"-" json:"current"`                      // 是否为当前请求所用会话（查询时填充）
+	User           User       `gorm:"foreignKey:UserID" json:"-"`
+}
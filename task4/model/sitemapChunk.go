@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// 站点地图分片类型
+const (
+	SitemapPosts   = "posts"   // 文章（按文章ID区间拆分）
+	SitemapAuthors = "authors" // 作者主页（按用户ID区间拆分）
+)
+
+// SitemapChunk 生成好的 sitemap 文件（分片 N 包含ID在 [N*ChunkSize+1, (N+1)*ChunkSize] 内的地址），
+// 文章变化时标记所在分片过期，由后台任务重新生成
+type SitemapChunk struct {
+	ID          uint       `gorm:"primarykey" json:"-"`
+	Kind        string     `gorm:"size:20;not null;uniqueIndex:idx_sitemap_chunk" json:"kind"` // 分片类型：posts/authors
+	Number      int        `gorm:"not null;uniqueIndex:idx_sitemap_chunk" json:"number"`       // 分片序号（从0开始）
+	ChunkSize   int        `gorm:"not null" json:"chunkSize"`                                  // 生成时的分片大小（配置修改后需重新生成）
+	URLCount    int        `gorm:"not null" json:"urlCount"`                                   // 包含的地址数（为0时不出现在索引中）
+	LastMod     *time.Time `json:"lastMod"`                                                    // 分片内地址的最近修改时间
+	Content     []byte     `gorm:"type:longblob" json:"-"`                                     // sitemap XML
+	GeneratedAt time.Time  `gorm:"not null" json:"generatedAt"`                                // 开始生成的时间
+	StaleAt     *time.Time `json:"staleAt"`                                                    // 最近一次标记过期的时间（晚于生成时间时需重新生成）
+}
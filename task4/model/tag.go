@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// Tag 文章标签（作者自由填写，统一为小写）
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"-"`
+}
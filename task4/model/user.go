@@ -1,16 +1,63 @@
 package model
 
 import (
+	"fmt"
+	"time"
+
 	"gorm.io/gorm"
 	"gotask/task4/util"
 )
 
+// 用户角色
+const (
+	RoleUser      = "user"      // 普通用户
+	RoleModerator = "moderator" // 内容审核员
+	RoleAdmin     = "admin"     // 管理员
+	RoleGhost     = "ghost"     // 已注销用户的占位账号（只由系统创建，不可登录，注册和管理后台都不能设置）
+)
+
 // User 用户模型
 type User struct {
 	gorm.Model
-	Username string `gorm:"size:50;unique;not null" json:"username"` // 用户名（唯一）
-	Password string `gorm:"size:100;not null" json:"-"`              // 密码（加密存储，不返回给前端）
-	Email    string `gorm:"size:100;unique;not null" json:"email"`   // 邮箱（唯一）
+	Username             string     `gorm:"size:50;unique;not null" json:"username"`             // 用户名（唯一）
+	Password             string     `gorm:"size:100;not null" json:"-"`                          // 密码（加密存储，不返回给前端）
+	Email                string     `gorm:"size:100;unique;not null" json:"email"`               // 邮箱（唯一）
+	Role                 string     `gorm:"size:20;not null;default:user" json:"role,omitempty"` // 角色：user/moderator/admin
+	BannedAt             *time.Time `json:"bannedAt,omitempty"`                                  // 永久封禁时间
+	SuspendedUntil       *time.Time `json:"suspendedUntil,omitempty"`                            // 临时封禁截止时间
+	BanReason            string     `gorm:"size:255" json:"banReason,omitempty"`                 // 封禁原因
+	PasswordResetToken   string     `gorm:"size:64;index" json:"-"`                              // 强制重置密码的令牌摘要（非空时禁止密码登录）
+	PasswordResetExpires *time.Time `json:"-"`                                                   // 重置令牌过期时间
+	AvatarKey            string     `gorm:"size:64" json:"-"`                                    // 已上传头像的存储标识（为空时使用默认头像）
+	Avatar               string     `gorm:"-" json:"avatar,omitempty"`                           // 头像地址（查询后填充）
+}
+
+// AvatarURL 用户头像地址，key 作为版本号（更换头像后地址随之变化，便于长期缓存）
+func AvatarURL(userID uint, key string) string {
+	url := fmt.Sprintf("/api/v1/users/%d/avatar", userID)
+	if key != "" {
+		url += "?v=" + key
+	}
+	return url
+}
+
+// AfterFind 查询后填充头像地址（钩子函数），需同时查询 AvatarKey
+func (u *User) AfterFind(tx *gorm.DB) error {
+	if u.ID != 0 {
+		u.Avatar = AvatarURL(u.ID, u.AvatarKey)
+	}
+	return nil
+}
+
+// Blocked 账号是否处于封禁状态（永久封禁或临时封禁未到期），返回对应错误
+func (u *User) Blocked() error {
+	if u.BannedAt != nil {
+		return util.NewErrno(util.ErrUserBanned, "%s", u.BanReason)
+	}
+	if u.SuspendedUntil != nil && time.Now().Before(*u.SuspendedUntil) {
+		return util.NewErrno(util.ErrUserSuspended, "截止 %s，%s", u.SuspendedUntil.Format("2006-01-02 15:04"), u.BanReason)
+	}
+	return nil
 }
 
 // BeforeSave 保存前加密密码（钩子函数）
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// UserDailyStat 用户每日计数（新增粉丝按天累加，用于作者统计）
+type UserDailyStat struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_day" json:"userId"`
+	Day       time.Time `gorm:"type:date;not null;uniqueIndex:idx_user_day" json:"day"`
+	Followers int       `gorm:"not null;default:0" json:"followers"` // 当日净增粉丝数（取消关注时扣减）
+}
@@ -0,0 +1,13 @@
+package model
+
+import "gorm.io/gorm"
+
+// UserIdentity 外部身份绑定（OIDC 登录时 issuer + subject 唯一对应一个本地用户）
+type UserIdentity struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"userId"`                                       // 外键：本地用户ID
+	Provider string `gorm:"size:255;not null;uniqueIndex:idx_provider_subject" json:"provider"` // 身份提供方（issuer）
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_provider_subject" json:"subject"`  // 身份提供方中的用户标识（sub）
+	Email    string `gorm:"size:100" json:"email"`                                              // 登录时提供方返回的邮箱
+	User     User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook 可订阅的事件（与发件箱中的领域事件一致）
+const (
+	WebhookPostCreated    = EventPostCreated
+	WebhookPostUpdated    = EventPostUpdated
+	WebhookCommentCreated = EventCommentCreated
+	WebhookAllEvents      = "*"
+)
+
+// 投递状态
+const (
+	DeliveryPending = "pending" // 等待投递（含等待重试）
+	DeliverySuccess = "success" // 投递成功
+	DeliveryFailed  = "failed"  // 超过最大重试次数
+)
+
+// Webhook 外部系统注册的事件回调地址
+type Webhook struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index" json:"userId"`         // 外键：注册人ID
+	Global      bool   `gorm:"not null;default:false" json:"global"` // 管理员注册的全站 Webhook（否则只接收注册人自己文章上的事件）
+	URL         string `gorm:"size:500;not null" json:"url"`         // 回调地址
+	Secret      string `gorm:"size:64;not null" json:"-"`            // 签名密钥（只在创建时返回）
+	Events      string `gorm:"size:255;not null" json:"events"`      // 订阅的事件（逗号分隔，* 表示全部）
+	Description string `gorm:"size:255" json:"description"`          // 备注
+	Active      bool   `gorm:"not null;default:true" json:"active"`  // 是否启用
+}
+
+// WebhookDelivery Webhook 投递记录（同时作为持久化的重试队列）
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID      uint       `gorm:"not null;index" json:"webhookId"`                       // 外键：Webhook ID
+	Event          string     `gorm:"size:50;not null" json:"event"`                         // 事件
+	Payload        string     `gorm:"type:text;not null" json:"payload"`                     // 请求体（JSON）
+	Status         string     `gorm:"size:20;not null;default:pending" json:"status"`        // 投递状态：pending/success/failed
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`                    // 已尝试次数
+	NextAttemptAt  *time.Time `gorm:"index:idx_delivery_due" json:"nextAttemptAt,omitempty"` // 下次投递时间（pending 时有效）
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`                               // 最近一次投递时间
+	ResponseStatus int        `json:"responseStatus"`                                        // 最近一次响应状态码
+	ResponseBody   string     `gorm:"type:text" json:"responseBody"`                         // 最近一次响应内容（截断）
+	Error          string     `gorm:"size:500" json:"error"`                                 // 最近一次错误信息
+	OutboxEventID  uint       `gorm:"index" json:"outboxEventId"`                            // 来源的发件箱事件ID（用于去重）
+	RedeliveryOf   *uint      `json:"redeliveryOf,omitempty"`                                // 手动重新投递时指向原投递记录
+	Webhook        Webhook    `gorm:"foreignKey:WebhookID" json:"-"`
+}
@@ -5,45 +5,201 @@ import (
 	"go.uber.org/zap"
 	"gotask/task4/handler"
 	"gotask/task4/middleware"
+	"gotask/task4/model"
+	"gotask/task4/service"
 )
 
+// Handlers 路由用到的所有控制器
+type Handlers struct {
+	User         *handler.UserHandler
+	Post         *handler.PostHandler
+	Comment      *handler.CommentHandler
+	OAuth        *handler.OAuthHandler
+	Session      *handler.SessionHandler
+	Account      *handler.AccountHandler
+	Admin        *handler.AdminHandler
+	Follow       *handler.FollowHandler
+	Report       *handler.ReportHandler
+	Mention      *handler.MentionHandler
+	Notification *handler.NotificationHandler
+	Like         *handler.LikeHandler
+	Stream       *handler.StreamHandler
+	Collab       *handler.CollabHandler
+	Webhook      *handler.WebhookHandler
+	Attachment   *handler.AttachmentHandler
+	Avatar       *handler.AvatarHandler
+	Category     *handler.CategoryHandler
+	Series       *handler.SeriesHandler
+	Stats        *handler.StatsHandler
+	Import       *handler.ImportHandler
+	Sitemap      *handler.SitemapHandler
+	Outbox       *handler.OutboxHandler
+}
+
 // Setup 初始化路由
 func Setup(
 	r *gin.Engine,
-	userHandler *handler.UserHandler,
-	postHandler *handler.PostHandler,
-	commentHandler *handler.CommentHandler,
+	h Handlers,
+	sessionService *service.SessionService,
 	logger *zap.Logger,
 ) {
 	// 全局中间件
 	r.Use(middleware.Logger(logger))
 	r.Use(middleware.Recovery(logger))
 
+	// JWKS 公钥发布（供其他服务验签）
+	r.GET("/.well-known/jwks.json", handler.JWKS)
+
+	// 站点地图（公开，供搜索引擎抓取）
+	r.GET("/sitemap.xml", h.Sitemap.Index)
+	r.GET("/sitemaps/:file", h.Sitemap.File)
+
 	// 公开路由
 	public := r.Group("/api/v1")
 	{
 		// 用户相关
-		public.POST("/register", userHandler.Register)
-		public.POST("/login", userHandler.Login)
+		public.POST("/register", h.User.Register)
+		public.POST("/login", h.User.Login)
+		public.POST("/password/reset", h.User.ResetPassword)
+		public.GET("/oauth/login", h.OAuth.Login)
+		public.GET("/oauth/callback", h.OAuth.Callback)
 
 		// 文章相关（公开访问）
-		public.GET("/posts/:id", postHandler.Get)
-		public.GET("/posts/list/:userId", postHandler.ListByUserId)
-		public.GET("/posts/page", postHandler.Page)
+		public.GET("/posts/:id", h.Post.Get)
+		public.GET("/posts/:id/related", h.Post.Related)
+		public.GET("/posts/list/:userId", h.Post.ListByUserId)
+		public.GET("/posts/page", h.Post.Page)
+
+		// 分类（公开访问）
+		public.GET("/categories", h.Category.Tree)
+		public.GET("/categories/:id/posts", h.Category.Posts)
+
+		// 系列（公开访问）
+		public.GET("/series/:id", h.Series.Get)
+		public.GET("/users/:id/series", h.Series.ListByUser)
+
+		// 附件下载（公开访问）
+		public.GET("/attachments/:key", h.Attachment.Download)
+		public.GET("/attachments/:key/:variant", h.Attachment.Variant)
+
+		// 用户头像（公开访问，未上传时为默认头像）
+		public.GET("/users/:id/avatar", h.Avatar.Get)
+
+		// 粉丝列表（公开访问）
+		public.GET("/users/:id/followers", h.Follow.Followers)
 
 		// 评论相关（公开访问）
-		public.GET("/posts/comments/:postId", commentHandler.ListByPost)
+		public.GET("/posts/comments/:postId", h.Comment.ListByPost)
 	}
 
 	// 需要认证的路由（JWT验证）
 	auth := r.Group("/api/v1")
-	auth.Use(middleware.JWTAuth())
+	auth.Use(middleware.JWTAuth(sessionService))
 	{
+		// 登录会话（需登录）
+		auth.GET("/sessions", h.Session.List)
+		auth.DELETE("/sessions/:id", h.Session.Revoke)
+		auth.POST("/logout", h.Session.Logout)
+
+		// 绑定第三方账号（需登录）
+		auth.POST("/me/oauth/link", h.OAuth.Link)
+
+		// 个人数据导出与账号注销（需登录）
+		auth.GET("/me/export", h.Account.Export)
+		auth.DELETE("/me", h.Account.Delete)
+		auth.POST("/me/deletion/cancel", h.Account.CancelDeletion)
+
+		// 头像（需登录）
+		auth.POST("/me/avatar", h.Avatar.Upload)
+		auth.DELETE("/me/avatar", h.Avatar.Remove)
+
+		// 关注（需登录）
+		auth.POST("/users/:id/follow", h.Follow.Follow)
+		auth.DELETE("/users/:id/follow", h.Follow.Unfollow)
+
+		// 我的文章数据统计（需登录）
+		auth.GET("/me/stats", h.Stats.Mine)
+
+		// 提及我的内容（需登录）
+		auth.GET("/me/mentions", h.Mention.List)
+
+		// 站内通知（需登录）
+		auth.GET("/notifications", h.Notification.List)
+		auth.GET("/notifications/unread-count", h.Notification.UnreadCount)
+		auth.POST("/notifications/read", h.Notification.MarkRead)
+		auth.POST("/notifications/read-all", h.Notification.MarkAllRead)
+
+		// 实时推送新通知和文章新评论（SSE，需登录）
+		auth.GET("/events", h.Stream.Stream)
+
+		// 文章页实时协作（WebSocket，需登录）
+		auth.GET("/posts/:id/live", h.Collab.Connect)
+
+		// Webhook（需登录，只接收自己文章上的事件）
+		auth.POST("/webhooks", h.Webhook.Create)
+		auth.GET("/webhooks", h.Webhook.List)
+		auth.PUT("/webhooks/:id/active", h.Webhook.SetActive)
+		auth.DELETE("/webhooks/:id", h.Webhook.Delete)
+		auth.GET("/webhooks/:id/deliveries", h.Webhook.Deliveries)
+		auth.POST("/webhooks/deliveries/:id/redeliver", h.Webhook.Redeliver)
+
 		// 文章相关（需登录）
-		auth.POST("/posts", postHandler.Create)
-		auth.PUT("/posts/update", postHandler.Update)
+		auth.POST("/posts", h.Post.Create)
+		auth.PUT("/posts/update", h.Post.Update)
+		auth.PUT("/posts/comment-settings", h.Post.UpdateCommentSettings)
+
+		// 系列（需登录，仅作者可修改）
+		auth.POST("/series", h.Series.Create)
+		auth.PUT("/series/:id", h.Series.Update)
+		auth.DELETE("/series/:id", h.Series.Delete)
+		auth.PUT("/series/:id/posts", h.Series.SetPosts)
+
+		// 附件上传（需登录）
+		auth.POST("/attachments", h.Attachment.Upload)
 
 		// 评论相关（需登录）
-		auth.POST("/posts/addComments", commentHandler.Create)
+		auth.POST("/posts/addComments", h.Comment.Create)
+
+		// 点赞（需登录）
+		auth.POST("/likes", h.Like.Like)
+		auth.DELETE("/likes", h.Like.Unlike)
+
+		// 评论审核（审核员处理全部，作者处理自己文章下的评论）
+		auth.GET("/moderation/comments", h.Comment.ModerationQueue)
+		auth.POST("/moderation/comments", h.Comment.Moderate)
+
+		// 举报（需登录）
+		auth.POST("/reports", h.Report.Create)
+	}
+
+	// 审核员路由（需审核员或管理员角色）
+	moderator := r.Group("/api/v1/moderation")
+	moderator.Use(middleware.JWTAuth(sessionService), middleware.RequireRole(model.RoleModerator, model.RoleAdmin))
+	{
+		moderator.GET("/reports", h.Report.Queue)
+		moderator.GET("/reports/detail", h.Report.Detail)
+		moderator.POST("/reports/resolve", h.Report.Resolve)
+		moderator.POST("/reports/dismiss", h.Report.Dismiss)
+	}
+
+	// 管理后台路由（需管理员角色）
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.JWTAuth(sessionService), middleware.RequireRole(model.RoleAdmin))
+	{
+		admin.GET("/users", h.Admin.SearchUsers)
+		admin.POST("/users/:id/suspend", h.Admin.Suspend)
+		admin.POST("/users/:id/ban", h.Admin.Ban)
+		admin.POST("/users/:id/unban", h.Admin.Unban)
+		admin.PUT("/users/:id/role", h.Admin.SetRole)
+		admin.POST("/users/:id/reset-password", h.Admin.ForcePasswordReset)
+		admin.POST("/users/:id/impersonate", h.Admin.Impersonate)
+		admin.GET("/audit-logs", h.Admin.ListAuditLogs)
+		admin.POST("/webhooks", h.Webhook.CreateGlobal)
+		admin.POST("/categories", h.Category.Create)
+		admin.PUT("/categories/:id", h.Category.Update)
+		admin.DELETE("/categories/:id", h.Category.Delete)
+		admin.POST("/import", h.Import.Import)
+		admin.GET("/outbox/failed", h.Outbox.ListFailed)
+		admin.POST("/outbox/:id/replay", h.Outbox.Replay)
 	}
-}
\ No newline at end of file
+}
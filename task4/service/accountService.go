@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// AccountService 账号数据导出与注销服务
+type AccountService struct {
+	db             *gorm.DB
+	sessionService *SessionService
+	avatarService  *AvatarService
+}
+
+func NewAccountService(db *gorm.DB, sessionService *SessionService, avatarService *AvatarService) *AccountService {
+	return &AccountService{db: db, sessionService: sessionService, avatarService: avatarService}
+}
+
+// UserExport 用户个人数据导出内容
+type UserExport struct {
+	ExportedAt    time.Time            `json:"exportedAt"`
+	Profile       model.User           `json:"profile"`
+	Identities    []model.UserIdentity `json:"identities"`
+	Sessions      []model.Session      `json:"sessions"`
+	Posts         []model.Post         `json:"posts"`
+	Comments      []model.Comment      `json:"comments"`
+	Following     []model.Follow       `json:"following"`
+	Reports       []model.Report       `json:"reports"`
+	Likes         []model.Like         `json:"likes"`
+	Notifications []model.Notification `json:"notifications"`
+	Webhooks      []model.Webhook      `json:"webhooks"`
+	Attachments   []model.Attachment   `json:"attachments"`
+	Series        []model.Series       `json:"series"`
+}
+
+// Export 导出用户的个人资料、文章和评论
+func (s *AccountService) Export(userID uint) (*UserExport, error) {
+	export := UserExport{ExportedAt: time.Now()}
+	if err := s.db.First(&export.Profile, userID).Error; err != nil {
+		return nil, util.ErrUserNotExist
+	}
+	if err := s.db.Where("user_id = ?", userID).Find(&export.Identities).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Sessions).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Posts).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Comments).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("follower_id = ?", userID).Order("created_at").Find(&export.Following).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("reporter_id = ?", userID).Order("created_at").Find(&export.Reports).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Likes).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Notifications).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Webhooks).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Attachments).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Series).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// RequestDeletion 申请注销账号：宽限期后执行，期间所有登录会话立即失效
+func (s *AccountService) RequestDeletion(userID uint, postAction string) (*model.AccountDeletion, error) {
+	deletion := model.AccountDeletion{
+		UserID:      userID,
+		PostAction:  postAction,
+		ScheduledAt: time.Now().Add(config.Cfg.AccountConfig.DeletionGracePeriod),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 重复申请时覆盖之前的申请
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.AccountDeletion{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&deletion).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionService.RevokeAllByUser(userID); err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// CancelDeletion 撤销注销申请（宽限期内重新登录后可操作）
+func (s *AccountService) CancelDeletion(userID uint) error {
+	result := s.db.Unscoped().Where("user_id = ?", userID).Delete(&model.AccountDeletion{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return util.ErrDeletionNotExist
+	}
+	return nil
+}
+
+// PurgeDueAccounts 执行已到期的注销申请（定时任务调用），返回处理的账号数
+func (s *AccountService) PurgeDueAccounts() (int, error) {
+	var deletions []model.AccountDeletion
+	if err := s.db.Where("scheduled_at <= ?", time.Now()).Find(&deletions).Error; err != nil {
+		return 0, err
+	}
+	for i, deletion := range deletions {
+		if err := s.purge(&deletion); err != nil {
+			return i, err
+		}
+	}
+	return len(deletions), nil
+}
+
+// purge 删除账号：评论转给占位账号（匿名化），文章按申请删除或转给占位账号（同时标记站点地图过期），
+// 最后物理删除外部身份、会话和用户本身，提交后删除头像文件
+func (s *AccountService) purge(deletion *model.AccountDeletion) error {
+	userID := deletion.UserID
+	var avatarKey string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ghost, err := ghostUser(tx)
+		if err != nil {
+			return err
+		}
+		var user model.User
+		if err := tx.Unscoped().Select("id", "avatar_key").Where("id = ?", userID).Find(&user).Error; err != nil {
+			return err
+		}
+		avatarKey = user.AvatarKey
+		if err := invalidateSitemap(tx, "user_id = ?", userID); err != nil {
+			return err
+		}
+
+		if deletion.PostAction == model.DeletionPostDelete {
+			postIDs := tx.Unscoped().Model(&model.Post{}).Select("id").Where("user_id = ?", userID)
+			// 附件取消关联，由定时任务清理文件
+			if err := tx.Unscoped().Model(&model.Attachment{}).Where("post_id IN (?)", postIDs).
+				Update("post_id", nil).Error; err != nil {
+				return err
+			}
+			// 指向这些文章及其评论的点赞、举报、通知和提及一并删除（须在删除评论之前）
+			commentIDs := tx.Unscoped().Model(&model.Comment{}).Select("id").Where("post_id IN (?)", postIDs)
+			targets := "(target_type = ? AND target_id IN (?)) OR (target_type = ? AND target_id IN (?))"
+			if err := tx.Where(targets, model.LikeTargetPost, postIDs, model.LikeTargetComment, commentIDs).
+				Delete(&model.Like{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where(targets, model.ReportTargetPost, postIDs, model.ReportTargetComment, commentIDs).
+				Delete(&model.Report{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("post_id IN (?) OR "+targets, postIDs, model.LikeTargetPost, postIDs, model.LikeTargetComment, commentIDs).
+				Delete(&model.Notification{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("post_id IN (?)", postIDs).Delete(&model.Mention{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("post_id IN (?)", postIDs).Delete(&model.Comment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN (?)", postIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN (?) OR related_id IN (?)", postIDs, postIDs).Delete(&model.RelatedPost{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN (?)", postIDs).Delete(&model.PostDailyStat{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.Post{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.Series{}).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Unscoped().Model(&model.Post{}).Where("user_id = ?", userID).
+				Update("user_id", ghost.ID).Error; err != nil {
+				return err
+			}
+			if err := invalidateSitemap(tx, "user_id = ?", ghost.ID); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&model.Series{}).Where("user_id = ?", userID).
+				Update("user_id", ghost.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&model.Comment{}).Where("user_id = ?", userID).
+			Update("user_id", ghost.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Attachment{}).Where("user_id = ?", userID).
+			Update("user_id", ghost.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("follower_id = ? OR followee_id = ?", userID, userID).Delete(&model.Follow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserDailyStat{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("reporter_id = ?", userID).Delete(&model.Report{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("mentioned_user_id = ?", userID).Delete(&model.Mention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Mention{}).Where("author_id = ?", userID).
+			Update("author_id", ghost.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ? OR actor_id = ?", userID, userID).Delete(&model.Notification{}).Error; err != nil {
+			return err
+		}
+
+		// 撤销该用户的点赞（同步扣减点赞数）
+		for targetType, m := range map[string]interface{}{model.LikeTargetPost: &model.Post{}, model.LikeTargetComment: &model.Comment{}} {
+			likedIDs := tx.Model(&model.Like{}).Select("target_id").Where("user_id = ? AND target_type = ?", userID, targetType)
+			if err := tx.Unscoped().Model(m).Where("id IN (?) AND like_count > 0", likedIDs).
+				UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Like{}).Error; err != nil {
+			return err
+		}
+		webhookIDs := tx.Unscoped().Model(&model.Webhook{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Unscoped().Where("webhook_id IN (?)", webhookIDs).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.Webhook{}).Error; err != nil {
+			return err
+		}
+		for _, m := range []interface{}{&model.UserIdentity{}, &model.Session{}, &model.AccountDeletion{}} {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&model.User{}, userID).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.avatarService.deleteFiles(context.Background(), userID, avatarKey)
+}
+
+// ghostUser 获取（不存在时创建）已注销用户的占位账号（按保留角色查找，注册的用户不可能冒充）
+func ghostUser(tx *gorm.DB) (*model.User, error) {
+	var ghost model.User
+	err := tx.Where("role = ?", model.RoleGhost).Order("id").First(&ghost).Error
+	if err == nil {
+		return &ghost, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	username := model.GhostUsername
+	var count int64
+	for i := 0; i < 5; i++ {
+		if err := tx.Model(&model.User{}).Unscoped().Where("username = ?", username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		username = model.GhostUsername + "_" + util.RandomHex(2)
+	}
+	ghost = model.User{
+		Username: username,
+		Email:    username + "@users.invalid",
+		Password: util.RandomHex(16), // 随机密码，占位账号不可登录
+		Role:     model.RoleGhost,
+	}
+	if err := tx.Create(&ghost).Error; err != nil {
+		return nil, err
+	}
+	return &ghost, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gotask/task4/model"
+)
+
+func TestGhostUserIgnoresRegisteredUsername(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken bool // 已有真实用户占用了占位账号的用户名（例如通过 OIDC 首次登录创建）
+	}{
+		{"username free", false},
+		{"username taken", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			var impostor *model.User
+			if tt.taken {
+				impostor = createTestUser(t, db, model.GhostUsername)
+			}
+
+			ghost, err := ghostUser(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ghost.Role != model.RoleGhost {
+				t.Fatalf("ghost role = %q, want %q", ghost.Role, model.RoleGhost)
+			}
+			if impostor != nil && ghost.ID == impostor.ID {
+				t.Fatalf("ghostUser returned the registered user %q", impostor.Username)
+			}
+			if !tt.taken && ghost.Username != model.GhostUsername {
+				t.Fatalf("ghost username = %q, want %q", ghost.Username, model.GhostUsername)
+			}
+
+			again, err := ghostUser(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.ID != ghost.ID {
+				t.Fatalf("second ghostUser = %d, want %d", again.ID, ghost.ID)
+			}
+		})
+	}
+}
+
+func TestSetRoleRejectsGhost(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "admin")
+	ghost, err := ghostUser(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewAdminService(db, NewSessionService(db))
+	if err := s.SetRole(Operator{UserID: admin.ID}, ghost.ID, model.RoleAdmin); err == nil {
+		t.Fatal("SetRole on ghost account succeeded")
+	}
+	var got model.User
+	if err := db.First(&got, ghost.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != model.RoleGhost {
+		t.Fatalf("ghost role = %q after SetRole", got.Role)
+	}
+}
+
+func TestPurgeDeletesReferencesToDeletedPosts(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	reader := createTestUser(t, db, "reader")
+	deleted := createTestPost(t, db, author.ID, "deleted")
+	kept := createTestPost(t, db, reader.ID, "kept")
+
+	// 每篇文章各有一条评论，以及指向文章和评论的点赞、举报、通知、提及
+	for _, post := range []*model.Post{deleted, kept} {
+		comment := model.Comment{Content: "comment", PostID: post.ID, UserID: reader.ID, Status: model.CommentApproved}
+		if err := db.Create(&comment).Error; err != nil {
+			t.Fatal(err)
+		}
+		rows := []interface{}{
+			&model.Like{UserID: reader.ID, TargetType: model.LikeTargetPost, TargetID: post.ID},
+			&model.Like{UserID: reader.ID, TargetType: model.LikeTargetComment, TargetID: comment.ID},
+			&model.Report{ReporterID: reader.ID, TargetType: model.ReportTargetPost, TargetID: post.ID, Reason: "spam"},
+			&model.Report{ReporterID: reader.ID, TargetType: model.ReportTargetComment, TargetID: comment.ID, Reason: "spam"},
+			&model.Notification{UserID: reader.ID, ActorID: reader.ID, Type: model.NotifyReply,
+				TargetType: model.LikeTargetComment, TargetID: comment.ID, PostID: post.ID},
+			&model.Mention{SourceType: model.MentionSourceComment, SourceID: comment.ID,
+				MentionedUserID: reader.ID, AuthorID: reader.ID, PostID: post.ID},
+		}
+		for _, row := range rows {
+			if err := db.Create(row).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := db.Create(&model.AccountDeletion{UserID: author.ID, PostAction: model.DeletionPostDelete,
+		ScheduledAt: time.Now().Add(-time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := NewAccountService(db, NewSessionService(db), NewAvatarService(db, nil))
+	if n, err := s.PurgeDueAccounts(); err != nil || n != 1 {
+		t.Fatalf("PurgeDueAccounts() = %d, %v", n, err)
+	}
+
+	tests := []struct {
+		name  string
+		model interface{}
+		want  int64
+	}{
+		{"posts", &model.Post{}, 1},
+		{"comments", &model.Comment{}, 1},
+		{"likes", &model.Like{}, 2},
+		{"reports", &model.Report{}, 2},
+		{"notifications", &model.Notification{}, 1},
+		{"mentions", &model.Mention{}, 1},
+	}
+	for _, tt := range tests {
+		var count int64
+		if err := db.Unscoped().Model(tt.model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != tt.want {
+			t.Fatalf("%s = %d, want %d (only rows of the kept post)", tt.name, count, tt.want)
+		}
+	}
+	var keptPost model.Post
+	if err := db.First(&keptPost, kept.ID).Error; err != nil {
+		t.Fatalf("kept post: %v", err)
+	}
+}
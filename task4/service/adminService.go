@@ -0,0 +1,221 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// Operator 执行管理操作的用户（写入审计日志）
+type Operator struct {
+	UserID uint
+	IP     string
+}
+
+// AdminService 管理后台服务（用户管理，所有写操作记录审计日志）
+type AdminService struct {
+	db             *gorm.DB
+	sessionService *SessionService
+}
+
+func NewAdminService(db *gorm.DB, sessionService *SessionService) *AdminService {
+	return &AdminService{db: db, sessionService: sessionService}
+}
+
+// UserQuery 用户搜索条件
+type UserQuery struct {
+	Keyword string // 匹配用户名或邮箱
+	Status  string // active/suspended/banned，为空不过滤
+	Role    string
+}
+
+// SearchUsers 分页搜索用户
+func (s *AdminService) SearchUsers(query UserQuery, page, pageSize int) (*util.PageResult, error) {
+	var (
+		users []model.User
+		total int64
+	)
+	db := s.db.Model(&model.User{})
+	if query.Keyword != "" {
+		like := "%" + query.Keyword + "%"
+		db = db.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+	if query.Role != "" {
+		db = db.Where("role = ?", query.Role)
+	}
+	now := time.Now()
+	switch query.Status {
+	case "banned":
+		db = db.Where("banned_at IS NOT NULL")
+	case "suspended":
+		db = db.Where("banned_at IS NULL AND suspended_until > ?", now)
+	case "active":
+		db = db.Where("banned_at IS NULL AND (suspended_until IS NULL OR suspended_until <= ?)", now)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Order("id DESC").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(users, total, page, pageSize), nil
+}
+
+// Suspend 临时封禁用户（到期自动解除），已登录的 token 立即失效
+func (s *AdminService) Suspend(op Operator, userID uint, duration time.Duration, reason string) error {
+	until := time.Now().Add(duration)
+	return s.updateUser(op, userID, "user.suspend", map[string]interface{}{
+		"suspended_until": until,
+		"ban_reason":      reason,
+	}, map[string]interface{}{"until": until, "reason": reason})
+}
+
+// Ban 永久封禁用户
+func (s *AdminService) Ban(op Operator, userID uint, reason string) error {
+	return s.updateUser(op, userID, "user.ban", map[string]interface{}{
+		"banned_at":  time.Now(),
+		"ban_reason": reason,
+	}, map[string]interface{}{"reason": reason})
+}
+
+// Unban 解除封禁（同时解除永久和临时封禁）
+func (s *AdminService) Unban(op Operator, userID uint) error {
+	return s.updateUser(op, userID, "user.unban", map[string]interface{}{
+		"banned_at":       nil,
+		"suspended_until": nil,
+		"ban_reason":      "",
+	}, nil)
+}
+
+// SetRole 修改用户角色
+func (s *AdminService) SetRole(op Operator, userID uint, role string) error {
+	return s.updateUser(op, userID, "user.role", map[string]interface{}{
+		"role": role,
+	}, map[string]interface{}{"role": role})
+}
+
+// cliOperator 命令行操作在审计日志中的操作人（没有登录用户）
+var cliOperator = Operator{UserID: 0, IP: "cli"}
+
+// PromoteByUsername 通过命令行修改用户角色（用于设置第一个管理员），记录审计日志
+func (s *AdminService) PromoteByUsername(username, role string) error {
+	if role != model.RoleUser && role != model.RoleModerator && role != model.RoleAdmin {
+		return util.NewErrno(util.ErrInvalidParam, "不支持的角色: %s", role)
+	}
+	var user model.User
+	if err := s.db.Select("id", "role").Where("username = ?", username).First(&user).Error; err != nil {
+		return util.ErrUserNotExist
+	}
+	return s.updateUser(cliOperator, user.ID, "user.role", map[string]interface{}{
+		"role": role,
+	}, map[string]interface{}{"role": role, "previous": user.Role})
+}
+
+// ForcePasswordReset 强制用户重置密码：吊销所有会话，禁止密码登录，
+// 返回一次性重置令牌（由管理员通过其他渠道交给用户）
+func (s *AdminService) ForcePasswordReset(op Operator, userID uint) (string, error) {
+	token := util.RandomHex(24)
+	expires := time.Now().Add(config.Cfg.AdminConfig.PasswordResetTTL)
+	err := s.updateUser(op, userID, "user.password_reset", map[string]interface{}{
+		"password_reset_token":   util.HashToken(token),
+		"password_reset_expires": expires,
+	}, map[string]interface{}{"expires": expires})
+	if err != nil {
+		return "", err
+	}
+	if err := s.sessionService.RevokeAllByUser(userID); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Impersonate 签发代登录目标用户的短期 token（用于客服排查问题，必须填写原因）；
+// 不能代登录审核员或管理员，避免借代登录获得其权限
+func (s *AdminService) Impersonate(op Operator, userID uint, reason string, client ClientInfo) (string, error) {
+	var user model.User
+	if err := s.db.Where("role <> ?", model.RoleGhost).First(&user, userID).Error; err != nil {
+		return "", util.ErrUserNotExist
+	}
+	if user.Role == model.RoleModerator || user.Role == model.RoleAdmin {
+		return "", util.NewErrno(util.ErrNoPermission, "不能代登录审核员或管理员")
+	}
+	if err := writeAudit(s.db, op, "user.impersonate", "user", userID, map[string]interface{}{"reason": reason}); err != nil {
+		return "", err
+	}
+	return s.sessionService.IssueImpersonation(&user, op.UserID, client)
+}
+
+// ListAuditLogs 分页查询审计日志（可按操作对象过滤）
+func (s *AdminService) ListAuditLogs(targetType string, targetID uint, page, pageSize int) (*util.PageResult, error) {
+	var (
+		logs  []model.AuditLog
+		total int64
+	)
+	db := s.db.Model(&model.AuditLog{})
+	if targetType != "" {
+		db = db.Where("target_type = ?", targetType)
+	}
+	if targetID != 0 {
+		db = db.Where("target_id = ?", targetID)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(logs, total, page, pageSize), nil
+}
+
+// updateUser 在同一事务中更新用户字段并写入审计日志
+func (s *AdminService) updateUser(op Operator, userID uint, action string, updates map[string]interface{}, detail interface{}) error {
+	if userID == op.UserID {
+		return util.NewErrno(util.ErrNoPermission, "不能对自己执行该操作")
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return util.ErrUserNotExist
+		}
+		if user.Role == model.RoleGhost {
+			return util.NewErrno(util.ErrNoPermission, "不能修改已注销用户的占位账号")
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 封禁和解封改变作者主页是否收录进 sitemap
+		if _, ok := updates["banned_at"]; ok {
+			if err := invalidateSitemap(tx, "user_id = ?", userID); err != nil {
+				return err
+			}
+		}
+		return writeAudit(tx, op, action, "user", userID, detail)
+	})
+}
+
+// writeAudit 写入一条审计日志
+func writeAudit(tx *gorm.DB, op Operator, action, targetType string, targetID uint, detail interface{}) error {
+	var detailJSON string
+	if detail != nil {
+		b, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		detailJSON = string(b)
+	}
+	return tx.Create(&model.AuditLog{
+		ActorID:    op.UserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Detail:     detailJSON,
+		IP:         op.IP,
+	}).Error
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+
+	"gotask/task4/model"
+)
+
+func TestPromoteByUsername(t *testing.T) {
+	db := newTestDB(t)
+	createTestUser(t, db, "alice")
+	ghost, err := ghostUser(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewAdminService(db, NewSessionService(db))
+
+	tests := []struct {
+		name     string
+		username string
+		role     string
+		wantErr  bool
+	}{
+		{"promote to admin", "alice", model.RoleAdmin, false},
+		{"unknown role", "alice", "owner", true},
+		{"unknown user", "nobody", model.RoleAdmin, true},
+		{"ghost account", ghost.Username, model.RoleAdmin, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.PromoteByUsername(tt.username, tt.role); (err != nil) != tt.wantErr {
+				t.Fatalf("PromoteByUsername(%q, %q) = %v, wantErr %v", tt.username, tt.role, err, tt.wantErr)
+			}
+		})
+	}
+
+	var user model.User
+	db.Where("username = ?", "alice").First(&user)
+	if user.Role != model.RoleAdmin {
+		t.Fatalf("role = %s, want admin", user.Role)
+	}
+	var logs []model.AuditLog
+	db.Where("action = ? AND target_id = ?", "user.role", user.ID).Find(&logs)
+	if len(logs) != 1 || logs[0].ActorID != 0 || logs[0].IP != "cli" {
+		t.Fatalf("audit logs = %+v, want one cli entry", logs)
+	}
+}
+
+func TestImpersonateRejectsPrivilegedTargets(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "admin")
+	s := NewAdminService(db, NewSessionService(db))
+	op := Operator{UserID: admin.ID}
+
+	tests := []struct {
+		role    string
+		wantErr bool
+	}{
+		{model.RoleUser, false},
+		{model.RoleModerator, true},
+		{model.RoleAdmin, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			target := createTestUser(t, db, "target-"+tt.role)
+			if err := db.Model(target).Update("role", tt.role).Error; err != nil {
+				t.Fatal(err)
+			}
+			token, err := s.Impersonate(op, target.ID, "support ticket", ClientInfo{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Impersonate(%s) = %v, wantErr %v", tt.role, err, tt.wantErr)
+			}
+			if tt.wantErr && token != "" {
+				t.Fatal("token issued for a privileged target")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+	"gotask/task4/util"
+)
+
+// 识别文件类型需要读取的字节数（与 http.DetectContentType 一致）
+const sniffLen = 512
+
+// 文件类型对应的扩展名
+var typeExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// AttachmentService 附件服务
+type AttachmentService struct {
+	db     *gorm.DB
+	store  storage.Storage
+	images *ImageService
+}
+
+func NewAttachmentService(db *gorm.DB, store storage.Storage, images *ImageService) *AttachmentService {
+	return &AttachmentService{db: db, store: store, images: images}
+}
+
+// Upload 上传附件：按文件内容（而非扩展名）识别类型并校验大小，上传后需在发布或修改文章时关联
+func (s *AttachmentService) Upload(ctx context.Context, userID uint, filename string, r io.Reader, size int64) (*model.Attachment, error) {
+	cfg := config.Cfg.StorageConfig
+	if size > cfg.MaxSize {
+		return nil, util.ErrFileTooLarge
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowedType(contentType) {
+		return nil, util.ErrFileType
+	}
+
+	attachment := model.Attachment{
+		UserID:      userID,
+		Key:         util.RandomHex(16) + typeExtensions[contentType],
+		Filename:    truncate(filepath.Base(filename), 255),
+		ContentType: contentType,
+		Size:        size,
+	}
+	body := io.MultiReader(bytes.NewReader(head), r)
+	if isImage(contentType) {
+		// 图片整体读入后去除 EXIF 等元数据（可能含拍摄位置），大小已在上面校验
+		data, err := io.ReadAll(io.LimitReader(body, cfg.MaxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if data, attachment.Width, attachment.Height, err = s.images.Sanitize(data, contentType); err != nil {
+			return nil, util.ErrFileType
+		}
+		attachment.Size = int64(len(data))
+		body = bytes.NewReader(data)
+	}
+	if err := s.store.Put(ctx, attachment.Key, body, attachment.Size, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&attachment).Error; err != nil {
+		s.store.Delete(ctx, attachment.Key)
+		return nil, err
+	}
+	s.images.Enqueue(&attachment)
+	return &attachment, nil
+}
+
+// Open 读取附件内容（所属文章被隐藏时不可访问）
+func (s *AttachmentService) Open(ctx context.Context, key string) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := s.find(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, err := s.store.Open(ctx, attachment.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, util.ErrAttachmentInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, rc, nil
+}
+
+// OpenVariant 读取图片缩略图（variant 形如 w480.jpg），返回缩略图内容和类型；缩略图缺失时重新生成
+func (s *AttachmentService) OpenVariant(ctx context.Context, key, variant string) (io.ReadCloser, string, error) {
+	attachment, err := s.find(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rc, contentType, err := s.images.OpenVariant(ctx, attachment, variant)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", util.ErrAttachmentInvalid
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return rc, contentType, nil
+}
+
+// find 查询可访问的附件
+func (s *AttachmentService) find(key string) (*model.Attachment, error) {
+	var attachment model.Attachment
+	if err := s.db.Where("`key` = ?", key).First(&attachment).Error; err != nil {
+		return nil, util.ErrAttachmentInvalid
+	}
+	if attachment.PostID != nil {
+		hidden, err := isHidden(s.db, model.ReportTargetPost, *attachment.PostID)
+		if err != nil {
+			return nil, err
+		}
+		if hidden {
+			return nil, util.ErrAttachmentInvalid
+		}
+	}
+	return &attachment, nil
+}
+
+// CollectOrphans 清理上传后超时仍未关联文章的附件（定时任务调用），返回清理的数量
+func (s *AttachmentService) CollectOrphans(ctx context.Context) (int, error) {
+	var orphans []model.Attachment
+	if err := s.db.Unscoped().
+		Where("post_id IS NULL AND created_at < ?", time.Now().Add(-config.Cfg.StorageConfig.OrphanTTL)).
+		Limit(500).Find(&orphans).Error; err != nil {
+		return 0, err
+	}
+	for i, attachment := range orphans {
+		if err := s.images.DeleteVariants(ctx, &attachment); err != nil {
+			return i, err
+		}
+		if err := s.store.Delete(ctx, attachment.Key); err != nil {
+			return i, err
+		}
+		if err := s.db.Unscoped().Delete(&attachment).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(orphans), nil
+}
+
+// linkAttachments 把文章的附件设置为 ids：关联新的附件（需为作者本人上传且未关联其他文章），
+// 取消关联不在列表中的附件（之后由定时任务清理）
+func linkAttachments(tx *gorm.DB, postID, userID uint, ids []uint) error {
+	unlink := tx.Model(&model.Attachment{}).Where("post_id = ?", postID)
+	if len(ids) > 0 {
+		unlink = unlink.Where("id NOT IN ?", ids)
+	}
+	if err := unlink.Update("post_id", nil).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	result := tx.Model(&model.Attachment{}).
+		Where("id IN ? AND user_id = ? AND (post_id IS NULL OR post_id = ?)", ids, userID, postID).
+		Update("post_id", postID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(ids)) {
+		var count int64
+		if err := tx.Model(&model.Attachment{}).Where("id IN ? AND post_id = ?", ids, postID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(ids)) {
+			return util.ErrAttachmentInvalid
+		}
+	}
+	return nil
+}
+
+// allowedType 判断文件类型是否允许上传
+func allowedType(contentType string) bool {
+	for _, t := range config.Cfg.StorageConfig.AllowedTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/imaging"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+	"gotask/task4/util"
+)
+
+// 允许作为头像上传的图片类型（均可解码）
+var avatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AvatarService 用户头像服务：上传的头像裁剪为正方形后按配置的尺寸保存，未上传时使用根据用户ID生成的默认头像
+type AvatarService struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewAvatarService(db *gorm.DB, store storage.Storage) *AvatarService {
+	return &AvatarService{db: db, store: store}
+}
+
+// Upload 上传头像，crop 为裁剪区域（相对于摆正后的原图，nil 表示取居中的最大正方形），返回新的头像地址
+func (s *AvatarService) Upload(ctx context.Context, userID uint, r io.Reader, size int64, crop *image.Rectangle) (string, error) {
+	if size > config.Cfg.StorageConfig.MaxSize {
+		return "", util.ErrFileTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(r, config.Cfg.StorageConfig.MaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !avatarTypes[contentType] {
+		return "", util.ErrFileType
+	}
+	img, err := imaging.Decode(data, config.Cfg.ImageConfig.MaxPixels)
+	if err != nil {
+		return "", util.ErrFileType
+	}
+
+	if crop == nil {
+		b := img.Bounds()
+		side := min(b.Dx(), b.Dy())
+		rect := image.Rect(0, 0, side, side).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+		crop = &rect
+	}
+	img, ok := imaging.Crop(img, *crop)
+	if !ok {
+		return "", util.NewErrno(util.ErrInvalidParam, "裁剪区域超出图片范围")
+	}
+
+	key := util.RandomHex(8)
+	for _, side := range config.Cfg.ImageConfig.AvatarSizes {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Resize(img, side), "jpeg", config.Cfg.ImageConfig.Quality); err != nil {
+			return "", err
+		}
+		if err := s.store.Put(ctx, avatarKey(userID, key, side), &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return "", err
+		}
+	}
+
+	old, err := s.setKey(userID, key)
+	if err != nil {
+		s.deleteFiles(ctx, userID, key)
+		return "", err
+	}
+	s.deleteFiles(ctx, userID, old)
+	return model.AvatarURL(userID, key), nil
+}
+
+// Remove 删除已上传的头像，恢复为默认头像
+func (s *AvatarService) Remove(ctx context.Context, userID uint) error {
+	old, err := s.setKey(userID, "")
+	if err != nil {
+		return err
+	}
+	return s.deleteFiles(ctx, userID, old)
+}
+
+// Open 读取用户头像，size 不在配置的尺寸中时使用默认尺寸；未上传头像时返回生成的默认头像
+func (s *AvatarService) Open(ctx context.Context, userID uint, size int) (io.ReadCloser, string, error) {
+	sizes := config.Cfg.ImageConfig.AvatarSizes
+	if !containsInt(sizes, size) {
+		size = sizes[0]
+	}
+
+	var user model.User
+	if err := s.db.Select("id", "avatar_key").First(&user, userID).Error; err != nil {
+		return nil, "", util.ErrUserNotExist
+	}
+	if user.AvatarKey != "" {
+		rc, err := s.store.Open(ctx, avatarKey(userID, user.AvatarKey, size))
+		if err == nil {
+			return rc, "image/jpeg", nil
+		}
+		if err != storage.ErrNotFound {
+			return nil, "", err
+		}
+		// 调整头像尺寸配置后旧头像缺少新尺寸，使用默认头像
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, imaging.Identicon([]byte(strconv.FormatUint(uint64(userID), 10)), size)); err != nil {
+		return nil, "", err
+	}
+	return io.NopCloser(&buf), "image/png", nil
+}
+
+// setKey 更新用户的头像标识，返回原来的标识
+func (s *AvatarService) setKey(userID uint, key string) (string, error) {
+	var old string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Select("id", "avatar_key").First(&user, userID).Error; err != nil {
+			return util.ErrUserNotExist
+		}
+		old = user.AvatarKey
+		return tx.Model(&user).UpdateColumn("avatar_key", key).Error // 跳过 BeforeSave（未查询密码）
+	})
+	return old, err
+}
+
+// deleteFiles 删除头像的全部尺寸
+func (s *AvatarService) deleteFiles(ctx context.Context, userID uint, key string) error {
+	if key == "" {
+		return nil
+	}
+	for _, side := range config.Cfg.ImageConfig.AvatarSizes {
+		if err := s.store.Delete(ctx, avatarKey(userID, key, side)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// avatarKey 头像在存储中的文件名
+func avatarKey(userID uint, key string, size int) string {
+	return fmt.Sprintf("%s%d.jpg", avatarPrefix(userID, key), size)
+}
+
+// avatarPrefix 头像各尺寸文件名的公共前缀
+func avatarPrefix(userID uint, key string) string {
+	return fmt.Sprintf("avatars/%d/%s_", userID, key)
+}
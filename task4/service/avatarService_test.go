@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+	"gotask/task4/util"
+)
+
+// openAvatar 读取头像并解码，返回内容类型和图片
+func openAvatar(t *testing.T, s *AvatarService, userID uint, size int) (string, image.Image) {
+	t.Helper()
+	rc, contentType, err := s.Open(context.Background(), userID, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contentType, img
+}
+
+func TestAvatarUploadAndOpen(t *testing.T) {
+	db := newTestDB(t)
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := createTestUser(t, db, "alice")
+	s := NewAvatarService(db, store)
+	ctx := context.Background()
+	// 原图大于最大的头像尺寸（缩放不会放大图片）
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 240))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, src); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未上传时返回确定的默认头像
+	contentType, img := openAvatar(t, s, user.ID, 40)
+	if contentType != "image/png" || img.Bounds().Dx() != 40 {
+		t.Fatalf("default avatar = %s %v, want 40px png", contentType, img.Bounds())
+	}
+	_, again := openAvatar(t, s, user.ID, 40)
+	if img.At(20, 20) != again.At(20, 20) {
+		t.Fatal("default avatar is not deterministic")
+	}
+
+	url, err := s.Upload(ctx, user.ID, bytes.NewReader(pngData.Bytes()), int64(pngData.Len()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored model.User
+	db.First(&stored, user.ID)
+	if stored.AvatarKey == "" || url != model.AvatarURL(user.ID, stored.AvatarKey) {
+		t.Fatalf("url = %s, avatar_key = %q", url, stored.AvatarKey)
+	}
+	sizes := []struct {
+		name     string
+		size     int
+		wantSide int
+	}{
+		{"configured size", 40, 40},
+		{"largest size", 200, 200},
+		{"unknown size uses default", 33, 80},
+	}
+	for _, tt := range sizes {
+		contentType, img := openAvatar(t, s, user.ID, tt.size)
+		if b := img.Bounds(); contentType != "image/jpeg" || b.Dx() != tt.wantSide || b.Dy() != tt.wantSide {
+			t.Fatalf("%s: avatar = %s %v, want %dpx jpeg", tt.name, contentType, b, tt.wantSide)
+		}
+	}
+
+	// 重新上传后删除旧头像的全部尺寸
+	oldKey := stored.AvatarKey
+	crop := image.Rect(0, 0, 10, 10)
+	if _, err := s.Upload(ctx, user.ID, bytes.NewReader(pngData.Bytes()), int64(pngData.Len()), &crop); err != nil {
+		t.Fatal(err)
+	}
+	for _, side := range config.Cfg.ImageConfig.AvatarSizes {
+		if _, err := store.Open(ctx, avatarKey(user.ID, oldKey, side)); err != storage.ErrNotFound {
+			t.Fatalf("old %dpx avatar: err = %v, want ErrNotFound", side, err)
+		}
+	}
+
+	// 删除头像后恢复为默认头像
+	db.First(&stored, user.ID)
+	if err := s.Remove(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if contentType, _ := openAvatar(t, s, user.ID, 80); contentType != "image/png" {
+		t.Fatalf("after remove content type = %s, want image/png", contentType)
+	}
+	if _, err := store.Open(ctx, avatarKey(user.ID, stored.AvatarKey, 80)); err != storage.ErrNotFound {
+		t.Fatalf("removed avatar: err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := s.Open(ctx, 999, 80); err != util.ErrUserNotExist {
+		t.Fatalf("unknown user: err = %v, want ErrUserNotExist", err)
+	}
+}
+
+func TestAvatarUploadRejects(t *testing.T) {
+	db := newTestDB(t)
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := createTestUser(t, db, "alice")
+	s := NewAvatarService(db, store)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	outside := image.Rect(40, 40, 80, 80)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		size    int64
+		crop    *image.Rectangle
+		wantErr *util.Errno
+	}{
+		{"too large", pngData.Bytes(), config.Cfg.StorageConfig.MaxSize + 1, nil, util.ErrFileTooLarge},
+		{"not an image", []byte(strings.Repeat("plain text ", 10)), 110, nil, util.ErrFileType},
+		{"truncated image", pngData.Bytes()[:100], 100, nil, util.ErrFileType},
+		{"crop outside image", pngData.Bytes(), int64(pngData.Len()), &outside, util.ErrInvalidParam},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Upload(context.Background(), user.ID, bytes.NewReader(tt.data), tt.size, tt.crop)
+			errno, ok := err.(*util.Errno)
+			if !ok || errno.Code != tt.wantErr.Code {
+				t.Fatalf("Upload() error = %v, want %v", err, tt.wantErr)
+			}
+			var stored model.User
+			db.First(&stored, user.ID)
+			if stored.AvatarKey != "" {
+				t.Fatalf("avatar_key = %q after rejected upload", stored.AvatarKey)
+			}
+		})
+	}
+}
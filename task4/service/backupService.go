@@ -0,0 +1,492 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+)
+
+// 站点备份格式标识和版本（格式不兼容地变化时递增版本号）
+const (
+	BackupFormat  = "gotask-site-backup"
+	BackupVersion = 1
+)
+
+// 备份文件中的固定文件
+const (
+	backupManifestFile = "manifest.json"
+	backupFilesIndex   = "files.jsonl"
+	backupFilesDir     = "files/"
+	backupBatchSize    = 500
+)
+
+// backupPostTag 文章与标签的关联（many2many 连接表没有对应的模型）
+type backupPostTag struct {
+	PostID uint
+	TagID  uint
+}
+
+func (backupPostTag) TableName() string { return "post_tags" }
+
+// backupEntities 参与备份的表，按外键依赖排列（恢复时依次插入）
+var backupEntities = []struct {
+	model interface{}
+	order string
+}{
+	{&model.User{}, "id"},
+	{&model.UserIdentity{}, "id"},
+	{&model.AccountDeletion{}, "id"},
+	{&model.Category{}, "id"},
+	{&model.Tag{}, "id"},
+	{&model.Series{}, "id"},
+	{&model.Post{}, "id"},
+	{&backupPostTag{}, "post_id, tag_id"},
+	{&model.Attachment{}, "id"},
+	{&model.Comment{}, "id"},
+	{&model.Follow{}, "id"},
+	{&model.Like{}, "id"},
+	{&model.Mention{}, "id"},
+	{&model.Notification{}, "id"},
+	{&model.Report{}, "id"},
+	{&model.Webhook{}, "id"},
+	{&model.AuditLog{}, "id"},
+	{&model.ImportRecord{}, "id"},
+	{&model.PostDailyStat{}, "id"},
+	{&model.UserDailyStat{}, "id"},
+}
+
+// backupExcluded 不参与备份的表：登录会话和进行中的外部登录（恢复后重新登录）、投递记录和发件箱（临时数据）、相关文章和站点地图（缓存，访问时重新生成）
+var backupExcluded = []string{"sessions", "oauth_logins", "webhook_deliveries", "outbox_events", "related_posts", "sitemap_chunks"}
+
+// backupExcludedColumns 不参与备份的列（表名.列名）：相关文章不备份，其计算时间恢复后为空，由定时任务重新计算
+var backupExcludedColumns = map[string]bool{"posts.related_computed_at": true}
+
+// BackupManifest 备份清单（manifest.json），描述备份的格式版本和包含的内容
+type BackupManifest struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Entities  []BackupEntity  `json:"entities"` // 按恢复顺序排列
+	Files     BackupFileStats `json:"files"`
+	Excluded  []string        `json:"excluded"` // 未备份的表
+}
+
+// BackupEntity 一张表的备份：每行一个 JSON 对象，字段名为列名
+type BackupEntity struct {
+	Name    string   `json:"name"` // 表名
+	File    string   `json:"file"`
+	Count   int      `json:"count"`
+	Columns []string `json:"columns"`
+}
+
+// BackupFileStats 存储中的文件（附件原图和头像的全部尺寸，缩略图访问时重新生成），files.jsonl 为文件索引
+type BackupFileStats struct {
+	Index   string `json:"index"`
+	Count   int    `json:"count"`
+	Missing int    `json:"missing"` // 记录存在但存储中已找不到的文件数（头像按用户计）
+}
+
+// backupFile 文件索引中的一行，文件内容位于 files/<key>
+type backupFile struct {
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// BackupService 站点完整备份与恢复服务（命令行使用）
+type BackupService struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewBackupService(db *gorm.DB, store storage.Storage) *BackupService {
+	return &BackupService{db: db, store: store}
+}
+
+// Export 把所有表和存储中的文件写入 zip 备份，保留ID和时间戳（软删除的记录也一并导出）。
+// 全部读取在同一个只读的 REPEATABLE READ 事务中进行（InnoDB 在第一次读取时建立一致性快照），
+// 导出期间的写入不会让各表之间的数据不一致
+func (s *BackupService) Export(ctx context.Context, w io.Writer) (*BackupManifest, error) {
+	zw := zip.NewWriter(w)
+	manifest := BackupManifest{
+		Format:    BackupFormat,
+		Version:   BackupVersion,
+		CreatedAt: time.Now(),
+		Excluded:  backupExcluded,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, e := range backupEntities {
+			entity, err := s.exportEntity(ctx, tx, zw, e.model, e.order)
+			if err != nil {
+				return err
+			}
+			manifest.Entities = append(manifest.Entities, *entity)
+		}
+		files, err := s.exportFiles(ctx, tx, zw)
+		if err != nil {
+			return err
+		}
+		manifest.Files = *files
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	mw, err := zw.Create(backupManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(mw)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// exportEntity 逐行读取一张表写入 data/<表名>.jsonl
+func (s *BackupService) exportEntity(ctx context.Context, tx *gorm.DB, zw *zip.Writer, m interface{}, order string) (*BackupEntity, error) {
+	sch, err := s.parseSchema(m)
+	if err != nil {
+		return nil, err
+	}
+	fields := backupFields(sch)
+	entity := BackupEntity{Name: sch.Table, File: "data/" + sch.Table + ".jsonl"}
+	for _, field := range fields {
+		entity.Columns = append(entity.Columns, field.DBName)
+	}
+
+	fw, err := zw.Create(entity.File)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Unscoped().Model(m).Order(order).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	encoder := json.NewEncoder(fw)
+	for rows.Next() {
+		rv := reflect.New(sch.ModelType)
+		if err := tx.ScanRows(rows, rv.Interface()); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			row[field.DBName], _ = field.ValueOf(ctx, rv.Elem())
+		}
+		if err := encoder.Encode(row); err != nil {
+			return nil, err
+		}
+		entity.Count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// exportFiles 写入附件原图和已上传的头像（按存储中实际存在的文件，记录文件内容对应的类型）
+func (s *BackupService) exportFiles(ctx context.Context, tx *gorm.DB, zw *zip.Writer) (*BackupFileStats, error) {
+	stats := BackupFileStats{Index: backupFilesIndex}
+	var keys []string
+	var attachments []model.Attachment
+	if err := tx.Unscoped().Select("key").Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		keys = append(keys, a.Key)
+	}
+	var users []model.User
+	if err := tx.Unscoped().Select("id", "avatar_key").Where("avatar_key <> ''").Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		// 头像尺寸配置可能在上传后修改过，以存储中实际保存的尺寸为准
+		objects, err := s.store.List(ctx, avatarPrefix(u.ID, u.AvatarKey))
+		if err != nil {
+			return nil, err
+		}
+		if len(objects) == 0 {
+			stats.Missing++
+		}
+		for _, o := range objects {
+			keys = append(keys, o.Key)
+		}
+	}
+
+	var index []backupFile
+	for _, key := range keys {
+		f, err := s.copyFile(ctx, zw, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			stats.Missing++
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		index = append(index, *f)
+	}
+	stats.Count = len(index)
+
+	iw, err := zw.Create(backupFilesIndex)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(iw)
+	for _, f := range index {
+		if err := encoder.Encode(f); err != nil {
+			return nil, err
+		}
+	}
+	return &stats, nil
+}
+
+// copyFile 把存储中的文件复制到 files/<key>，按文件内容识别类型
+func (s *BackupService) copyFile(ctx context.Context, zw *zip.Writer, key string) (*backupFile, error) {
+	rc, err := s.store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	r := bufio.NewReaderSize(rc, 512)
+	head, err := r.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	fw, err := zw.Create(backupFilesDir + key)
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(fw, r)
+	if err != nil {
+		return nil, err
+	}
+	return &backupFile{Key: key, ContentType: http.DetectContentType(head), Size: size}, nil
+}
+
+// Restore 把备份恢复到空数据库（已有数据时拒绝恢复），先写入文件，再在一个事务中按顺序插入所有表
+func (s *BackupService) Restore(ctx context.Context, r io.ReaderAt, size int64) (*BackupManifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("备份文件格式错误: %w", err)
+	}
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	var manifest BackupManifest
+	if err := readZipJSON(entries, backupManifestFile, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != BackupFormat {
+		return nil, fmt.Errorf("不是站点备份文件: %q", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > BackupVersion {
+		return nil, fmt.Errorf("不支持的备份版本 %d（当前版本 %d）", manifest.Version, BackupVersion)
+	}
+
+	models := make(map[string]interface{}, len(backupEntities))
+	for _, e := range backupEntities {
+		sch, err := s.parseSchema(e.model)
+		if err != nil {
+			return nil, err
+		}
+		models[sch.Table] = e.model
+	}
+	for _, entity := range manifest.Entities {
+		if models[entity.Name] == nil {
+			return nil, fmt.Errorf("备份中包含未知的表: %s", entity.Name)
+		}
+		if entries[entity.File] == nil {
+			return nil, fmt.Errorf("备份不完整，缺少 %s", entity.File)
+		}
+	}
+	if err := s.checkEmpty(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := s.restoreFiles(ctx, entries); err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, entity := range manifest.Entities {
+			if err := s.restoreEntity(ctx, tx, entries[entity.File], models[entity.Name]); err != nil {
+				return fmt.Errorf("%s: %w", entity.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// checkEmpty 确认所有参与备份的表都没有数据（包括软删除的记录）
+func (s *BackupService) checkEmpty(ctx context.Context) error {
+	for _, e := range backupEntities {
+		var count int64
+		if err := s.db.WithContext(ctx).Unscoped().Model(e.model).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			sch, _ := s.parseSchema(e.model)
+			return fmt.Errorf("数据库不为空（%s 表已有 %d 条记录），只能恢复到空数据库", sch.Table, count)
+		}
+	}
+	return nil
+}
+
+// restoreEntity 逐行解码并批量插入一张表：按当前模型的字段类型解码，以列名插入（不执行钩子，也不套用列默认值）
+func (s *BackupService) restoreEntity(ctx context.Context, tx *gorm.DB, f *zip.File, m interface{}) error {
+	sch, err := s.parseSchema(m)
+	if err != nil {
+		return err
+	}
+	fields := make(map[string]*schema.Field)
+	for _, field := range backupFields(sch) {
+		fields[field.DBName] = field
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	decoder := json.NewDecoder(bufio.NewReader(rc))
+	batch := make([]map[string]interface{}, 0, backupBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Table(sch.Table).Create(&batch).Error; err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for line := 1; ; line++ {
+		var raw map[string]json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		row := make(map[string]interface{}, len(raw))
+		for column, data := range raw {
+			field := fields[column]
+			if field == nil && backupExcludedColumns[sch.Table+"."+column] {
+				continue // 旧备份中包含后来排除的列
+			}
+			if field == nil {
+				return fmt.Errorf("第 %d 行: 未知的列 %s", line, column)
+			}
+			value := reflect.New(field.FieldType)
+			if err := json.Unmarshal(data, value.Interface()); err != nil {
+				return fmt.Errorf("第 %d 行 %s: %w", line, column, err)
+			}
+			row[column] = value.Elem().Interface()
+		}
+		batch = append(batch, row)
+		if len(batch) == backupBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// restoreFiles 按文件索引把 files/ 下的文件写回存储（同名文件会被覆盖，恢复失败后可重新执行）
+func (s *BackupService) restoreFiles(ctx context.Context, entries map[string]*zip.File) error {
+	f := entries[backupFilesIndex]
+	if f == nil {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	decoder := json.NewDecoder(rc)
+	for {
+		var file backupFile
+		if err := decoder.Decode(&file); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", backupFilesIndex, err)
+		}
+		entry := entries[backupFilesDir+file.Key]
+		if entry == nil {
+			return fmt.Errorf("备份不完整，缺少文件 %s", file.Key)
+		}
+		if err := s.putFile(ctx, entry, file); err != nil {
+			return fmt.Errorf("%s: %w", file.Key, err)
+		}
+	}
+}
+
+func (s *BackupService) putFile(ctx context.Context, entry *zip.File, file backupFile) error {
+	rc, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return s.store.Put(ctx, file.Key, rc, int64(entry.UncompressedSize64), file.ContentType)
+}
+
+// parseSchema 解析模型对应的表结构
+func (s *BackupService) parseSchema(m interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(m); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// backupFields 表中实际存在且参与备份的列（排除关联、gorm:"-" 字段和 backupExcludedColumns）
+func backupFields(sch *schema.Schema) []*schema.Field {
+	var fields []*schema.Field
+	for _, field := range sch.Fields {
+		if field.DBName != "" && field.Readable && field.Creatable && !backupExcludedColumns[sch.Table+"."+field.DBName] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// readZipJSON 读取压缩包中的 JSON 文件
+func readZipJSON(entries map[string]*zip.File, name string, v interface{}) error {
+	f := entries[name]
+	if f == nil {
+		return fmt.Errorf("备份不完整，缺少 %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+)
+
+func TestBackupExportRestore(t *testing.T) {
+	db := newTestDB(t)
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	post := createTestPost(t, db, alice.ID, "hello")
+	createTestPost(t, db, bob.ID, "world")
+	if err := db.Create(&model.Comment{Content: "nice", PostID: post.ID, UserID: bob.ID, Status: model.CommentApproved}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&model.Post{}).Where("1 = 1").UpdateColumn("related_computed_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&model.Post{}, post.ID).Error; err != nil { // 软删除的记录也导出
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := NewBackupService(db, store).Export(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, e := range manifest.Entities {
+		counts[e.Name] = e.Count
+	}
+
+	t.Run("restore", func(t *testing.T) {
+		target := newTestDB(t)
+		if _, err := NewBackupService(target, store).Restore(context.Background(), bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			table string
+			model interface{}
+			want  int
+		}{
+			{"users", &model.User{}, 2},
+			{"posts", &model.Post{}, 2},
+			{"comments", &model.Comment{}, 1},
+		}
+		for _, tt := range tests {
+			var count int64
+			if err := target.Unscoped().Model(tt.model).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if counts[tt.table] != tt.want || int(count) != tt.want {
+				t.Errorf("%s: exported %d, restored %d, want %d", tt.table, counts[tt.table], count, tt.want)
+			}
+		}
+		var restored model.Post
+		if err := target.Unscoped().First(&restored, post.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !restored.DeletedAt.Valid || restored.Title != "hello" {
+			t.Fatalf("restored post = %+v, want soft-deleted hello", restored)
+		}
+		// 相关文章不在备份中，恢复的文章需重新计算
+		var computed int64
+		if err := target.Unscoped().Model(&model.Post{}).Where("related_computed_at IS NOT NULL").Count(&computed).Error; err != nil {
+			t.Fatal(err)
+		}
+		if computed != 0 {
+			t.Fatalf("%d restored posts keep related_computed_at", computed)
+		}
+	})
+}
+
+func TestBackupExportFiles(t *testing.T) {
+	db := newTestDB(t)
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 300, 300))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAvatarService(db, store).Upload(ctx, alice.ID, bytes.NewReader(img.Bytes()), int64(img.Len()), nil); err != nil {
+		t.Fatal(err)
+	}
+	// 上传后修改了头像尺寸配置：备份仍包含实际保存的全部尺寸
+	config.Cfg.ImageConfig.AvatarSizes = []int{64}
+	defer testConfig()
+	// 头像记录存在但文件已丢失
+	db.Model(bob).UpdateColumn("avatar_key", "lost")
+	if err := store.Put(ctx, "attachments/a.png", bytes.NewReader(img.Bytes()), int64(img.Len()), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	// 记录的类型与文件内容不符时以文件内容为准
+	db.Create(&model.Attachment{UserID: alice.ID, Key: "attachments/a.png", Filename: "a.png", ContentType: "application/octet-stream", Size: int64(img.Len())})
+
+	var buf bytes.Buffer
+	manifest, err := NewBackupService(db, store).Export(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Files.Count != 4 || manifest.Files.Missing != 1 {
+		t.Fatalf("files = %+v, want 4 files and 1 missing", manifest.Files)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := zr.Open(backupFilesIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	got := make(map[string]string)
+	for decoder := json.NewDecoder(index); decoder.More(); {
+		var f backupFile
+		if err := decoder.Decode(&f); err != nil {
+			t.Fatal(err)
+		}
+		got[f.Key] = f.ContentType
+	}
+
+	var user model.User
+	db.Select("id", "avatar_key").First(&user, alice.ID)
+	tests := []struct {
+		key         string
+		contentType string
+	}{
+		{"attachments/a.png", "image/png"},
+		{avatarKey(alice.ID, user.AvatarKey, 80), "image/jpeg"},
+		{avatarKey(alice.ID, user.AvatarKey, 40), "image/jpeg"},
+		{avatarKey(alice.ID, user.AvatarKey, 200), "image/jpeg"},
+	}
+	for _, tt := range tests {
+		if got[tt.key] != tt.contentType {
+			t.Errorf("%s: content type = %q, want %q", tt.key, got[tt.key], tt.contentType)
+		}
+	}
+}
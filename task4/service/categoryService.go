@@ -0,0 +1,241 @@
+package service
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// CategoryService 文章分类服务
+type CategoryService struct {
+	db *gorm.DB
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db}
+}
+
+// CategoryInput 创建或修改分类的参数
+type CategoryInput struct {
+	Name     string
+	ParentID *uint // 为空表示顶级分类
+	Sort     int
+}
+
+// Tree 查询完整的分类树（同级按 sort、ID 升序）
+func (s *CategoryService) Tree() ([]model.Category, error) {
+	var categories []model.Category
+	if err := s.db.Order("sort, id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]model.Category)
+	var roots []model.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+	var build func(nodes []model.Category) []model.Category
+	build = func(nodes []model.Category) []model.Category {
+		for i := range nodes {
+			nodes[i].Children = build(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+// Create 创建分类（管理员）
+func (s *CategoryService) Create(op Operator, input CategoryInput) (*model.Category, error) {
+	category := model.Category{Name: input.Name, ParentID: input.ParentID, Sort: input.Sort}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, 0, input.ParentID); err != nil {
+			return err
+		}
+		if err := checkCategoryName(tx, 0, input); err != nil {
+			return err
+		}
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, op, "category.create", "category", category.ID, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Update 修改分类的名称、父分类和排序（管理员），不能移动到自身或子分类下
+func (s *CategoryService) Update(op Operator, id uint, input CategoryInput) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var category model.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return util.ErrCategoryNotExist
+		}
+		if err := checkCategoryParent(tx, id, input.ParentID); err != nil {
+			return err
+		}
+		if err := checkCategoryName(tx, id, input); err != nil {
+			return err
+		}
+		if err := tx.Model(&category).Updates(map[string]interface{}{
+			"name":      input.Name,
+			"parent_id": input.ParentID,
+			"sort":      input.Sort,
+		}).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, op, "category.update", "category", id, input)
+	})
+}
+
+// Delete 删除分类（管理员），有子分类时不能删除，分类下的文章变为未分类：
+// 不修改文章的更新时间，为每篇文章写入修改事件（Webhook、站点地图等订阅者据此更新）
+func (s *CategoryService) Delete(op Operator, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var category model.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return util.ErrCategoryNotExist
+		}
+		var count int64
+		if err := tx.Model(&model.Category{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return util.ErrCategoryNotEmpty
+		}
+		var posts []model.Post
+		if err := tx.Where("category_id = ?", id).Find(&posts).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Post{}).Where("category_id = ?", id).UpdateColumn("category_id", nil).Error; err != nil {
+			return err
+		}
+		for i := range posts {
+			posts[i].CategoryID = nil
+			if err := recordEvent(tx, model.EventPostUpdated, posts[i].ID, postEventData(&posts[i])); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&category).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, op, "category.delete", "category", id, map[string]interface{}{"name": category.Name})
+	})
+}
+
+// PagePosts 分页查询分类及其全部子孙分类下的文章（按创建时间倒序）
+func (s *CategoryService) PagePosts(id uint, page, pageSize int) (*util.PageResult, error) {
+	var category model.Category
+	if err := s.db.First(&category, id).Error; err != nil {
+		return nil, util.ErrCategoryNotExist
+	}
+	ids, err := descendantCategoryIDs(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		posts []model.Post
+		total int64
+	)
+	db := s.db.Model(&model.Post{}).Where("category_id IN ? AND hidden = ?", ids, false)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Preload("Category").Preload("Tags").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(posts, total, page, pageSize), nil
+}
+
+// descendantCategoryIDs 查询分类自身及全部子孙分类的ID（分类数量有限，逐层查询）；
+// 记录已访问的分类，数据中存在循环引用时也能结束
+func descendantCategoryIDs(db *gorm.DB, id uint) ([]uint, error) {
+	ids := []uint{id}
+	visited := map[uint]bool{id: true}
+	level := []uint{id}
+	for len(level) > 0 {
+		var children []uint
+		if err := db.Model(&model.Category{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		var next []uint
+		for _, child := range children {
+			if !visited[child] {
+				visited[child] = true
+				next = append(next, child)
+			}
+		}
+		ids = append(ids, next...)
+		level = next
+	}
+	return ids, nil
+}
+
+// checkCategoryParent 校验父分类存在，且不是分类自身或其子孙分类（id 为 0 表示新建）
+func checkCategoryParent(tx *gorm.DB, id uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if err := tx.Select("id").First(&model.Category{}, *parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return util.NewErrno(util.ErrCategoryNotExist, "parentId=%d", *parentID)
+		}
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+	descendants, err := descendantCategoryIDs(tx, id)
+	if err != nil {
+		return err
+	}
+	for _, d := range descendants {
+		if d == *parentID {
+			return util.ErrCategoryParent
+		}
+	}
+	return nil
+}
+
+// checkCategoryName 校验同级分类名称不重复
+func checkCategoryName(tx *gorm.DB, id uint, input CategoryInput) error {
+	db := tx.Model(&model.Category{}).Where("name = ? AND id <> ?", input.Name, id)
+	if input.ParentID == nil {
+		db = db.Where("parent_id IS NULL")
+	} else {
+		db = db.Where("parent_id = ?", *input.ParentID)
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return util.ErrCategoryExist
+	}
+	return nil
+}
+
+// checkCategory 校验文章的分类存在（为空表示未分类）
+func checkCategory(tx *gorm.DB, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	if err := tx.Select("id").First(&model.Category{}, *categoryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return util.ErrCategoryNotExist
+		}
+		return err
+	}
+	return nil
+}
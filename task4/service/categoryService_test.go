@@ -0,0 +1,79 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"gotask/task4/model"
+)
+
+func TestDescendantCategoryIDsCycle(t *testing.T) {
+	db := newTestDB(t)
+	create := func(name string, parentID *uint) *model.Category {
+		category := model.Category{Name: name, ParentID: parentID}
+		if err := db.Create(&category).Error; err != nil {
+			t.Fatal(err)
+		}
+		return &category
+	}
+	a := create("a", nil)
+	b := create("b", &a.ID)
+	c := create("c", &b.ID)
+	d := create("d", &a.ID)
+	// 直接改库制造循环引用 a -> b -> c -> a
+	if err := db.Model(a).UpdateColumn("parent_id", c.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		id   uint
+		want []uint
+	}{
+		{"cycle root", a.ID, []uint{a.ID, b.ID, c.ID, d.ID}},
+		{"inside cycle", c.ID, []uint{a.ID, b.ID, c.ID, d.ID}},
+		{"leaf", d.ID, []uint{d.ID}},
+	}
+	for _, tt := range tests {
+		got, err := descendantCategoryIDs(db, tt.id)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: ids = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteCategoryKeepsPostUpdatedAt(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "admin")
+	s := NewCategoryService(db)
+	category, err := s.Create(Operator{UserID: admin.ID}, CategoryInput{Name: "news"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := createTestPost(t, db, admin.ID, "hello")
+	updatedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := db.Model(post).UpdateColumns(map[string]interface{}{"category_id": category.ID, "updated_at": updatedAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Delete(Operator{UserID: admin.ID}, category.ID); err != nil {
+		t.Fatal(err)
+	}
+	var got model.Post
+	if err := db.First(&got, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.CategoryID != nil || !got.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("post category = %v, updated_at = %v, want nil and %v", got.CategoryID, got.UpdatedAt, updatedAt)
+	}
+	var event model.OutboxEvent
+	if err := db.Where("type = ? AND aggregate_id = ?", model.EventPostUpdated, post.ID).First(&event).Error; err != nil {
+		t.Fatalf("post.updated event: %v", err)
+	}
+}
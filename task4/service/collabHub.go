@@ -0,0 +1,178 @@
+package service
+
+import (
+	"sort"
+	"sync"
+
+	"gotask/task4/model"
+)
+
+// 协作频道消息类型
+const (
+	CollabPresence       = "presence"        // 服务端：房间在线用户变化
+	CollabTyping         = "typing"          // 双向：正在输入
+	CollabCommentEdit    = "comment.edit"    // 客户端：修改评论
+	CollabCommentUpdated = "comment.updated" // 服务端：评论已修改
+	CollabError          = "error"           // 服务端：请求处理失败
+)
+
+// CollabUser 协作频道中的用户
+type CollabUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+}
+
+// CollabMessage 服务端下发的协作消息
+type CollabMessage struct {
+	Type    string         `json:"type"`
+	User    *CollabUser    `json:"user,omitempty"`    // 消息发起人
+	Users   []CollabUser   `json:"users,omitempty"`   // 在线用户（presence）
+	Comment *model.Comment `json:"comment,omitempty"` // 修改后的评论
+	Msg     string         `json:"msg,omitempty"`     // 错误信息
+}
+
+// CollabMember 房间成员（对应一个 WebSocket 连接，同一用户可有多个连接）
+type CollabMember struct {
+	User CollabUser
+	Send chan CollabMessage // 待发送队列
+	done chan struct{}
+	once sync.Once
+}
+
+func NewCollabMember(user CollabUser, bufferSize int) *CollabMember {
+	return &CollabMember{
+		User: user,
+		Send: make(chan CollabMessage, bufferSize),
+		done: make(chan struct{}),
+	}
+}
+
+// Done 成员被移出房间（离开、消费过慢或服务关闭）时关闭
+func (m *CollabMember) Done() <-chan struct{} {
+	return m.done
+}
+
+// Close 关闭成员
+func (m *CollabMember) Close() {
+	m.once.Do(func() { close(m.done) })
+}
+
+// Deliver 投递消息（不阻塞），队列已满时返回 false
+func (m *CollabMember) Deliver(msg CollabMessage) bool {
+	select {
+	case m.Send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// CollabBroker 协作消息分发：当前为单实例内存实现，
+// 多实例部署时可替换为基于 Redis/NATS 等的分布式实现
+type CollabBroker interface {
+	Join(room string, m *CollabMember)                              // 加入房间并广播在线用户
+	Leave(room string, m *CollabMember)                             // 离开房间并广播在线用户
+	Broadcast(room string, msg CollabMessage, except *CollabMember) // 向房间广播（except 不接收）
+	Close()                                                         // 关闭所有连接
+}
+
+// MemoryCollabBroker 进程内的协作消息分发
+type MemoryCollabBroker struct {
+	mu     sync.Mutex
+	rooms  map[string]map[*CollabMember]struct{}
+	closed bool
+}
+
+func NewMemoryCollabBroker() *MemoryCollabBroker {
+	return &MemoryCollabBroker{rooms: make(map[string]map[*CollabMember]struct{})}
+}
+
+func (b *MemoryCollabBroker) Join(room string, m *CollabMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		m.Close()
+		return
+	}
+	if b.rooms[room] == nil {
+		b.rooms[room] = make(map[*CollabMember]struct{})
+	}
+	b.rooms[room][m] = struct{}{}
+	b.broadcastPresence(room)
+}
+
+func (b *MemoryCollabBroker) Leave(room string, m *CollabMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m.Close()
+	members, ok := b.rooms[room]
+	if !ok {
+		return
+	}
+	if _, ok := members[m]; !ok {
+		return
+	}
+	delete(members, m)
+	if len(members) == 0 {
+		delete(b.rooms, room)
+		return
+	}
+	b.broadcastPresence(room)
+}
+
+func (b *MemoryCollabBroker) Broadcast(room string, msg CollabMessage, except *CollabMember) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.broadcast(room, msg, except) {
+		b.broadcastPresence(room)
+	}
+}
+
+func (b *MemoryCollabBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for room, members := range b.rooms {
+		for m := range members {
+			m.Close()
+		}
+		delete(b.rooms, room)
+	}
+}
+
+// broadcast 向房间广播，返回是否有成员因消费过慢被移出；调用方需持有锁。
+// 正在输入这类瞬时消息在队列满时直接丢弃，其他消息则断开慢连接（客户端重连后重新同步）
+func (b *MemoryCollabBroker) broadcast(room string, msg CollabMessage, except *CollabMember) bool {
+	evicted := false
+	for m := range b.rooms[room] {
+		if m == except || m.Deliver(msg) || msg.Type == CollabTyping {
+			continue
+		}
+		delete(b.rooms[room], m)
+		m.Close()
+		evicted = true
+	}
+	if len(b.rooms[room]) == 0 {
+		delete(b.rooms, room)
+	}
+	return evicted
+}
+
+// broadcastPresence 广播房间在线用户（按用户去重）；调用方需持有锁
+func (b *MemoryCollabBroker) broadcastPresence(room string) {
+	for {
+		seen := make(map[uint]bool)
+		var users []CollabUser
+		for m := range b.rooms[room] {
+			if !seen[m.User.ID] {
+				seen[m.User.ID] = true
+				users = append(users, m.User)
+			}
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+		if !b.broadcast(room, CollabMessage{Type: CollabPresence, Users: users}, nil) {
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"gotask/task4/config"
+	"gotask/task4/model"
+)
+
+// 评论进入待审核的原因
+const (
+	ReasonPostRequiresApproval = "post_requires_approval" // 文章开启了评论审核
+	ReasonTooManyLinks         = "too_many_links"         // 链接数超过上限
+	ReasonBlockedWord          = "blocked_word"           // 命中屏蔽词
+	ReasonNewAccountLink       = "new_account_link"       // 新账号发布含链接的评论
+	ReasonDuplicate            = "duplicate"              // 短时间内重复发布相同内容
+)
+
+var linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// moderate 按配置的规则检查评论，返回进入待审核的原因（为空表示直接发布）
+func (s *CommentService) moderate(content string, author *model.User, post *model.Post) (string, error) {
+	cfg := config.Cfg.ModerationConfig
+
+	if post.RequireCommentApproval && post.UserID != author.ID {
+		return ReasonPostRequiresApproval, nil
+	}
+
+	links := len(linkPattern.FindAllStringIndex(content, -1))
+	if links > cfg.MaxLinks {
+		return ReasonTooManyLinks, nil
+	}
+
+	lower := strings.ToLower(content)
+	for _, word := range cfg.BlockedWords {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			return ReasonBlockedWord, nil
+		}
+	}
+
+	if links > 0 && time.Since(author.CreatedAt) < cfg.NewAccountAge {
+		return ReasonNewAccountLink, nil
+	}
+
+	var duplicates int64
+	if err := s.db.Model(&model.Comment{}).
+		Where("user_id = ? AND content = ? AND created_at > ?", author.ID, content, time.Now().Add(-cfg.DuplicateWindow)).
+		Count(&duplicates).Error; err != nil {
+		return "", err
+	}
+	if duplicates > 0 {
+		return ReasonDuplicate, nil
+	}
+	return "", nil
+}
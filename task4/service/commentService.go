@@ -15,33 +15,276 @@ func NewCommentService(db *gorm.DB) *CommentService {
 	return &CommentService{db: db}
 }
 
-// Create 创建评论（需验证文章存在）
-func (s *CommentService) Create(content string, postID, userID uint) (*model.Comment, error) {
+// Create 创建评论（需验证文章存在），parentID 不为空时为回复同一文章下的评论
+func (s *CommentService) Create(content string, postID, userID uint, parentID *uint) (*model.Comment, error) {
 	// 检查文章是否存在
 	var post model.Post
-	if err := s.db.Where("id = ?", postID).First(&post).Error; err != nil {
+	if err := s.db.Where("id = ? AND hidden = ?", postID, false).First(&post).Error; err != nil {
 		return nil, util.ErrPostNotExist
 	}
 
+	if err := checkCommentStatus(s.db, &post, userID); err != nil {
+		return nil, err
+	}
+
+	// 被回复的评论必须是同一文章下已公开的评论
+	if parentID != nil {
+		var parent model.Comment
+		if err := s.db.Where("id = ? AND post_id = ? AND status = ? AND hidden = ?",
+			*parentID, postID, model.CommentApproved, false).First(&parent).Error; err != nil {
+			return nil, util.ErrCommentNotExist
+		}
+	}
+
+	var author model.User
+	if err := s.db.First(&author, userID).Error; err != nil {
+		return nil, util.ErrUserNotExist
+	}
+
+	// 按审核规则判断直接发布还是进入待审核队列
+	reason, err := s.moderate(content, &author, &post)
+	if err != nil {
+		return nil, err
+	}
+	status := model.CommentApproved
+	if reason != "" {
+		status = model.CommentPending
+	}
+
 	// 创建评论
 	comment := model.Comment{
-		Content: content,
-		PostID:  postID,
-		UserID:  userID,
+		Content:          content,
+		PostID:           postID,
+		UserID:           userID,
+		ParentID:         parentID,
+		Status:           status,
+		ModerationReason: reason,
 	}
-	if err := s.db.Create(&comment).Error; err != nil {
+	// 待审核的评论在审核通过后才处理 @提及和通知
+	err = transaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if status != model.CommentApproved {
+			return nil
+		}
+		return publishComment(tx, &comment)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &comment, nil
 }
 
-// ListByPostID 获取文章的所有评论（包含评论者信息）
+// checkCommentStatus 检查文章评论设置是否允许该用户评论（作者本人不受"仅粉丝"限制）
+func checkCommentStatus(db *gorm.DB, post *model.Post, userID uint) error {
+	if post.CommentsClosed() {
+		return util.ErrCommentClosed
+	}
+	if post.CommentStatus == model.CommentFollowersOnly && post.UserID != userID {
+		following, err := isFollowing(db, userID, post.UserID)
+		if err != nil {
+			return err
+		}
+		if !following {
+			return util.ErrCommentFollowers
+		}
+	}
+	return nil
+}
+
+// Update 修改评论内容（仅评论者本人，评论需属于该文章，且文章评论设置仍允许其评论）；
+// 修改后的内容重新按审核规则检查，命中规则时转入待审核
+func (s *CommentService) Update(postID, id, userID uint, content string) (*model.Comment, error) {
+	var comment model.Comment
+	if err := s.db.Where("id = ? AND post_id = ? AND hidden = ?", id, postID, false).First(&comment).Error; err != nil {
+		return nil, util.ErrCommentNotExist
+	}
+	if comment.UserID != userID {
+		return nil, util.ErrNoPermission
+	}
+	if comment.Content == content {
+		return &comment, nil
+	}
+	var post model.Post
+	if err := s.db.Where("id = ? AND hidden = ?", postID, false).First(&post).Error; err != nil {
+		return nil, util.ErrPostNotExist
+	}
+	if err := checkCommentStatus(s.db, &post, userID); err != nil {
+		return nil, err
+	}
+	var author model.User
+	if err := s.db.First(&author, userID).Error; err != nil {
+		return nil, util.ErrUserNotExist
+	}
+
+	reason, err := s.moderate(content, &author, &post)
+	if err != nil {
+		return nil, err
+	}
+	wasApproved := comment.Status == model.CommentApproved
+	comment.Content = content
+	if reason != "" {
+		comment.Status = model.CommentPending
+		comment.ModerationReason = reason
+	}
+	updates := map[string]interface{}{
+		"content":           comment.Content,
+		"status":            comment.Status,
+		"moderation_reason": comment.ModerationReason,
+	}
+	err = transaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Updates(updates).Error; err != nil {
+			return err
+		}
+		if comment.Status != model.CommentApproved {
+			if wasApproved {
+				return unpublishComment(tx, &comment)
+			}
+			return nil
+		}
+		return syncMentions(tx, model.MentionSourceComment, comment.ID, comment.PostID, comment.UserID, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListByPostID 获取文章的所有已发布评论（包含评论者信息），文章不存在或被隐藏时返回 ErrPostNotExist
 func (s *CommentService) ListByPostID(postID uint) ([]model.Comment, error) {
+	// 被隐藏的文章（及已删除的文章）不公开评论
+	var count int64
+	if err := s.db.Model(&model.Post{}).Where("id = ? AND hidden = ?", postID, false).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, util.ErrPostNotExist
+	}
+
 	var comments []model.Comment
 	if err := s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
-		return db.Select("ID", "Username") // 只返回评论者ID和用户名
-	}).Where("post_id = ?", postID).Order("created_at DESC").Find(&comments).Error; err != nil {
+		return db.Select("ID", "Username", "AvatarKey") // 只返回评论者ID、用户名和头像
+	}).Where("post_id = ? AND status = ? AND hidden = ?", postID, model.CommentApproved, false).Order("created_at DESC").Find(&comments).Error; err != nil {
 		return nil, err
 	}
 	return comments, nil
-}
\ No newline at end of file
+}
+
+// ModerationQueue 分页查询审核队列：审核员可查看全部，普通用户只能查看自己文章下的评论
+func (s *CommentService) ModerationQueue(viewerID uint, isModerator bool, status string, page, pageSize int) (*util.PageResult, error) {
+	var (
+		comments []model.Comment
+		total    int64
+	)
+	db := s.db.Model(&model.Comment{}).Where("comments.status = ?", status)
+	if !isModerator {
+		db = db.Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+			Where("posts.user_id = ?", viewerID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey", "CreatedAt")
+	}).Order("comments.created_at").Offset(offset).Limit(pageSize).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(comments, total, page, pageSize), nil
+}
+
+// Moderate 批量审核评论（approve 发布 / reject 拒绝），返回实际处理的条数；
+// 普通用户只能审核自己文章下的评论，其余ID会被忽略
+func (s *CommentService) Moderate(op Operator, isModerator bool, ids []uint, action string) (int64, error) {
+	status := model.CommentApproved
+	if action == "reject" {
+		status = model.CommentRejected
+	}
+
+	var affected int64
+	err := transaction(s.db, func(tx *gorm.DB) error {
+		var allowed []uint
+		query := tx.Model(&model.Comment{}).Where("comments.id IN ?", ids)
+		if !isModerator {
+			query = query.Joins("JOIN posts ON posts.id = comments.post_id").Where("posts.user_id = ?", op.UserID)
+		}
+		if err := query.Pluck("comments.id", &allowed).Error; err != nil {
+			return err
+		}
+		if len(allowed) == 0 {
+			return nil
+		}
+
+		// 本次才通过审核的评论（已发布的评论重复通过时不再发通知），以及本次被拒绝的已公开评论
+		var published, unpublished []model.Comment
+		if status == model.CommentApproved {
+			if err := tx.Where("id IN ? AND status <> ?", allowed, model.CommentApproved).Find(&published).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Where("id IN ? AND status = ?", allowed, model.CommentApproved).Find(&unpublished).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&model.Comment{}).Where("id IN ?", allowed).Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		for _, id := range allowed {
+			if err := writeAudit(tx, op, "comment."+action, "comment", id, nil); err != nil {
+				return err
+			}
+		}
+		for i := range published {
+			published[i].Status = status
+			if err := publishComment(tx, &published[i]); err != nil {
+				return err
+			}
+		}
+		for i := range unpublished {
+			if err := unpublishComment(tx, &unpublished[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return affected, err
+}
+
+// publishComment 评论公开后处理 @提及、累加文章评论数（被举报隐藏的评论不计入）、写入评论发布事件（通知、Webhook 由发件箱转发），
+// 并实时推送给正在浏览文章的用户
+func publishComment(tx *gorm.DB, comment *model.Comment) error {
+	if !comment.Hidden {
+		if err := tx.Model(&model.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := recordPostActivity(tx, comment.PostID, statComments, 1); err != nil {
+			return err
+		}
+	}
+	if err := syncMentions(tx, model.MentionSourceComment, comment.ID, comment.PostID, comment.UserID, comment.Content); err != nil {
+		return err
+	}
+	if err := recordEvent(tx, model.EventCommentCreated, comment.ID, commentEventData(comment)); err != nil {
+		return err
+	}
+	publish(tx, PostTopic(comment.PostID), EventComment, comment)
+	return nil
+}
+
+// unpublishComment 已公开的评论被拒绝或修改后转入待审核时，扣减文章评论数和当日评论计数（被隐藏的评论隐藏时已扣减）
+func unpublishComment(tx *gorm.DB, comment *model.Comment) error {
+	if comment.Hidden {
+		return nil
+	}
+	if err := tx.Model(&model.Post{}).Where("id = ? AND comment_count > 0", comment.PostID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count - 1")).Error; err != nil {
+		return err
+	}
+	return recordPostActivity(tx, comment.PostID, statComments, -1)
+}
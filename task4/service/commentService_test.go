@@ -0,0 +1,167 @@
+package service
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+func TestCommentCountFollowsApprovedStatus(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	reader := createTestUser(t, db, "reader")
+	post := createTestPost(t, db, author.ID, "hello")
+	s := NewCommentService(db)
+
+	comment, err := s.Create("first comment", post.ID, reader.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := Operator{UserID: author.ID}
+	moderate := func(action string) func() error {
+		return func() error {
+			_, err := s.Moderate(op, false, []uint{comment.ID}, action)
+			return err
+		}
+	}
+	hide := func(hidden bool) func() error {
+		return func() error {
+			_, err := setHidden(db, model.ReportTargetComment, comment.ID, hidden)
+			return err
+		}
+	}
+
+	steps := []struct {
+		name string
+		do   func() error
+		want int
+	}{
+		{"created", func() error { return nil }, 1},
+		{"rejected", moderate("reject"), 0},
+		{"rejected again", moderate("reject"), 0},
+		{"re-approved", moderate("approve"), 1},
+		{"approved again", moderate("approve"), 1},
+		{"edited into pending", func() error {
+			_, err := s.Update(post.ID, comment.ID, reader.ID, "buy spam here")
+			return err
+		}, 0},
+		{"approved after edit", moderate("approve"), 1},
+		{"hidden", hide(true), 0},
+		{"hidden again", hide(true), 0},
+		{"rejected while hidden", moderate("reject"), 0},
+		{"approved while hidden", moderate("approve"), 0},
+		{"unhidden", hide(false), 1},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		var got model.Post
+		if err := db.First(&got, post.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.CommentCount != step.want {
+			t.Fatalf("%s: comment_count = %d, want %d", step.name, got.CommentCount, step.want)
+		}
+		if daily := dailyComments(t, db, post.ID); daily != step.want {
+			t.Fatalf("%s: daily comments = %d, want %d", step.name, daily, step.want)
+		}
+	}
+}
+
+// dailyComments 文章每日统计中累计的评论数
+func dailyComments(t *testing.T, db *gorm.DB, postID uint) int {
+	t.Helper()
+	var total int
+	if err := db.Model(&model.PostDailyStat{}).Where("post_id = ?", postID).
+		Select("COALESCE(SUM(comments), 0)").Scan(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	return total
+}
+
+func TestListByPostIDHiddenPost(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	visible := createTestPost(t, db, author.ID, "visible")
+	hidden := createTestPost(t, db, author.ID, "hidden")
+	db.Model(hidden).UpdateColumn("hidden", true)
+	for _, post := range []*model.Post{visible, hidden} {
+		if err := db.Create(&model.Comment{Content: "hi", PostID: post.ID, UserID: author.ID, Status: model.CommentApproved}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewCommentService(db)
+	tests := []struct {
+		name    string
+		postID  uint
+		want    int
+		wantErr error
+	}{
+		{"visible", visible.ID, 1, nil},
+		{"hidden", hidden.ID, 0, util.ErrPostNotExist},
+		{"missing", 999, 0, util.ErrPostNotExist},
+	}
+	for _, tt := range tests {
+		comments, err := s.ListByPostID(tt.postID)
+		if err != tt.wantErr || len(comments) != tt.want {
+			t.Errorf("%s: ListByPostID() = %d comments, %v; want %d, %v", tt.name, len(comments), err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUpdateChecksCommentStatus(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	reader := createTestUser(t, db, "reader")
+	post := createTestPost(t, db, author.ID, "hello")
+	s := NewCommentService(db)
+
+	follow := model.Follow{FollowerID: reader.ID, FolloweeID: author.ID}
+	if err := db.Create(&follow).Error; err != nil {
+		t.Fatal(err)
+	}
+	setStatus := func(status string) {
+		if err := db.Model(&model.Post{}).Where("id = ?", post.ID).
+			UpdateColumn("comment_status", status).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	setStatus(model.CommentFollowersOnly)
+	readerComment, err := s.Create("reader comment", post.ID, reader.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorComment, err := s.Create("author comment", post.ID, author.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 取消关注后不能再借修改评论绕过"仅粉丝"限制
+	if err := db.Delete(&follow).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		status  string
+		comment *model.Comment
+		userID  uint
+		want    error
+	}{
+		{"unfollowed reader", model.CommentFollowersOnly, readerComment, reader.ID, util.ErrCommentFollowers},
+		{"post author", model.CommentFollowersOnly, authorComment, author.ID, nil},
+		{"closed", model.CommentClosed, readerComment, reader.ID, util.ErrCommentClosed},
+		{"closed author", model.CommentClosed, authorComment, author.ID, util.ErrCommentClosed},
+		{"reopened", model.CommentOpen, readerComment, reader.ID, nil},
+	}
+	for _, tt := range tests {
+		setStatus(tt.status)
+		_, err := s.Update(post.ID, tt.comment.ID, tt.userID, tt.name+" edit")
+		if err != tt.want {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
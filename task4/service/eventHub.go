@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 实时事件类型
+const (
+	EventComment      = "comment"      // 文章有新评论
+	EventNotification = "notification" // 用户收到新通知
+	EventReset        = "reset"        // 错过的事件无法补发，客户端需重新拉取数据
+)
+
+// Event 实时推送的事件
+type Event struct {
+	ID    string      // 事件ID：<启动批次>-<递增序号>（用于 Last-Event-ID 断线续传）
+	Topic string      // 主题：post:<id> / user:<id>（reset 事件为空）
+	Type  string      // 事件类型
+	Data  interface{} // 事件内容（JSON 序列化后推送）
+}
+
+// PostTopic 文章评论主题
+func PostTopic(postID uint) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// UserTopic 用户通知主题
+func UserTopic(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// EventHub 进程内的发布/订阅中心：每个订阅者有独立的缓冲队列，
+// 并保留最近的事件用于断线重连补发（多实例部署时各实例只推送本实例产生的事件）
+type EventHub struct {
+	mu         sync.Mutex
+	epoch      string // 启动批次（重启后序号从头开始，用于识别重启前的事件ID）
+	seq        uint64
+	subs       map[*Subscription]struct{}
+	history    []Event  // 环形缓冲
+	seqs       []uint64 // 与 history 对应的事件序号
+	next       int      // 下一个写入位置
+	bufferSize int
+	closed     bool
+}
+
+func NewEventHub(bufferSize, historySize int) *EventHub {
+	return &EventHub{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		subs:       make(map[*Subscription]struct{}),
+		history:    make([]Event, 0, historySize),
+		seqs:       make([]uint64, 0, historySize),
+		bufferSize: bufferSize,
+	}
+}
+
+// eventID 事件ID
+func (h *EventHub) eventID(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// replayFrom 解析 Last-Event-ID，返回需要补发的起始序号；
+// ID 不属于本次启动、错过的事件已不在保留范围内或 ID 无效时返回原因（需要客户端重新同步）。调用方需持有锁
+func (h *EventHub) replayFrom(lastEventID string) (uint64, string) {
+	epoch, seqStr, ok := strings.Cut(lastEventID, "-")
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if !ok || err != nil {
+		return 0, "invalid"
+	}
+	if epoch != h.epoch || seq > h.seq {
+		return 0, "restarted"
+	}
+	oldest := h.seq + 1 // 没有保留事件时只能补发之后的事件
+	if len(h.seqs) > 0 {
+		oldest = h.seqs[h.next%len(h.seqs)]
+	}
+	if seq+1 < oldest {
+		return 0, "expired"
+	}
+	return seq, ""
+}
+
+// Subscription 一个订阅者（对应一个 SSE 连接）
+type Subscription struct {
+	C      chan Event
+	topics map[string]bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Done 订阅结束（服务关闭或消费过慢被断开）时关闭
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Subscribe 订阅主题；lastEventID 不为空时先把保留的事件中错过的部分放入队列，
+// 无法补发（服务重启过、错过的事件已不在保留范围内或 ID 无效）时先放入一个 reset 事件，客户端收到后应重新拉取数据
+func (h *EventHub) Subscribe(topics []string, lastEventID string) *Subscription {
+	wanted := make(map[string]bool, len(topics))
+	for _, t := range topics {
+		wanted[t] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var missed []Event
+	if lastEventID != "" {
+		from, reason := h.replayFrom(lastEventID)
+		if reason != "" {
+			missed = append(missed, Event{ID: h.eventID(h.seq), Type: EventReset, Data: map[string]string{"reason": reason}})
+		} else {
+			// 按时间顺序遍历环形缓冲
+			for i := 0; i < len(h.history); i++ {
+				j := (h.next + i) % len(h.history)
+				if h.seqs[j] > from && wanted[h.history[j].Topic] {
+					missed = append(missed, h.history[j])
+				}
+			}
+		}
+	}
+
+	sub := &Subscription{
+		C:      make(chan Event, h.bufferSize+len(missed)),
+		topics: wanted,
+		done:   make(chan struct{}),
+	}
+	if h.closed {
+		sub.close()
+		return sub
+	}
+	for _, ev := range missed {
+		sub.C <- ev
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe 取消订阅
+func (h *EventHub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	sub.close()
+}
+
+// Publish 发布事件（不阻塞：订阅者缓冲已满时断开该订阅者，由客户端重连补发）
+func (h *EventHub) Publish(topic, eventType string, data interface{}) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	ev := Event{ID: h.eventID(h.seq), Topic: topic, Type: eventType, Data: data}
+	if cap(h.history) > 0 {
+		if len(h.history) < cap(h.history) {
+			h.history = append(h.history, ev)
+			h.seqs = append(h.seqs, h.seq)
+		} else {
+			h.history[h.next] = ev
+			h.seqs[h.next] = h.seq
+		}
+		h.next = (h.next + 1) % cap(h.history)
+	}
+
+	for sub := range h.subs {
+		if sub.topics[topic] {
+			h.deliver(sub, ev)
+		}
+	}
+}
+
+// deliver 投递事件到订阅者队列，调用方需持有锁
+func (h *EventHub) deliver(sub *Subscription, ev Event) {
+	select {
+	case sub.C <- ev:
+	default:
+		delete(h.subs, sub)
+		sub.close()
+	}
+}
+
+// Close 关闭所有订阅（服务器关闭时调用，让 SSE 连接尽快结束）
+func (h *EventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		sub.close()
+	}
+}
+
+// events 全局事件中心（由 main 设置，未设置时不推送）
+var events *EventHub
+
+// SetEventHub 设置全局事件中心
+func SetEventHub(hub *EventHub) {
+	events = hub
+}
+
+// pendingEventsKey 事务中待推送事件在 context 中的 key
+type pendingEventsKey struct{}
+
+// pendingEvent 事务提交后才推送的事件
+type pendingEvent struct {
+	topic     string
+	eventType string
+	data      interface{}
+}
+
+// transaction 执行事务，事务提交成功后再推送事务中产生的事件（回滚时丢弃）
+func transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	var pending []pendingEvent
+	ctx := context.WithValue(db.Statement.Context, pendingEventsKey{}, &pending)
+	if err := db.WithContext(ctx).Transaction(fc); err != nil {
+		return err
+	}
+	for _, e := range pending {
+		events.Publish(e.topic, e.eventType, e.data)
+	}
+	return nil
+}
+
+// publish 推送事件：在 transaction 中调用时延迟到提交后推送，否则立即推送
+func publish(tx *gorm.DB, topic, eventType string, data interface{}) {
+	if pending, ok := tx.Statement.Context.Value(pendingEventsKey{}).(*[]pendingEvent); ok {
+		*pending = append(*pending, pendingEvent{topic: topic, eventType: eventType, data: data})
+		return
+	}
+	events.Publish(topic, eventType, data)
+}
@@ -0,0 +1,56 @@
+package service
+
+import "testing"
+
+func TestEventHubReplay(t *testing.T) {
+	hub := NewEventHub(10, 3)
+	topic := PostTopic(1)
+	for i := 0; i < 5; i++ { // 只保留最近 3 个事件（序号 3-5）
+		hub.Publish(topic, EventComment, i)
+	}
+	other := NewEventHub(10, 3) // 模拟重启后的新实例
+	other.Publish(topic, EventComment, 0)
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		wantReset   string // 期望的 reset 原因（为空表示正常补发）
+		wantData    []interface{}
+	}{
+		{"no last event", "", "", nil},
+		{"up to date", hub.eventID(5), "", nil},
+		{"missed retained events", hub.eventID(3), "", []interface{}{3, 4}},
+		{"oldest retained boundary", hub.eventID(2), "", []interface{}{2, 3, 4}},
+		{"missed events expired", hub.eventID(1), "expired", nil},
+		{"before restart", other.eventID(1), "restarted", nil},
+		{"legacy numeric id", "3", "invalid", nil},
+		{"future sequence", hub.eventID(9), "restarted", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := hub.Subscribe([]string{topic}, tt.lastEventID)
+			defer hub.Unsubscribe(sub)
+			var got []Event
+			for len(sub.C) > 0 {
+				got = append(got, <-sub.C)
+			}
+			if tt.wantReset != "" {
+				if len(got) != 1 || got[0].Type != EventReset || got[0].Data.(map[string]string)["reason"] != tt.wantReset {
+					t.Fatalf("events = %+v, want reset %q", got, tt.wantReset)
+				}
+				if got[0].ID != hub.eventID(5) {
+					t.Fatalf("reset id = %q, want current %q", got[0].ID, hub.eventID(5))
+				}
+				return
+			}
+			if len(got) != len(tt.wantData) {
+				t.Fatalf("events = %+v, want data %v", got, tt.wantData)
+			}
+			for i, ev := range got {
+				if ev.Data != tt.wantData[i] {
+					t.Fatalf("event %d data = %v, want %v", i, ev.Data, tt.wantData[i])
+				}
+			}
+		})
+	}
+}
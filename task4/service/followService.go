@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// FollowService 关注服务
+type FollowService struct {
+	db *gorm.DB
+}
+
+func NewFollowService(db *gorm.DB) *FollowService {
+	return &FollowService{db: db}
+}
+
+// Follow 关注用户（重复关注直接返回成功），新关注时通知被关注者
+func (s *FollowService) Follow(followerID, followeeID uint) error {
+	if followerID == followeeID {
+		return util.ErrFollowSelf
+	}
+	var followee model.User
+	if err := s.db.Select("id").First(&followee, followeeID).Error; err != nil {
+		return util.ErrUserNotExist
+	}
+
+	var follow model.Follow
+	err := s.db.Unscoped().Where("follower_id = ? AND followee_id = ?", followerID, followeeID).First(&follow).Error
+	if err == nil {
+		// 取消关注后再次关注，恢复软删除的记录
+		if !follow.DeletedAt.Valid {
+			return nil
+		}
+		return transaction(s.db, func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Model(&follow).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			if err := recordFollowerActivity(tx, followeeID, 1); err != nil {
+				return err
+			}
+			return notifyFollow(tx, followerID, followeeID)
+		})
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return transaction(s.db, func(tx *gorm.DB) error {
+		if err := tx.Create(&model.Follow{FollowerID: followerID, FolloweeID: followeeID}).Error; err != nil {
+			return err
+		}
+		if err := recordFollowerActivity(tx, followeeID, 1); err != nil {
+			return err
+		}
+		return notifyFollow(tx, followerID, followeeID)
+	})
+}
+
+// Unfollow 取消关注
+func (s *FollowService) Unfollow(followerID, followeeID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&model.Follow{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return recordFollowerActivity(tx, followeeID, -1)
+	})
+}
+
+// IsFollowing 判断 followerID 是否关注了 followeeID
+func (s *FollowService) IsFollowing(followerID, followeeID uint) (bool, error) {
+	return isFollowing(s.db, followerID, followeeID)
+}
+
+// Followers 分页查询用户的粉丝（包含粉丝用户名）
+func (s *FollowService) Followers(userID uint, page, pageSize int) (*util.PageResult, error) {
+	var (
+		follows []model.Follow
+		total   int64
+	)
+	db := s.db.Model(&model.Follow{}).Where("followee_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Preload("Follower", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&follows).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(follows, total, page, pageSize), nil
+}
+
+// notifyFollow 通知被关注者（取消后再次关注时，若上次的通知仍未读则不重复通知）
+func notifyFollow(tx *gorm.DB, followerID, followeeID uint) error {
+	var count int64
+	if err := tx.Model(&model.Notification{}).
+		Where("user_id = ? AND actor_id = ? AND type = ? AND read_at IS NULL", followeeID, followerID, model.NotifyFollow).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return notify(tx, model.Notification{
+		UserID:     followeeID,
+		ActorID:    followerID,
+		Type:       model.NotifyFollow,
+		TargetType: "user",
+		TargetID:   followerID,
+	})
+}
+
+// isFollowing 判断关注关系（供其他服务复用）
+func isFollowing(db *gorm.DB, followerID, followeeID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&model.Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
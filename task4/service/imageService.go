@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/imaging"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+)
+
+// 缩略图格式对应的扩展名和 MIME 类型
+var variantFormats = map[string]struct{ ext, contentType string }{
+	"jpeg": {"jpg", "image/jpeg"},
+	"png":  {"png", "image/png"},
+	"webp": {"webp", "image/webp"},
+}
+
+// ImageService 图片处理：上传时去除元数据，后台异步生成缩略图，访问时缺失的缩略图按需重新生成
+type ImageService struct {
+	db       *gorm.DB
+	store    storage.Storage
+	jobs     chan uint
+	mu       sync.Mutex
+	inflight map[string]chan struct{} // 正在生成的缩略图（避免并发重复生成）
+}
+
+func NewImageService(db *gorm.DB, store storage.Storage) *ImageService {
+	return &ImageService{
+		db:       db,
+		store:    store,
+		jobs:     make(chan uint, config.Cfg.ImageConfig.QueueSize),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Start 启动后台生成缩略图的 worker，生成失败时调用 onError
+func (s *ImageService) Start(onError func(attachmentID uint, err error)) {
+	for i := 0; i < config.Cfg.ImageConfig.Workers; i++ {
+		go func() {
+			for id := range s.jobs {
+				if err := s.GenerateAll(context.Background(), id); err != nil {
+					onError(id, err)
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue 把图片加入后台生成队列（队列满时跳过，访问时再按需生成）
+func (s *ImageService) Enqueue(attachment *model.Attachment) {
+	if !isImage(attachment.ContentType) {
+		return
+	}
+	select {
+	case s.jobs <- attachment.ID:
+	default:
+	}
+}
+
+// GenerateAll 生成图片的全部缩略图
+func (s *ImageService) GenerateAll(ctx context.Context, attachmentID uint) error {
+	var attachment model.Attachment
+	if err := s.db.First(&attachment, attachmentID).Error; err != nil {
+		return err
+	}
+	img, err := s.load(ctx, &attachment)
+	if err != nil {
+		return err
+	}
+	cfg := config.Cfg.ImageConfig
+	for _, width := range cfg.Widths {
+		for _, format := range cfg.Formats {
+			if err := s.save(ctx, img, variantKey(attachment.Key, width, format), width, format); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// OpenVariant 读取缩略图，不存在时重新生成；variant 形如 w480.jpg
+func (s *ImageService) OpenVariant(ctx context.Context, attachment *model.Attachment, variant string) (io.ReadCloser, string, error) {
+	width, format, ok := parseVariant(variant)
+	if !ok || !isImage(attachment.ContentType) {
+		return nil, "", storage.ErrNotFound
+	}
+	key := variantKey(attachment.Key, width, format)
+	contentType := variantFormats[format].contentType
+
+	rc, err := s.store.Open(ctx, key)
+	if err != storage.ErrNotFound {
+		return rc, contentType, err
+	}
+	if err := s.regenerate(ctx, attachment, key, width, format); err != nil {
+		return nil, "", err
+	}
+	rc, err = s.store.Open(ctx, key)
+	return rc, contentType, err
+}
+
+// DeleteVariants 删除图片的全部缩略图
+func (s *ImageService) DeleteVariants(ctx context.Context, attachment *model.Attachment) error {
+	if !isImage(attachment.ContentType) {
+		return nil
+	}
+	cfg := config.Cfg.ImageConfig
+	for _, width := range cfg.Widths {
+		for _, format := range cfg.Formats {
+			if err := s.store.Delete(ctx, variantKey(attachment.Key, width, format)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sanitize 处理上传的原图，去除 EXIF/XMP 等元数据：JPEG 无损去除（带方向信息的按方向摆正后重新编码），
+// PNG、BMP 重新编码（去除附加块和尾部数据），GIF 逐帧重新编码（去除注释和 XMP 扩展块），WebP 去除 EXIF/XMP 块，
+// 其他图片类型不接受；返回处理后的内容和图片尺寸
+func (s *ImageService) Sanitize(data []byte, contentType string) ([]byte, int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	switch contentType {
+	case "image/jpeg":
+		if imaging.Orientation(data) == 1 {
+			stripped, err := imaging.StripJPEGMetadata(data)
+			return stripped, cfg.Width, cfg.Height, err
+		}
+		return s.reencode(data, "jpeg")
+	case "image/png":
+		return s.reencode(data, "png")
+	case "image/bmp":
+		return s.reencode(data, "bmp")
+	case "image/gif":
+		out, err := imaging.ReencodeGIF(data, config.Cfg.ImageConfig.MaxPixels)
+		return out, cfg.Width, cfg.Height, err
+	case "image/webp":
+		stripped, err := imaging.StripWebPMetadata(data)
+		return stripped, cfg.Width, cfg.Height, err
+	}
+	return nil, 0, 0, fmt.Errorf("unsupported image type: %s", contentType)
+}
+
+// reencode 解码后重新编码原图（重新编码的图片不包含元数据）
+func (s *ImageService) reencode(data []byte, format string) ([]byte, int, int, error) {
+	img, err := imaging.Decode(data, config.Cfg.ImageConfig.MaxPixels)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, 92); err != nil {
+		return nil, 0, 0, err
+	}
+	b := img.Bounds()
+	return buf.Bytes(), b.Dx(), b.Dy(), nil
+}
+
+// regenerate 生成单个缩略图（同一缩略图同时只生成一次，其他请求等待结果）
+func (s *ImageService) regenerate(ctx context.Context, attachment *model.Attachment, key string, width int, format string) error {
+	s.mu.Lock()
+	if done, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	s.inflight[key] = done
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	img, err := s.load(ctx, attachment)
+	if err != nil {
+		return err
+	}
+	return s.save(ctx, img, key, width, format)
+}
+
+// load 读取并解码原图
+func (s *ImageService) load(ctx context.Context, attachment *model.Attachment) (image.Image, error) {
+	rc, err := s.store.Open(ctx, attachment.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, config.Cfg.StorageConfig.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return imaging.Decode(data, config.Cfg.ImageConfig.MaxPixels)
+}
+
+// save 缩放、编码并写入存储
+func (s *ImageService) save(ctx context.Context, img image.Image, key string, width int, format string) error {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, imaging.Resize(img, width), format, config.Cfg.ImageConfig.Quality); err != nil {
+		return err
+	}
+	return s.store.Put(ctx, key, &buf, int64(buf.Len()), variantFormats[format].contentType)
+}
+
+// variantKey 缩略图在存储中的文件名：<原图名>_w<宽度>.<扩展名>
+func variantKey(key string, width int, format string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[:i]
+	}
+	return fmt.Sprintf("%s_w%d.%s", key, width, variantFormats[format].ext)
+}
+
+// parseVariant 解析缩略图名称（w480.jpg），只接受配置中的宽度和格式
+func parseVariant(variant string) (int, string, bool) {
+	name, ext, ok := strings.Cut(variant, ".")
+	if !ok || !strings.HasPrefix(name, "w") {
+		return 0, "", false
+	}
+	width, err := strconv.Atoi(name[1:])
+	if err != nil {
+		return 0, "", false
+	}
+	format := ""
+	for f, v := range variantFormats {
+		if v.ext == ext {
+			format = f
+		}
+	}
+	cfg := config.Cfg.ImageConfig
+	return width, format, format != "" && containsInt(cfg.Widths, width) && containsString(cfg.Formats, format)
+}
+
+// isImage 判断是否为可处理的图片
+func isImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
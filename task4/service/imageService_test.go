@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"gotask/task4/config"
+	"gotask/task4/imaging"
+	"gotask/task4/model"
+	"gotask/task4/storage"
+)
+
+// testImage 测试用的小图片
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 4), uint8(y * 5), 128, 255})
+		}
+	}
+	return img
+}
+
+// withPNGText 在 PNG 的 IHDR 之后插入一个 tEXt 块
+func withPNGText(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	ihdrEnd := 8 + 8 + 13 + 4
+	chunk := make([]byte, 8, 12+len(text))
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	return append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+}
+
+// withJPEGExif 在 JPEG 的 SOI 之后插入一个 EXIF（APP1）段
+func withJPEGExif(t *testing.T, payload string) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	seg = append(seg, "Exif\x00\x00"+payload...)
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	return append(append(append([]byte{}, data[:2]...), seg...), data[2:]...)
+}
+
+// withWebPExif 生成带 VP8X 和 EXIF 块的 WebP
+func withWebPExif(t *testing.T, payload string) []byte {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, testImage(), "webp", 0); err != nil {
+		t.Fatal(err)
+	}
+	vp8x := []byte("VP8X\x0a\x00\x00\x00\x08\x00\x00\x00\x3f\x00\x00\x2f\x00\x00")
+	exif := append([]byte("EXIF\x00\x00\x00\x00"), payload...)
+	binary.LittleEndian.PutUint32(exif[4:], uint32(len(payload)))
+	if len(payload)%2 == 1 {
+		exif = append(exif, 0)
+	}
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), vp8x...)
+	data = append(data, buf.Bytes()[12:]...)
+	data = append(data, exif...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	return data
+}
+
+// withGIFComment 生成两帧的动画 GIF，并在结尾前插入注释扩展块和 XMP 应用扩展块
+func withGIFComment(t *testing.T, payload string) []byte {
+	g := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 2; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 64, 48), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), testImage(), image.Point{}, draw.Src)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	ext := []byte{0x21, 0xFE, byte(len(payload))}
+	ext = append(append(ext, payload...), 0)
+	ext = append(ext, 0x21, 0xFF, 11)
+	ext = append(ext, "XMP DataXMP"...)
+	ext = append(append(ext, byte(len(payload))), payload...)
+	ext = append(ext, 0)
+	return append(append(append([]byte{}, data[:len(data)-1]...), ext...), data[len(data)-1])
+}
+
+// withBMPTrailer 生成 BMP，并在像素数据之后附加数据
+func withBMPTrailer(t *testing.T, payload string) []byte {
+	var buf bytes.Buffer
+	if err := bmp.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	return append(buf.Bytes(), payload...)
+}
+
+func TestImageSanitizeStripsMetadata(t *testing.T) {
+	testConfig()
+	const secret = "GPS 31.2304N 121.4737E"
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"jpeg exif", "image/jpeg", withJPEGExif(t, secret)},
+		{"png text", "image/png", withPNGText(t, "Comment\x00"+secret)},
+		{"webp exif", "image/webp", withWebPExif(t, secret)},
+		{"gif comment", "image/gif", withGIFComment(t, secret)},
+		{"bmp trailer", "image/bmp", withBMPTrailer(t, secret)},
+	}
+	s := NewImageService(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Contains(tt.data, []byte(secret)) {
+				t.Fatal("fixture does not contain metadata")
+			}
+			out, width, height, err := s.Sanitize(tt.data, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(out, []byte(secret)) {
+				t.Fatal("metadata not removed")
+			}
+			if width != 64 || height != 48 {
+				t.Fatalf("size = %dx%d, want 64x48", width, height)
+			}
+			if _, _, err := image.Decode(bytes.NewReader(out)); err != nil {
+				t.Fatalf("sanitized image does not decode: %v", err)
+			}
+			if tt.contentType == "image/gif" {
+				g, err := gif.DecodeAll(bytes.NewReader(out))
+				if err != nil || len(g.Image) != 2 {
+					t.Fatalf("animation not kept: %v", err)
+				}
+			}
+		})
+	}
+
+	if _, _, _, err := s.Sanitize(withPNGText(t, secret), "image/x-icon"); err == nil {
+		t.Fatal("unsupported image type accepted")
+	}
+}
+
+func TestImageWebPVariant(t *testing.T) {
+	db := newTestDB(t)
+	config.Cfg.ImageConfig.Widths = []int{32}
+	config.Cfg.ImageConfig.Formats = []string{"jpeg", "webp"}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.Put(ctx, "photo.png", bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	attachment := &model.Attachment{Key: "photo.png", ContentType: "image/png"}
+
+	s := NewImageService(db, store)
+	tests := []struct {
+		variant     string
+		contentType string
+		wantErr     bool
+	}{
+		{"w32.webp", "image/webp", false},
+		{"w32.jpg", "image/jpeg", false},
+		{"w32.png", "", true},  // 未配置的格式
+		{"w64.webp", "", true}, // 未配置的宽度
+	}
+	for _, tt := range tests {
+		rc, contentType, err := s.OpenVariant(ctx, attachment, tt.variant)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.variant, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		img, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.variant, err)
+		}
+		if contentType != tt.contentType || "image/"+format != tt.contentType || img.Bounds().Dx() != 32 {
+			t.Fatalf("%s: %s %s %v", tt.variant, contentType, format, img.Bounds())
+		}
+	}
+}
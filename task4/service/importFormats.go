@@ -0,0 +1,304 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+	"gotask/task4/config"
+)
+
+// 导入格式
+const (
+	ImportFormatMarkdown = "markdown" // Markdown 文件的 zip 压缩包（支持 YAML front matter）
+	ImportFormatWXR      = "wxr"      // WordPress 导出的 WXR 文件
+)
+
+// 导入文件的限制（防止压缩炸弹，解压后的总大小另见 ImportConfig.MaxUncompressed）
+const (
+	importMaxFiles    = 10000
+	importMaxFileSize = 10 << 20
+)
+
+// importPost 从导入文件解析出的文章
+type importPost struct {
+	Source     string // 来源标识（用于重复导入时识别同一篇文章）
+	Title      string
+	Content    string
+	Author     string // 来源作者的登录名
+	Date       time.Time
+	Tags       []string
+	Category   string
+	Comments   []importComment
+	SkipReason string // 不导入的原因（草稿等）
+}
+
+// importComment 从导入文件解析出的评论
+type importComment struct {
+	Source       string
+	ParentSource string // 回复的评论（为空表示直接评论文章）
+	Author       string // 评论者名称
+	AuthorLogin  string // 评论者在来源站点的登录名（来源站点注册用户的评论）
+	Date         time.Time
+	Content      string
+}
+
+// detectImportFormat 按文件内容识别导入格式
+func detectImportFormat(data []byte) (string, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return ImportFormatMarkdown, nil
+	}
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if bytes.Contains(head, []byte("<rss")) || bytes.HasPrefix(bytes.TrimSpace(head), []byte("<?xml")) {
+		return ImportFormatWXR, nil
+	}
+	return "", errors.New("无法识别的导入文件格式（支持 Markdown zip 压缩包和 WordPress WXR）")
+}
+
+// markdownFrontMatter Markdown 文件的 front matter
+type markdownFrontMatter struct {
+	Title    string   `yaml:"title"`
+	Date     string   `yaml:"date"`
+	Author   string   `yaml:"author"`
+	Tags     []string `yaml:"tags"`
+	Category string   `yaml:"category"`
+	Slug     string   `yaml:"slug"`
+	Draft    bool     `yaml:"draft"`
+}
+
+// parseMarkdownZip 解析 Markdown 压缩包：每个 .md 文件为一篇文章，标题依次取 front matter、
+// 第一个一级标题和文件名；来源标识取 front matter 的 slug，没有时取文件路径
+func parseMarkdownZip(data []byte) ([]importPost, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(zr.File) > importMaxFiles {
+		return nil, fmt.Errorf("压缩包内文件过多（最多 %d 个）", importMaxFiles)
+	}
+
+	var (
+		posts []importPost
+		total int64 // 已解压的总字节数
+	)
+	maxTotal := config.Cfg.ImportConfig.MaxUncompressed
+	for _, f := range zr.File {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if f.FileInfo().IsDir() || (ext != ".md" && ext != ".markdown") || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		if f.UncompressedSize64 > importMaxFileSize {
+			return nil, fmt.Errorf("%s: 文件过大", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		// 压缩包中记录的大小可能不实，按实际读出的字节数累计
+		body, err := io.ReadAll(io.LimitReader(rc, min(importMaxFileSize, maxTotal-total+1)))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if total += int64(len(body)); total > maxTotal {
+			return nil, fmt.Errorf("压缩包解压后超过 %d 字节", maxTotal)
+		}
+		post, err := parseMarkdown(f.Name, string(body))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// parseMarkdown 解析单个 Markdown 文件
+func parseMarkdown(name, text string) (importPost, error) {
+	text = strings.TrimPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff")
+	var fm markdownFrontMatter
+	if strings.HasPrefix(text, "---\n") {
+		end := strings.Index(text[4:], "\n---")
+		if end < 0 {
+			return importPost{}, errors.New("front matter 未结束")
+		}
+		if err := yaml.Unmarshal([]byte(text[4:4+end]), &fm); err != nil {
+			return importPost{}, fmt.Errorf("front matter 格式错误: %w", err)
+		}
+		text = strings.TrimLeft(text[4+end+4:], "-\n")
+	}
+
+	post := importPost{
+		Source:   "markdown:" + name,
+		Title:    fm.Title,
+		Content:  strings.TrimSpace(text),
+		Author:   fm.Author,
+		Tags:     fm.Tags,
+		Category: fm.Category,
+	}
+	if fm.Slug != "" {
+		post.Source = "markdown:" + fm.Slug
+	}
+	if post.Title == "" {
+		if first, rest, _ := strings.Cut(post.Content, "\n"); strings.HasPrefix(first, "# ") {
+			post.Title = strings.TrimSpace(first[2:])
+			post.Content = strings.TrimSpace(rest)
+		} else {
+			post.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+		}
+	}
+	if fm.Date != "" {
+		date, err := parseImportDate(fm.Date, time.Local)
+		if err != nil {
+			return importPost{}, err
+		}
+		post.Date = date
+	}
+	if fm.Draft {
+		post.SkipReason = "草稿"
+	}
+	return post, nil
+}
+
+// wxrExport WordPress 导出文件（WXR）中用到的部分
+type wxrExport struct {
+	Channel struct {
+		BlogURL string `xml:"base_blog_url"`
+		SiteURL string `xml:"base_site_url"`
+		Authors []struct {
+			ID    string `xml:"author_id"`
+			Login string `xml:"author_login"`
+		} `xml:"author"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// wxrItem WXR 中的文章
+type wxrItem struct {
+	Title      string `xml:"title"`
+	Creator    string `xml:"creator"`
+	Content    string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID     string `xml:"post_id"`
+	PostDate   string `xml:"post_date_gmt"`
+	Status     string `xml:"status"`
+	PostType   string `xml:"post_type"`
+	Categories []struct {
+		Domain string `xml:"domain,attr"`
+		Name   string `xml:",chardata"`
+	} `xml:"category"`
+	Comments []struct {
+		ID       string `xml:"comment_id"`
+		Author   string `xml:"comment_author"`
+		UserID   string `xml:"comment_user_id"`
+		Date     string `xml:"comment_date_gmt"`
+		Content  string `xml:"comment_content"`
+		Approved string `xml:"comment_approved"`
+		Type     string `xml:"comment_type"`
+		Parent   string `xml:"comment_parent"`
+	} `xml:"comment"`
+}
+
+// parseWXR 解析 WordPress 导出文件：只导入已发布的文章（post_type=post）和已审核的普通评论
+func parseWXR(data []byte) ([]importPost, error) {
+	var export wxrExport
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	if err := decoder.Decode(&export); err != nil {
+		return nil, fmt.Errorf("WXR 格式错误: %w", err)
+	}
+	logins := make(map[string]string, len(export.Channel.Authors))
+	for _, author := range export.Channel.Authors {
+		logins[author.ID] = author.Login
+	}
+	// 文章ID只在来源站点内唯一，来源标识带上站点地址，导入多个站点时互不覆盖
+	prefix := "wxr:"
+	if site := wxrSite(export.Channel.BlogURL, export.Channel.SiteURL); site != "" {
+		prefix += site + ":"
+	}
+
+	var posts []importPost
+	for _, item := range export.Channel.Items {
+		if item.PostType != "" && item.PostType != "post" {
+			continue // 页面、附件、菜单等
+		}
+		source := prefix + item.PostID
+		post := importPost{
+			Source:  source,
+			Title:   strings.TrimSpace(item.Title),
+			Content: strings.TrimSpace(item.Content),
+			Author:  item.Creator,
+		}
+		if item.Status != "publish" {
+			post.SkipReason = "未发布（" + item.Status + "）"
+		}
+		if date, err := parseImportDate(item.PostDate, time.UTC); err == nil {
+			post.Date = date
+		}
+		for _, c := range item.Categories {
+			switch c.Domain {
+			case "post_tag":
+				post.Tags = append(post.Tags, c.Name)
+			case "category":
+				if post.Category == "" && c.Name != "Uncategorized" {
+					post.Category = c.Name
+				}
+			}
+		}
+		for _, c := range item.Comments {
+			if c.Approved != "1" || (c.Type != "" && c.Type != "comment") {
+				continue // 未审核、垃圾评论和 pingback
+			}
+			comment := importComment{
+				Source:  source + "#comment-" + c.ID,
+				Author:  strings.TrimSpace(c.Author),
+				Content: strings.TrimSpace(c.Content),
+			}
+			if c.UserID != "" && c.UserID != "0" {
+				comment.AuthorLogin = logins[c.UserID]
+			}
+			if c.Parent != "" && c.Parent != "0" {
+				comment.ParentSource = source + "#comment-" + c.Parent
+			}
+			if date, err := parseImportDate(c.Date, time.UTC); err == nil {
+				comment.Date = date
+			}
+			post.Comments = append(post.Comments, comment)
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// wxrSite 取第一个不为空的站点地址，去掉协议和末尾的斜杠
+func wxrSite(urls ...string) string {
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if i := strings.Index(u, "://"); i >= 0 {
+			u = u[i+3:]
+		}
+		if u = strings.TrimRight(u, "/"); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// parseImportDate 解析导入文件中的日期，不带时区的日期按 loc 解析（WXR 的 GMT 时间和常见的 front matter 格式）
+func parseImportDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的日期: %s", s)
+}
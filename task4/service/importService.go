@@ -0,0 +1,378 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// 导入结果的处理方式
+const (
+	ImportCreate = "create" // 新建
+	ImportUpdate = "update" // 内容有变化，更新已导入的文章
+	ImportSkip   = "skip"   // 已导入且无变化，或不需要导入
+	ImportError  = "error"  // 导入失败（不影响其他文章）
+)
+
+// errDryRun 试运行结束后回滚事务
+var errDryRun = errors.New("dry run")
+
+// ImportService 批量导入文章（Markdown 压缩包、WordPress WXR）
+type ImportService struct {
+	db *gorm.DB
+}
+
+func NewImportService(db *gorm.DB) *ImportService {
+	return &ImportService{db: db}
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Format          string            // 导入格式，为空时按文件内容识别
+	DryRun          bool              // 试运行：完整执行后回滚，只返回报告
+	DefaultAuthorID uint              // 未映射的来源作者使用的作者
+	AuthorMap       map[string]string // 来源作者登录名 → 本站用户名（只按显式映射关联本站用户，不按登录名或邮箱自动匹配）
+}
+
+// ParseAuthorMap 解析作者映射（每项为 来源登录名=本站用户名）
+func ParseAuthorMap(pairs []string) (map[string]string, error) {
+	authorMap := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		login, username, ok := strings.Cut(pair, "=")
+		login, username = strings.TrimSpace(login), strings.TrimSpace(username)
+		if !ok || login == "" || username == "" {
+			return nil, util.NewErrno(util.ErrInvalidParam, "作者映射格式错误（应为 来源登录名=本站用户名）: %s", pair)
+		}
+		authorMap[login] = username
+	}
+	return authorMap, nil
+}
+
+// ImportItem 单篇文章的导入结果
+type ImportItem struct {
+	Source   string `json:"source"`
+	Title    string `json:"title"`
+	Action   string `json:"action"` // create/update/skip/error
+	PostID   uint   `json:"postId,omitempty"`
+	Comments int    `json:"comments,omitempty"` // 新导入的评论数
+	Message  string `json:"message,omitempty"`
+}
+
+// ImportReport 导入报告
+type ImportReport struct {
+	DryRun   bool              `json:"dryRun"`
+	Format   string            `json:"format"`
+	Created  int               `json:"created"`
+	Updated  int               `json:"updated"`
+	Skipped  int               `json:"skipped"`
+	Failed   int               `json:"failed"`
+	Comments int               `json:"comments"` // 新导入的评论数
+	Authors  map[string]string `json:"authors"`  // 来源作者 → 本站用户名（文章作者）
+	Items    []ImportItem      `json:"items"`
+}
+
+// Import 导入文章及其标签、分类和评论。通过导入记录识别已导入的内容，重复导入时跳过未变化的文章、
+// 更新有变化的文章并补充新评论。导入的内容不发送通知和 Webhook
+func (s *ImportService) Import(op Operator, data []byte, opts ImportOptions) (*ImportReport, error) {
+	format := opts.Format
+	if format == "" {
+		var err error
+		if format, err = detectImportFormat(data); err != nil {
+			return nil, util.NewErrno(util.ErrInvalidParam, "%s", err.Error())
+		}
+	}
+	var (
+		posts []importPost
+		err   error
+	)
+	switch format {
+	case ImportFormatMarkdown:
+		posts, err = parseMarkdownZip(data)
+	case ImportFormatWXR:
+		posts, err = parseWXR(data)
+	default:
+		return nil, util.NewErrno(util.ErrInvalidParam, "不支持的导入格式: %s", format)
+	}
+	if err != nil {
+		return nil, util.NewErrno(util.ErrInvalidParam, "%s", err.Error())
+	}
+
+	report := ImportReport{DryRun: opts.DryRun, Format: format, Authors: make(map[string]string)}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		im := importer{tx: tx, opts: opts, report: &report, users: make(map[string]*model.User)}
+		if err := im.loadAuthors(); err != nil {
+			return err
+		}
+		for i := range posts {
+			if err := im.importPost(&posts[i]); err != nil {
+				return err
+			}
+		}
+		if err := writeAudit(tx, op, "site.import", "site", 0, map[string]interface{}{
+			"format": format, "dryRun": opts.DryRun, "created": report.Created, "updated": report.Updated,
+		}); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// importer 一次导入的上下文
+type importer struct {
+	tx     *gorm.DB
+	opts   ImportOptions
+	report *ImportReport
+	users  map[string]*model.User // 映射的本站用户（按来源登录名）
+	author *model.User            // 默认作者
+	ghost  *model.User
+}
+
+// importPost 导入一篇文章，单篇失败时回滚到保存点并记入报告
+func (im *importer) importPost(p *importPost) error {
+	item := ImportItem{Source: p.Source, Title: p.Title}
+	if p.SkipReason != "" {
+		item.Action, item.Message = ImportSkip, p.SkipReason
+		im.add(item)
+		return nil
+	}
+	if err := im.tx.SavePoint("import_post").Error; err != nil {
+		return err
+	}
+	if err := im.savePost(p, &item); err != nil {
+		if rbErr := im.tx.RollbackTo("import_post").Error; rbErr != nil {
+			return rbErr
+		}
+		item.Action, item.Message, item.Comments = ImportError, err.Error(), 0
+	}
+	im.add(item)
+	return nil
+}
+
+// add 记录导入结果
+func (im *importer) add(item ImportItem) {
+	switch item.Action {
+	case ImportCreate:
+		im.report.Created++
+	case ImportUpdate:
+		im.report.Updated++
+	case ImportSkip:
+		im.report.Skipped++
+	case ImportError:
+		im.report.Failed++
+	}
+	im.report.Comments += item.Comments
+	im.report.Items = append(im.report.Items, item)
+}
+
+// savePost 新建或更新文章，并导入新评论
+func (im *importer) savePost(p *importPost, item *ImportItem) error {
+	if strings.TrimSpace(p.Title) == "" || strings.TrimSpace(p.Content) == "" {
+		return errors.New("标题或正文为空")
+	}
+	author := im.postAuthor(p.Author)
+	categoryID, err := im.category(p.Category)
+	if err != nil {
+		return err
+	}
+	checksum := importChecksum(p)
+
+	var record model.ImportRecord
+	err = im.tx.Where("source = ?", p.Source).First(&record).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		post := model.Post{
+			Title:      truncate(p.Title, 200),
+			Content:    p.Content,
+			UserID:     author.ID,
+			CategoryID: categoryID,
+		}
+		if !p.Date.IsZero() {
+			post.CreatedAt, post.UpdatedAt = p.Date, p.Date
+		}
+		if err := im.tx.Create(&post).Error; err != nil {
+			return err
+		}
+		if err := setPostTags(im.tx, &post, p.Tags); err != nil {
+			return err
+		}
+		if err := invalidateSitemap(im.tx, "id = ?", post.ID); err != nil {
+			return err
+		}
+		record = model.ImportRecord{Source: p.Source, TargetType: model.ReportTargetPost, TargetID: post.ID, Checksum: checksum}
+		if err := im.tx.Create(&record).Error; err != nil {
+			return err
+		}
+		item.Action = ImportCreate
+	case err != nil:
+		return err
+	case record.Checksum == checksum:
+		item.Action = ImportSkip
+		item.Message = "已导入且内容未变化"
+	default:
+		var post model.Post
+		if err := im.tx.First(&post, record.TargetID).Error; err != nil {
+			return errors.New("之前导入的文章已被删除")
+		}
+		if err := im.tx.Model(&post).Updates(map[string]interface{}{
+			"title":       truncate(p.Title, 200),
+			"content":     p.Content,
+			"category_id": categoryID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := setPostTags(im.tx, &post, p.Tags); err != nil {
+			return err
+		}
+		if err := invalidateSitemap(im.tx, "id = ?", post.ID); err != nil {
+			return err
+		}
+		if err := im.tx.Model(&record).Update("checksum", checksum).Error; err != nil {
+			return err
+		}
+		item.Action = ImportUpdate
+	}
+	item.PostID = record.TargetID
+	if category := p.Category; category != "" && categoryID == nil {
+		item.Message = "分类「" + category + "」不存在，未设置分类"
+	}
+
+	if item.Comments, err = im.importComments(record.TargetID, p.Comments); err != nil {
+		return err
+	}
+	return refreshHotScore(im.tx, record.TargetID)
+}
+
+// importComments 导入文章的新评论（已导入的跳过），按时间顺序导入以便先建立被回复的评论
+func (im *importer) importComments(postID uint, comments []importComment) (int, error) {
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].Date.Before(comments[j].Date) })
+	created := 0
+	for _, c := range comments {
+		if strings.TrimSpace(c.Content) == "" {
+			continue
+		}
+		var count int64
+		if err := im.tx.Model(&model.ImportRecord{}).Where("source = ?", c.Source).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		commenter, err := im.commenter(c.AuthorLogin)
+		if err != nil {
+			return 0, err
+		}
+		comment := model.Comment{Content: c.Content, PostID: postID, UserID: commenter.ID, Status: model.CommentApproved}
+		if commenter == im.ghost {
+			comment.AuthorName = truncate(c.Author, 100)
+		}
+		if !c.Date.IsZero() {
+			comment.CreatedAt, comment.UpdatedAt = c.Date, c.Date
+		}
+		if c.ParentSource != "" {
+			var parent model.ImportRecord
+			if err := im.tx.Where("source = ?", c.ParentSource).First(&parent).Error; err == nil {
+				comment.ParentID = &parent.TargetID
+			}
+		}
+		if err := im.tx.Create(&comment).Error; err != nil {
+			return 0, err
+		}
+		if err := im.tx.Create(&model.ImportRecord{
+			Source: c.Source, TargetType: model.ReportTargetComment, TargetID: comment.ID,
+		}).Error; err != nil {
+			return 0, err
+		}
+		created++
+	}
+	if created > 0 {
+		if err := im.tx.Model(&model.Post{}).Where("id = ?", postID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + ?", created)).Error; err != nil {
+			return 0, err
+		}
+	}
+	return created, nil
+}
+
+// loadAuthors 查询默认作者和映射的本站用户（映射的用户不存在时导入失败）
+func (im *importer) loadAuthors() error {
+	im.author = &model.User{}
+	if err := im.tx.Select("id", "username").Where("role <> ?", model.RoleGhost).
+		First(im.author, im.opts.DefaultAuthorID).Error; err != nil {
+		return util.NewErrno(util.ErrUserNotExist, "默认作者不存在")
+	}
+	for login, username := range im.opts.AuthorMap {
+		var user model.User
+		if err := im.tx.Select("id", "username").Where("username = ? AND role <> ?", username, model.RoleGhost).
+			First(&user).Error; err != nil {
+			return util.NewErrno(util.ErrUserNotExist, "作者映射 %s 对应的用户 %s 不存在", login, username)
+		}
+		im.users[login] = &user
+	}
+	return nil
+}
+
+// postAuthor 文章作者：按显式映射关联本站用户，未映射时使用默认作者
+func (im *importer) postAuthor(login string) *model.User {
+	user, ok := im.users[login]
+	if !ok {
+		user = im.author
+	}
+	if login != "" {
+		im.report.Authors[login] = user.Username
+	}
+	return user
+}
+
+// commenter 评论者：来源站点注册用户按显式映射关联本站用户，其他评论者使用已注销用户的占位账号（评论保留原评论者名称）
+func (im *importer) commenter(login string) (*model.User, error) {
+	if user, ok := im.users[login]; ok && login != "" {
+		return user, nil
+	}
+	if im.ghost == nil {
+		var err error
+		if im.ghost, err = ghostUser(im.tx); err != nil {
+			return nil, err
+		}
+	}
+	return im.ghost, nil
+}
+
+// category 按名称匹配分类（不自动创建，分类由管理员维护）
+func (im *importer) category(name string) (*uint, error) {
+	if name == "" {
+		return nil, nil
+	}
+	var ids []uint
+	if err := im.tx.Model(&model.Category{}).Where("name = ?", name).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ids[0], nil
+}
+
+// importChecksum 文章导入内容的摘要
+func importChecksum(p *importPost) string {
+	h := sha256.New()
+	for _, s := range append([]string{p.Title, p.Content, p.Category, p.Date.UTC().Format(time.RFC3339)}, p.Tags...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gotask/task4/model"
+)
+
+// testWXR 包含两位作者的 WXR：alice 的文章下有 bob（已登录）和匿名访客的评论
+const testWXR = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<wp:base_site_url>https://old.example.com</wp:base_site_url>
+	<wp:base_blog_url>https://old.example.com/blog/</wp:base_blog_url>
+	<wp:author><wp:author_id>1</wp:author_id><wp:author_login>alice</wp:author_login><wp:author_email>alice@example.com</wp:author_email></wp:author>
+	<wp:author><wp:author_id>2</wp:author_id><wp:author_login>bob</wp:author_login><wp:author_email>bob@example.com</wp:author_email></wp:author>
+	<item>
+		<title>Hello</title>
+		<dc:creator>alice</dc:creator>
+		<content:encoded><![CDATA[Hello world]]></content:encoded>
+		<wp:post_id>10</wp:post_id>
+		<wp:post_date_gmt>2020-01-02 03:04:05</wp:post_date_gmt>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<wp:comment>
+			<wp:comment_id>1</wp:comment_id>
+			<wp:comment_author>Bob</wp:comment_author>
+			<wp:comment_author_email>bob@example.com</wp:comment_author_email>
+			<wp:comment_date_gmt>2020-01-03 00:00:00</wp:comment_date_gmt>
+			<wp:comment_content>Nice</wp:comment_content>
+			<wp:comment_approved>1</wp:comment_approved>
+			<wp:comment_user_id>2</wp:comment_user_id>
+		</wp:comment>
+		<wp:comment>
+			<wp:comment_id>2</wp:comment_id>
+			<wp:comment_author>Visitor</wp:comment_author>
+			<wp:comment_author_email>admin@example.com</wp:comment_author_email>
+			<wp:comment_date_gmt>2020-01-04 00:00:00</wp:comment_date_gmt>
+			<wp:comment_content>Thanks</wp:comment_content>
+			<wp:comment_approved>1</wp:comment_approved>
+			<wp:comment_user_id>0</wp:comment_user_id>
+		</wp:comment>
+	</item>
+</channel>
+</rss>`
+
+func TestImportMapsAuthorsExplicitly(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorMap     map[string]string
+		wantErr       bool
+		wantAuthor    string // 文章作者
+		wantBobAuthor string // bob 的评论者
+	}{
+		// 本站的 alice、bob 与来源作者同名同邮箱，但未映射时不关联
+		{"no mapping", nil, false, "admin", model.GhostUsername},
+		{"mapped", map[string]string{"alice": "alice", "bob": "bob"}, false, "alice", "bob"},
+		{"mapped to other user", map[string]string{"alice": "bob"}, false, "bob", model.GhostUsername},
+		{"unknown local user", map[string]string{"alice": "nobody"}, true, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			admin := createTestUser(t, db, "admin")
+			createTestUser(t, db, "alice")
+			createTestUser(t, db, "bob")
+
+			report, err := NewImportService(db).Import(Operator{UserID: admin.ID}, []byte(testWXR), ImportOptions{
+				DefaultAuthorID: admin.ID,
+				AuthorMap:       tt.authorMap,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Import() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if report.Created != 1 || report.Comments != 2 || report.Authors["alice"] != tt.wantAuthor {
+				t.Fatalf("report = %+v", report)
+			}
+
+			var post model.Post
+			if err := db.Preload("User").First(&post, report.Items[0].PostID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if post.User.Username != tt.wantAuthor {
+				t.Fatalf("post author = %s, want %s", post.User.Username, tt.wantAuthor)
+			}
+			var comments []model.Comment
+			if err := db.Preload("User").Order("id").Find(&comments).Error; err != nil {
+				t.Fatal(err)
+			}
+			if comments[0].User.Username != tt.wantBobAuthor {
+				t.Fatalf("bob's comment author = %s, want %s", comments[0].User.Username, tt.wantBobAuthor)
+			}
+			// 匿名访客的邮箱与本站用户相同也不关联，归入占位账号并保留原评论者名称
+			if comments[1].User.Role != model.RoleGhost || comments[1].AuthorName != "Visitor" {
+				t.Fatalf("visitor comment = %+v, want ghost with author name", comments[1])
+			}
+			wantName := ""
+			if tt.wantBobAuthor == model.GhostUsername {
+				wantName = "Bob"
+			}
+			if comments[0].AuthorName != wantName {
+				t.Fatalf("bob's comment author name = %q, want %q", comments[0].AuthorName, wantName)
+			}
+		})
+	}
+}
+
+func TestParseAuthorMap(t *testing.T) {
+	tests := []struct {
+		pairs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{nil, map[string]string{}, false},
+		{[]string{"alice=alice2", " bob = robert "}, map[string]string{"alice": "alice2", "bob": "robert"}, false},
+		{[]string{"alice"}, nil, true},
+		{[]string{"=bob"}, nil, true},
+		{[]string{"alice="}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAuthorMap(tt.pairs)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseAuthorMap(%v) error = %v, wantErr %v", tt.pairs, err, tt.wantErr)
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("ParseAuthorMap(%v) = %v, want %v", tt.pairs, got, tt.want)
+		}
+	}
+}
+
+func TestParseWXRSourceIncludesSite(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"blog url", testWXR, "wxr:old.example.com/blog:10"},
+		{"site url only", strings.Replace(testWXR, "<wp:base_blog_url>https://old.example.com/blog/</wp:base_blog_url>", "", 1), "wxr:old.example.com:10"},
+		{"other site", strings.ReplaceAll(testWXR, "old.example.com", "other.example.org"), "wxr:other.example.org/blog:10"},
+	}
+	for _, tt := range tests {
+		posts, err := parseWXR([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(posts) != 1 || posts[0].Source != tt.want {
+			t.Fatalf("%s: posts = %+v, want source %q", tt.name, posts, tt.want)
+		}
+		if c := posts[0].Comments[0]; c.Source != tt.want+"#comment-1" {
+			t.Fatalf("%s: comment source = %q", tt.name, c.Source)
+		}
+	}
+}
+
+func TestParseMarkdownZipTotalLimit(t *testing.T) {
+	build := func(files, size int) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for i := 0; i < files; i++ {
+			w, err := zw.Create(fmt.Sprintf("post-%d.md", i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("# Title\n\n" + strings.Repeat("a", size)))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	// 测试配置的上限为 1MB，单个文件都不超过单文件上限
+	tests := []struct {
+		name    string
+		files   int
+		size    int
+		wantErr bool
+	}{
+		{"under total", 3, 300 << 10, false},
+		{"over total", 4, 300 << 10, true},
+	}
+	for _, tt := range tests {
+		posts, err := parseMarkdownZip(build(tt.files, tt.size))
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if !tt.wantErr && len(posts) != tt.files {
+			t.Fatalf("%s: posts = %d, want %d", tt.name, len(posts), tt.files)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// LikeService 点赞服务
+type LikeService struct {
+	db *gorm.DB
+}
+
+func NewLikeService(db *gorm.DB) *LikeService {
+	return &LikeService{db: db}
+}
+
+// Like 点赞文章或评论（重复点赞直接返回成功），首次点赞时通知内容作者
+func (s *LikeService) Like(userID uint, targetType string, targetID uint) error {
+	return transaction(s.db, func(tx *gorm.DB) error {
+		target, err := likeTarget(tx, targetType, targetID)
+		if err != nil {
+			return err
+		}
+
+		// 依靠唯一索引去重：并发的重复点赞只有一个能插入，其余直接返回（不重复计数）
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.Like{UserID: userID, TargetType: targetType, TargetID: targetID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Model(target.model).Where("id = ?", targetID).
+			UpdateColumn("like_count", gorm.Expr("like_count + 1")).Error; err != nil {
+			return err
+		}
+		if targetType == model.LikeTargetPost {
+			if err := recordPostActivity(tx, targetID, statLikes, 1); err != nil {
+				return err
+			}
+		}
+
+		// 取消后再次点赞时，若上次的通知仍未读则不重复通知
+		var count int64
+		if err := tx.Model(&model.Notification{}).
+			Where("user_id = ? AND actor_id = ? AND type = ? AND target_type = ? AND target_id = ? AND read_at IS NULL",
+				target.authorID, userID, model.NotifyLike, targetType, targetID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		return notify(tx, model.Notification{
+			UserID:     target.authorID,
+			ActorID:    userID,
+			Type:       model.NotifyLike,
+			TargetType: targetType,
+			TargetID:   targetID,
+			PostID:     target.postID,
+			Excerpt:    excerpt(target.content),
+		})
+	})
+}
+
+// Unlike 取消点赞
+func (s *LikeService) Unlike(userID uint, targetType string, targetID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+			Delete(&model.Like{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if targetType == model.LikeTargetComment {
+			return tx.Model(&model.Comment{}).Where("id = ? AND like_count > 0", targetID).
+				UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error
+		}
+		if err := tx.Model(&model.Post{}).Where("id = ? AND like_count > 0", targetID).
+			UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error; err != nil {
+			return err
+		}
+		return recordPostActivity(tx, targetID, statLikes, -1)
+	})
+}
+
+// likedTarget 被点赞的内容
+type likedTarget struct {
+	model    interface{}
+	authorID uint
+	postID   uint
+	content  string
+}
+
+// likeTarget 查询被点赞的内容（只能点赞公开展示的文章和评论）
+func likeTarget(tx *gorm.DB, targetType string, targetID uint) (*likedTarget, error) {
+	switch targetType {
+	case model.LikeTargetPost:
+		var post model.Post
+		if err := tx.Where("id = ? AND hidden = ?", targetID, false).First(&post).Error; err != nil {
+			return nil, util.ErrPostNotExist
+		}
+		return &likedTarget{model: &model.Post{}, authorID: post.UserID, postID: post.ID, content: post.Title}, nil
+	case model.LikeTargetComment:
+		var comment model.Comment
+		if err := tx.Where("id = ? AND status = ? AND hidden = ?", targetID, model.CommentApproved, false).
+			First(&comment).Error; err != nil {
+			return nil, util.ErrCommentNotExist
+		}
+		return &likedTarget{model: &model.Comment{}, authorID: comment.UserID, postID: comment.PostID, content: comment.Content}, nil
+	}
+	return nil, util.ErrInvalidParam
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+
+	"gotask/task4/model"
+)
+
+func TestLikeIsIdempotent(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	reader := createTestUser(t, db, "reader")
+	racer := createTestUser(t, db, "racer")
+	post := createTestPost(t, db, author.ID, "hello")
+	s := NewLikeService(db)
+
+	like := func(userID uint) func() error {
+		return func() error { return s.Like(userID, model.LikeTargetPost, post.ID) }
+	}
+	steps := []struct {
+		name string
+		do   func() error
+		want int
+	}{
+		{"liked", like(reader.ID), 1},
+		{"liked again", like(reader.ID), 1},
+		// 模拟并发请求：另一事务已先插入点赞记录，本次插入冲突后不应再计数
+		{"lost race", func() error {
+			if err := db.Create(&model.Like{UserID: racer.ID, TargetType: model.LikeTargetPost, TargetID: post.ID}).Error; err != nil {
+				return err
+			}
+			return s.Like(racer.ID, model.LikeTargetPost, post.ID)
+		}, 1},
+		{"unliked", func() error { return s.Unlike(reader.ID, model.LikeTargetPost, post.ID) }, 0},
+		{"liked after unlike", like(reader.ID), 1},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		var got model.Post
+		if err := db.First(&got, post.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.LikeCount != step.want {
+			t.Fatalf("%s: like_count = %d, want %d", step.name, got.LikeCount, step.want)
+		}
+	}
+
+	var rows int64
+	if err := db.Model(&model.Like{}).Where("user_id = ?", reader.ID).Count(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Fatalf("like rows = %d, want 1", rows)
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// 单条内容最多解析的提及人数（防止刷屏通知）
+const maxMentions = 20
+
+// @用户名：前面不能紧跟字母数字（排除邮箱地址），用户名匹配字母（包括中日韩等文字）、数字和 _ . -
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.\-]+)`)
+
+// 可被提及的用户名长度（按字符计）：注册要求至少 3 个字符，外部登录和导入生成的用户名可能更长
+const (
+	mentionMinLen = 3
+	mentionMaxLen = 50
+)
+
+// MentionService 提及服务
+type MentionService struct {
+	db *gorm.DB
+}
+
+func NewMentionService(db *gorm.DB) *MentionService {
+	return &MentionService{db: db}
+}
+
+// ListByUser 分页查询提及了某用户的记录
+func (s *MentionService) ListByUser(userID uint, page, pageSize int) (*util.PageResult, error) {
+	var (
+		mentions []model.Mention
+		total    int64
+	)
+	db := s.db.Model(&model.Mention{}).Where("mentioned_user_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Preload("Author", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&mentions).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(mentions, total, page, pageSize), nil
+}
+
+// parseMentions 解析内容中的 @用户名（去重，保持出现顺序）
+func parseMentions(content string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(m[1], ".-") // 句末的标点不属于用户名
+		key := strings.ToLower(name)
+		if n := utf8.RuneCountInString(name); n < mentionMinLen || n > mentionMaxLen || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// syncMentions 根据最新内容同步提及记录：删除不再提及的，为新提及的用户写入记录并发送通知。
+// 需在内容写入的同一事务中调用
+func syncMentions(tx *gorm.DB, sourceType string, sourceID, postID, authorID uint, content string) error {
+	var users []model.User
+	if names := parseMentions(content); len(names) > 0 {
+		if err := tx.Select("ID", "Username").
+			Where("username IN ? AND id <> ? AND role <> ?", names, authorID, model.RoleGhost).
+			Find(&users).Error; err != nil {
+			return err
+		}
+	}
+
+	var existing []uint
+	if err := tx.Unscoped().Model(&model.Mention{}).
+		Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Pluck("mentioned_user_id", &existing).Error; err != nil {
+		return err
+	}
+	current := make(map[uint]bool, len(users))
+	for _, u := range users {
+		current[u.ID] = true
+	}
+	var removed []uint
+	known := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+		if !current[id] {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Unscoped().Where("source_type = ? AND source_id = ? AND mentioned_user_id IN ?", sourceType, sourceID, removed).
+			Delete(&model.Mention{}).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, u := range users {
+		if known[u.ID] {
+			continue // 已提及过的用户不重复通知
+		}
+		if err := tx.Create(&model.Mention{
+			SourceType:      sourceType,
+			SourceID:        sourceID,
+			MentionedUserID: u.ID,
+			AuthorID:        authorID,
+			PostID:          postID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := notify(tx, model.Notification{
+			UserID:     u.ID,
+			ActorID:    authorID,
+			Type:       model.NotifyMention,
+			TargetType: sourceType,
+			TargetID:   sourceID,
+			PostID:     postID,
+			Excerpt:    excerpt(content),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify 写入一条站内通知并实时推送给接收人（不给自己发通知）
+func notify(tx *gorm.DB, n model.Notification) error {
+	if n.UserID == n.ActorID {
+		return nil
+	}
+	if err := tx.Create(&n).Error; err != nil {
+		return err
+	}
+	publish(tx, UserTopic(n.UserID), EventNotification, n)
+	return nil
+}
+
+// excerpt 截取内容摘要（按字符截断）
+func excerpt(content string) string {
+	r := []rune(strings.TrimSpace(content))
+	if len(r) <= 60 {
+		return string(r)
+	}
+	return string(r[:60]) + "…"
+}
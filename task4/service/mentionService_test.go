@@ -0,0 +1,52 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gotask/task4/model"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"hi @alice and @bob.", []string{"alice", "bob"}},
+		{"你好 @张三丰 和 @山田太郎", []string{"张三丰", "山田太郎"}},
+		{"@张三 少于三个字符", nil},
+		{"（@欧阳修）请看", []string{"欧阳修"}},
+		{"mail alice@example.com", nil},
+		{"邮箱 张三@example.com", nil},
+		{"@ab too short, @Alice @alice dedupe", []string{"Alice"}},
+		{"@abcdefghijklmnopqrstuvwxyz longer than registration allows", []string{"abcdefghijklmnopqrstuvwxyz"}},
+		{"@" + strings.Repeat("a", 51) + " too long", nil},
+	}
+	for _, tt := range tests {
+		if got := parseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMentions(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestSyncMentionsExistingUsernames(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	// 外部登录生成的用户名可能超过注册时的长度上限，也应能被提及
+	long := createTestUser(t, db, "very.long-username_from_sso")
+	short := createTestUser(t, db, "张三丰")
+	post := createTestPost(t, db, author.ID, "hello")
+
+	if err := syncMentions(db, model.MentionSourcePost, post.ID, post.ID, author.ID,
+		"thanks @very.long-username_from_sso and @张三丰"); err != nil {
+		t.Fatal(err)
+	}
+	var mentioned []uint
+	if err := db.Model(&model.Mention{}).Order("mentioned_user_id").Pluck("mentioned_user_id", &mentioned).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint{long.ID, short.ID}; !reflect.DeepEqual(mentioned, want) {
+		t.Fatalf("mentioned = %v, want %v", mentioned, want)
+	}
+}
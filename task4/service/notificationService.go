@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// NotificationService 站内通知服务
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// List 分页查询用户的通知（按时间倒序，可只看未读）
+func (s *NotificationService) List(userID uint, unreadOnly bool, page, pageSize int) (*util.PageResult, error) {
+	var (
+		notifications []model.Notification
+		total         int64
+	)
+	db := s.db.Model(&model.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Preload("Actor", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(notifications, total, page, pageSize), nil
+}
+
+// MarkRead 将指定通知标记为已读（只处理属于该用户的通知），返回处理条数
+func (s *NotificationService) MarkRead(userID uint, ids []uint) (int64, error) {
+	result := s.db.Model(&model.Notification{}).
+		Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, ids).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// MarkAllRead 将用户的全部未读通知标记为已读，返回处理条数
+func (s *NotificationService) MarkAllRead(userID uint) (int64, error) {
+	result := s.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// UnreadCount 查询用户的未读通知数
+func (s *NotificationService) UnreadCount(userID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// HandleCommentCreated 发件箱订阅者：评论发布后通知文章作者和被回复者
+func (s *NotificationService) HandleCommentCreated(ctx context.Context, event *model.OutboxEvent) error {
+	var data CommentEventData
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return err
+	}
+	comment := model.Comment{PostID: data.PostID, UserID: data.UserID, ParentID: data.ParentID, Content: data.Content}
+	comment.ID = data.CommentID
+	err := transaction(s.db.WithContext(ctx), func(tx *gorm.DB) error {
+		return notifyComment(tx, &comment)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // 文章或被回复的评论已随账号注销被删除
+	}
+	return err
+}
+
+// notifyComment 评论发布后通知文章作者；回复评论时通知被回复的评论者。
+// 事件可能重复投递，已发送过的通知不再重复发送
+func notifyComment(tx *gorm.DB, comment *model.Comment) error {
+	var post model.Post
+	if err := tx.Select("id", "user_id").First(&post, comment.PostID).Error; err != nil {
+		return err
+	}
+	n := model.Notification{
+		ActorID:    comment.UserID,
+		TargetType: model.MentionSourceComment,
+		TargetID:   comment.ID,
+		PostID:     comment.PostID,
+		Excerpt:    excerpt(comment.Content),
+	}
+
+	if comment.ParentID != nil {
+		var parent model.Comment
+		if err := tx.Select("id", "user_id").First(&parent, *comment.ParentID).Error; err != nil {
+			return err
+		}
+		n.UserID, n.Type = parent.UserID, model.NotifyReply
+		if err := notifyOnce(tx, n); err != nil {
+			return err
+		}
+		if parent.UserID == post.UserID {
+			return nil // 回复的正是文章作者，不再重复通知
+		}
+	}
+
+	n.UserID, n.Type = post.UserID, model.NotifyComment
+	return notifyOnce(tx, n)
+}
+
+// notifyOnce 同一接收人、类型和对象的通知只发送一次
+func notifyOnce(tx *gorm.DB, n model.Notification) error {
+	var count int64
+	if err := tx.Model(&model.Notification{}).
+		Where("user_id = ? AND type = ? AND target_type = ? AND target_id = ?", n.UserID, n.Type, n.TargetType, n.TargetID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return notify(tx, n)
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// OIDCLoginTTL 登录流程（state）有效期，也是浏览器中 state cookie 的有效期
+const OIDCLoginTTL = 10 * time.Minute
+
+// OIDCService 外部身份登录服务（授权码 + PKCE）
+type OIDCService struct {
+	db             *gorm.DB
+	sessionService *SessionService
+	cfg            config.OIDCConfig
+	httpClient     *http.Client
+
+	mu   sync.Mutex
+	meta *oidcMetadata               // 发现文档（首次使用时加载）
+	keys map[string]crypto.PublicKey // 身份提供方验签公钥（kid -> key）
+}
+
+// oidcMetadata 发现文档中用到的字段
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims ID Token 载荷
+type idTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+func NewOIDCService(db *gorm.DB, sessionService *SessionService) *OIDCService {
+	return &OIDCService{
+		db:             db,
+		sessionService: sessionService,
+		cfg:            config.Cfg.OIDCConfig,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		keys:           make(map[string]crypto.PublicKey),
+	}
+}
+
+// Enabled 是否开启了 OIDC 登录
+func (s *OIDCService) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+// AuthURL 生成跳转到身份提供方的授权地址，并在数据库中记录 state 对应的 PKCE verifier 和 nonce；
+// 返回的 state 需由调用方写入浏览器 cookie，回调时校验。linkUserID 非零表示已登录用户发起的账号绑定
+func (s *OIDCService) AuthURL(ctx context.Context, linkUserID uint) (string, string, error) {
+	meta, err := s.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, nonce := util.RandomHex(16), util.RandomHex(16)
+	verifier := oauth2.GenerateVerifier()
+
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	if err := db.Where("expires_at < ?", now).Delete(&model.OAuthLogin{}).Error; err != nil {
+		return "", "", err
+	}
+	login := model.OAuthLogin{
+		StateHash: util.HashToken(state),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ExpiresAt: now.Add(OIDCLoginTTL),
+	}
+	if linkUserID != 0 {
+		login.LinkUserID = &linkUserID
+	}
+	if err := db.Create(&login).Error; err != nil {
+		return "", "", err
+	}
+
+	authURL := s.oauthConfig(meta).AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	return authURL, state, nil
+}
+
+// Login 处理回调：校验 state 与发起登录的浏览器 cookie 一致，用授权码换取令牌、校验 ID Token、
+// 关联本地用户，返回本站 JWT
+func (s *OIDCService) Login(ctx context.Context, code, state, cookieState string, client ClientInfo) (string, error) {
+	// state 必须来自同一浏览器，防止把攻击者的回调地址发给受害者完成登录（login CSRF）
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		return "", util.ErrOAuthState
+	}
+	login, err := s.consumeLogin(ctx, state)
+	if err != nil {
+		return "", err
+	}
+
+	meta, err := s.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
+	token, err := s.oauthConfig(meta).Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return "", util.NewErrno(util.ErrOAuthFailed, "%v", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return "", util.NewErrno(util.ErrOAuthFailed, "缺少 id_token")
+	}
+	claims, err := s.verifyIDToken(ctx, meta, rawIDToken, login.Nonce)
+	if err != nil {
+		return "", util.NewErrno(util.ErrOAuthFailed, "%v", err)
+	}
+
+	user, err := s.findOrCreateUser(meta.Issuer, claims, login.LinkUserID)
+	if err != nil {
+		return "", err
+	}
+	if err := user.Blocked(); err != nil {
+		return "", err
+	}
+	return s.sessionService.Issue(user, client)
+}
+
+// consumeLogin 取出 state 对应的登录记录并删除（state 只能使用一次，并发回调时只有一个成功）
+func (s *OIDCService) consumeLogin(ctx context.Context, state string) (*model.OAuthLogin, error) {
+	db := s.db.WithContext(ctx)
+	var login model.OAuthLogin
+	if err := db.Where("state_hash = ?", util.HashToken(state)).First(&login).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, util.ErrOAuthState
+		}
+		return nil, err
+	}
+	result := db.Delete(&login)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().After(login.ExpiresAt) {
+		return nil, util.ErrOAuthState
+	}
+	return &login, nil
+}
+
+// oauthConfig 组装 oauth2 客户端配置
+func (s *OIDCService) oauthConfig(meta *oidcMetadata) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Scopes:       s.cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  meta.AuthorizationEndpoint,
+			TokenURL: meta.TokenEndpoint,
+		},
+	}
+}
+
+// discover 加载并缓存发现文档
+func (s *OIDCService) discover(ctx context.Context) (*oidcMetadata, error) {
+	s.mu.Lock()
+	meta := s.meta
+	s.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	url := strings.TrimSuffix(s.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	meta = &oidcMetadata{}
+	if err := s.getJSON(ctx, url, meta); err != nil {
+		return nil, fmt.Errorf("加载 OIDC 发现文档失败: %v", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(s.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("OIDC issuer 不匹配: %s", meta.Issuer)
+	}
+
+	s.mu.Lock()
+	s.meta = meta
+	s.mu.Unlock()
+	return meta, nil
+}
+
+// verifyIDToken 校验 ID Token 的签名、issuer、audience、有效期和 nonce
+func (s *OIDCService) verifyIDToken(ctx context.Context, meta *oidcMetadata, raw, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return s.publicKey(ctx, meta, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(s.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("nonce 不匹配")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("缺少 sub")
+	}
+	return claims, nil
+}
+
+// publicKey 按 kid 查找身份提供方公钥，未命中时重新拉取 JWKS（应对对方密钥轮换）
+func (s *OIDCService) publicKey(ctx context.Context, meta *oidcMetadata, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var set util.JWKSet
+	if err := s.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if pub, err := util.ParseJWK(jwk); err == nil {
+			keys[jwk.Kid] = pub
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	if key, ok = keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown kid: %q", kid)
+	}
+	return key, nil
+}
+
+// findOrCreateUser 按外部身份查找本地用户；linkUserID 非空时把外部身份绑定到该用户，
+// 否则首次登录时创建新用户（邮箱与本地账号相同时不自动关联，需本人登录后主动绑定）
+func (s *OIDCService) findOrCreateUser(provider string, claims *idTokenClaims, linkUserID *uint) (*model.User, error) {
+	var user model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var identity model.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, claims.Subject).First(&identity).Error
+		if err == nil {
+			if linkUserID != nil && identity.UserID != *linkUserID {
+				return util.ErrOAuthLinked
+			}
+			return tx.First(&user, identity.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if linkUserID != nil {
+			if err := tx.Where("role <> ?", model.RoleGhost).First(&user, *linkUserID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return util.ErrUserNotExist
+				}
+				return err
+			}
+		} else {
+			if claims.Email != "" {
+				var count int64
+				if err := tx.Model(&model.User{}).Where("email = ? AND role <> ?", claims.Email, model.RoleGhost).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					return util.ErrOAuthLinkRequired
+				}
+			}
+			created, err := createExternalUser(tx, claims)
+			if err != nil {
+				return err
+			}
+			user = *created
+		}
+
+		return tx.Create(&model.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// usernameInvalidChars 生成用户名时去掉的字符（只保留 @提及能解析的字母、数字和 _ . -）
+var usernameInvalidChars = regexp.MustCompile(`[^\p{L}\p{N}_.\-]`)
+
+// createExternalUser 为首次登录的外部身份创建本地用户（随机密码，只能通过 OIDC 登录）
+func createExternalUser(tx *gorm.DB, claims *idTokenClaims) (*model.User, error) {
+	base := claims.PreferredUsername
+	if base == "" && claims.Email != "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if runes := []rune(base); len(runes) < 3 {
+		base = "user"
+	} else if len(runes) > 15 {
+		base = string(runes[:15]) // 留出重名时追加后缀的长度
+	}
+
+	email := claims.Email
+	if email == "" {
+		email = claims.Subject + "@oidc.invalid"
+	}
+	var count int64
+	if err := tx.Model(&model.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		// 与幽灵账号等无法登录的本地账号重复，不能复用
+		return nil, util.NewErrno(util.ErrEmailExist, "email=%s", email)
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		if err := tx.Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		username = base + "_" + util.RandomHex(2)
+	}
+
+	user := model.User{
+		Username: username,
+		Email:    email,
+		Password: util.RandomHex(16), // BeforeSave 钩子中加密
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// getJSON 请求并解析 JSON
+func (s *OIDCService) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
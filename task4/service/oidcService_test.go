@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// oidcStub 测试用的身份提供方：授权后签发携带 nonce 的 ID Token，兑换授权码时校验 PKCE
+type oidcStub struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]oidcStubGrant // 授权码 -> 授权信息
+	claims map[string]interface{}   // 下一次签发的 ID Token 中的用户信息
+}
+
+type oidcStubGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newOIDCStub(t *testing.T) *oidcStub {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &oidcStub{key: key, codes: make(map[string]oidcStubGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.server.URL,
+			"authorization_endpoint": stub.server.URL + "/authorize",
+			"token_endpoint":         stub.server.URL + "/token",
+			"jwks_uri":               stub.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(util.JWKSet{Keys: []util.JWK{{
+			Kty: "RSA", Kid: "stub", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		stub.mu.Lock()
+		grant, ok := stub.codes[r.Form.Get("code")]
+		delete(stub.codes, r.Form.Get("code"))
+		claims := jwt.MapClaims{"iss": stub.server.URL, "aud": "blog", "exp": time.Now().Add(time.Minute).Unix(), "nonce": grant.nonce}
+		for k, v := range stub.claims {
+			claims[k] = v
+		}
+		stub.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		idToken, _ := token.SignedString(key)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access", "token_type": "Bearer", "expires_in": 60, "id_token": idToken,
+		})
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// authorize 模拟用户在身份提供方登录并同意授权，返回回调中的 code 和 state
+func (stub *oidcStub) authorize(t *testing.T, authURL string, claims map[string]interface{}) (string, string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorize without PKCE: %s", authURL)
+	}
+	code := util.RandomHex(8)
+	stub.mu.Lock()
+	stub.codes[code] = oidcStubGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	stub.claims = claims
+	stub.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func TestOIDCLogin(t *testing.T) {
+	stub := newOIDCStub(t)
+	db := newTestDB(t)
+	config.Cfg.OIDCConfig = config.OIDCConfig{Enabled: true, Issuer: stub.server.URL, ClientID: "blog", RedirectURL: "http://blog/callback"}
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		claims       map[string]interface{}
+		linkUserID   uint                                      // 已登录用户发起绑定
+		tamper       func(code, state string) (string, string) // 修改回调参数
+		cookie       func(state string) string                 // 回调时浏览器携带的 state cookie
+		restart      bool                                      // 授权期间服务重启（或回调落到其他实例）
+		wantErr      *util.Errno
+		wantUsername string
+	}{
+		{"new user", map[string]interface{}{"sub": "1", "preferred_username": "张三丰"}, 0, nil, nil, false, nil, "张三丰"},
+		{"same subject", map[string]interface{}{"sub": "1"}, 0, nil, nil, false, nil, "张三丰"},
+		{"verified email requires linking", map[string]interface{}{"sub": "2", "email": "alice@example.com", "email_verified": true}, 0, nil, nil, false, util.ErrOAuthLinkRequired, ""},
+		{"signed-in user links account", map[string]interface{}{"sub": "2", "email": "alice@example.com", "email_verified": true}, alice.ID, nil, nil, false, nil, "alice"},
+		{"linked subject logs in", map[string]interface{}{"sub": "2"}, 0, nil, nil, false, nil, "alice"},
+		{"subject linked to another user", map[string]interface{}{"sub": "2"}, bob.ID, nil, nil, false, util.ErrOAuthLinked, ""},
+		{"survives restart", map[string]interface{}{"sub": "1"}, 0, nil, nil, true, nil, "张三丰"},
+		{"missing state cookie", map[string]interface{}{"sub": "1"}, 0, nil, func(string) string { return "" }, false, util.ErrOAuthState, ""},
+		{"state from another browser", map[string]interface{}{"sub": "1"}, 0, nil, func(string) string { return "other" }, false, util.ErrOAuthState, ""},
+		{"unknown state", map[string]interface{}{"sub": "1"}, 0, func(code, state string) (string, string) { return code, "forged" }, func(string) string { return "forged" }, false, util.ErrOAuthState, ""},
+		{"wrong code", map[string]interface{}{"sub": "1"}, 0, func(code, state string) (string, string) { return "bad", state }, nil, false, util.ErrOAuthFailed, ""},
+		{"nonce mismatch", map[string]interface{}{"sub": "1", "nonce": "other"}, 0, nil, nil, false, util.ErrOAuthFailed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewOIDCService(db, NewSessionService(db))
+			authURL, cookie, err := s.AuthURL(ctx, tt.linkUserID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, state := stub.authorize(t, authURL, tt.claims)
+			if state != cookie {
+				t.Fatalf("state = %s, want cookie value %s", state, cookie)
+			}
+			if tt.tamper != nil {
+				code, state = tt.tamper(code, state)
+			}
+			if tt.cookie != nil {
+				cookie = tt.cookie(cookie)
+			}
+			if tt.restart {
+				s = NewOIDCService(db, NewSessionService(db))
+			}
+			token, err := s.Login(ctx, code, state, cookie, ClientInfo{})
+			var errno *util.Errno
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && (!errors.As(err, &errno) || errno.Code != tt.wantErr.Code)) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			claims, err := util.ParseToken(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims["username"] != tt.wantUsername {
+				t.Fatalf("username = %v, want %s", claims["username"], tt.wantUsername)
+			}
+			// state 只能使用一次
+			if _, err := s.Login(ctx, code, state, cookie, ClientInfo{}); err != util.ErrOAuthState {
+				t.Fatalf("replayed state: err = %v, want ErrOAuthState", err)
+			}
+		})
+	}
+
+	t.Run("expired state", func(t *testing.T) {
+		s := NewOIDCService(db, NewSessionService(db))
+		authURL, _, err := s.AuthURL(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, state := stub.authorize(t, authURL, map[string]interface{}{"sub": "1"})
+		db.Model(&model.OAuthLogin{}).Where("state_hash = ?", util.HashToken(state)).
+			Update("expires_at", time.Now().Add(-time.Second))
+		if _, err := s.Login(ctx, code, state, state, ClientInfo{}); err != util.ErrOAuthState {
+			t.Fatalf("err = %v, want ErrOAuthState", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// OutboxHandler 发件箱事件的订阅者。事件至少投递一次（失败或进程退出后会重新投递），
+// 订阅者需保证幂等；返回错误时该事件稍后对所有订阅者重新投递
+type OutboxHandler func(ctx context.Context, event *model.OutboxEvent) error
+
+// outboxSubscriber 已注册的订阅者
+type outboxSubscriber struct {
+	name    string
+	types   map[string]bool // 为空表示订阅全部事件
+	handler OutboxHandler
+}
+
+// OutboxRelay 把发件箱中的事件转发给进程内的订阅者
+type OutboxRelay struct {
+	db          *gorm.DB
+	subscribers []outboxSubscriber
+}
+
+func NewOutboxRelay(db *gorm.DB) *OutboxRelay {
+	return &OutboxRelay{db: db}
+}
+
+// Subscribe 注册订阅者（需在启动转发前调用），eventTypes 为空表示订阅全部事件
+func (r *OutboxRelay) Subscribe(name string, handler OutboxHandler, eventTypes ...string) {
+	types := make(map[string]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		types[t] = true
+	}
+	r.subscribers = append(r.subscribers, outboxSubscriber{name: name, types: types, handler: handler})
+}
+
+// RelayPending 转发到期的事件（定时任务调用），返回转发成功的事件数；
+// 失败次数达到上限的事件标记为失败，不再自动重试
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	cfg := config.Cfg.OutboxConfig
+	var events []model.OutboxEvent
+	if err := r.db.Where("published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		Order("id").Limit(cfg.BatchSize).Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	relayed := 0
+	for i := range events {
+		event := &events[i]
+		// 抢占事件：把下次转发时间推后，多实例部署时避免同时处理
+		result := r.db.Model(&model.OutboxEvent{}).
+			Where("id = ? AND published_at IS NULL AND next_attempt_at = ?", event.ID, event.NextAttemptAt).
+			Update("next_attempt_at", time.Now().Add(cfg.RetryMaxDelay))
+		if result.Error != nil {
+			return relayed, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		if err := r.dispatch(ctx, event); err != nil {
+			event.Attempts++
+			updates := map[string]interface{}{
+				"attempts":   event.Attempts,
+				"last_error": truncate(err.Error(), 500),
+			}
+			if event.Attempts >= cfg.MaxAttempts {
+				updates["failed_at"] = time.Now()
+			} else {
+				updates["next_attempt_at"] = time.Now().Add(retryDelay(cfg.RetryBaseDelay, cfg.RetryMaxDelay, event.Attempts))
+			}
+			if err := r.db.Model(event).Updates(updates).Error; err != nil {
+				return relayed, err
+			}
+			continue
+		}
+		if err := r.db.Model(event).Update("published_at", time.Now()).Error; err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+	return relayed, nil
+}
+
+// CleanupPublished 删除超过保留时间的已转发事件，返回删除的条数
+func (r *OutboxRelay) CleanupPublished() (int64, error) {
+	result := r.db.Where("published_at < ?", time.Now().Add(-config.Cfg.OutboxConfig.Retention)).Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// ListFailed 分页查询转发失败（已放弃自动重试）的事件，最近失败的在前
+func (r *OutboxRelay) ListFailed(page, pageSize int) (*util.PageResult, error) {
+	query := r.db.Model(&model.OutboxEvent{}).Where("failed_at IS NOT NULL")
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var events []model.OutboxEvent
+	if err := query.Order("failed_at DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return util.CalcPageResult(events, total, page, pageSize), nil
+}
+
+// Replay 重新投递转发失败的事件（清零失败次数，立即转发），记录审计日志
+func (r *OutboxRelay) Replay(op Operator, id uint) error {
+	return transaction(r.db, func(tx *gorm.DB) error {
+		var event model.OutboxEvent
+		if err := tx.Where("id = ? AND failed_at IS NOT NULL", id).First(&event).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return util.ErrOutboxNotFailed
+			}
+			return err
+		}
+		if err := tx.Model(&event).Updates(map[string]interface{}{
+			"failed_at":       nil,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, op, "outbox.replay", "outbox_event", event.ID, map[string]interface{}{
+			"type":      event.Type,
+			"attempts":  event.Attempts,
+			"lastError": event.LastError,
+		})
+	})
+}
+
+// dispatch 把事件交给订阅了该事件的所有订阅者，汇总失败的订阅者
+func (r *OutboxRelay) dispatch(ctx context.Context, event *model.OutboxEvent) error {
+	var failed []string
+	for _, sub := range r.subscribers {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+		if err := sub.handler(ctx, event); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", sub.name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// PostEventData 文章事件（post.created/post.updated）的数据，也是 Webhook 请求体中的 data
+type PostEventData struct {
+	PostID     uint      `json:"postId"`
+	UserID     uint      `json:"userId"` // 作者ID
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	CategoryID *uint     `json:"categoryId"`
+	SeriesID   *uint     `json:"seriesId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// CommentEventData 评论事件（comment.created）的数据，也是 Webhook 请求体中的 data
+type CommentEventData struct {
+	CommentID uint      `json:"commentId"`
+	PostID    uint      `json:"postId"`
+	UserID    uint      `json:"userId"`   // 评论者ID
+	ParentID  *uint     `json:"parentId"` // 回复的评论ID（直接评论文章时为 null）
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// postEventData 由文章生成事件数据（只包含文章自身字段，不含未加载的关联）
+func postEventData(post *model.Post) PostEventData {
+	return PostEventData{
+		PostID:     post.ID,
+		UserID:     post.UserID,
+		Title:      post.Title,
+		Content:    post.Content,
+		CategoryID: post.CategoryID,
+		SeriesID:   post.SeriesID,
+		CreatedAt:  post.CreatedAt,
+		UpdatedAt:  post.UpdatedAt,
+	}
+}
+
+// commentEventData 由评论生成事件数据
+func commentEventData(comment *model.Comment) CommentEventData {
+	return CommentEventData{
+		CommentID: comment.ID,
+		PostID:    comment.PostID,
+		UserID:    comment.UserID,
+		ParentID:  comment.ParentID,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+	}
+}
+
+// recordEvent 写入发件箱，需在业务数据变更的同一事务中调用
+func recordEvent(tx *gorm.DB, eventType string, aggregateID uint, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.OutboxEvent{
+		Type:          eventType,
+		AggregateID:   aggregateID,
+		Payload:       string(payload),
+		NextAttemptAt: time.Now(),
+	}).Error
+}
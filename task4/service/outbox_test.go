@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gotask/task4/model"
+)
+
+// relayUntilSettled 反复转发事件（每次把下次转发时间调到过去），直到事件转发成功或被标记为失败
+func relayUntilSettled(t *testing.T, r *OutboxRelay, id uint) model.OutboxEvent {
+	t.Helper()
+	var event model.OutboxEvent
+	for i := 0; i < 10; i++ {
+		if err := r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).
+			Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.RelayPending(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.db.First(&event, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if event.PublishedAt != nil || event.FailedAt != nil {
+			return event
+		}
+	}
+	t.Fatalf("event %d still pending: %+v", id, event)
+	return event
+}
+
+func TestRelayPendingGivesUpAfterMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int // 订阅者前几次处理失败
+		wantPublished bool
+		wantAttempts  int
+	}{
+		{"succeeds first time", 0, true, 0},
+		{"succeeds after retries", 2, true, 2},
+		{"poison event", 100, false, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			calls := 0
+			relay := NewOutboxRelay(db)
+			relay.Subscribe("test", func(ctx context.Context, event *model.OutboxEvent) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("boom")
+				}
+				return nil
+			})
+			if err := recordEvent(db, model.EventPostCreated, 1, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			event := relayUntilSettled(t, relay, 1)
+			if (event.PublishedAt != nil) != tt.wantPublished || (event.FailedAt != nil) == tt.wantPublished {
+				t.Fatalf("event = %+v, wantPublished %v", event, tt.wantPublished)
+			}
+			if event.Attempts != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", event.Attempts, tt.wantAttempts)
+			}
+
+			// 失败的事件不再自动重试
+			before := calls
+			relayUntilSettled(t, relay, 1)
+			if calls != before {
+				t.Fatalf("settled event dispatched again")
+			}
+		})
+	}
+}
+
+func TestReplayFailedOutboxEvent(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "admin")
+	healthy := false
+	relay := NewOutboxRelay(db)
+	relay.Subscribe("test", func(ctx context.Context, event *model.OutboxEvent) error {
+		if !healthy {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+	if err := recordEvent(db, model.EventPostCreated, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordEvent(db, model.EventPostCreated, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	relayUntilSettled(t, relay, 1)
+
+	page, err := relay.ListFailed(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := page.List.([]model.OutboxEvent); len(failed) != 1 || failed[0].ID != 1 || failed[0].LastError == "" {
+		t.Fatalf("failed events = %+v, want event 1", failed)
+	}
+
+	op := Operator{UserID: admin.ID, IP: "127.0.0.1"}
+	tests := []struct {
+		name    string
+		id      uint
+		wantErr bool
+	}{
+		{"failed event", 1, false},
+		{"already requeued", 1, true},
+		{"pending event", 2, true},
+		{"missing event", 99, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := relay.Replay(op, tt.id); (err != nil) != tt.wantErr {
+				t.Fatalf("Replay(%d) = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+
+	var audits int64
+	db.Model(&model.AuditLog{}).Where("action = ? AND target_id = ?", "outbox.replay", 1).Count(&audits)
+	if audits != 1 {
+		t.Fatalf("audit logs = %d, want 1", audits)
+	}
+
+	healthy = true
+	if event := relayUntilSettled(t, relay, 1); event.PublishedAt == nil {
+		t.Fatalf("replayed event not published: %+v", event)
+	}
+}
+
+func TestEventPayloads(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	reader := createTestUser(t, db, "reader")
+	posts := NewPostService(db)
+	post, err := posts.Create("first", "hello", author.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := posts.Update(post.ID, "second", "", author.ID, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	comment, err := NewCommentService(db).Create("nice post", post.ID, reader.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		eventType string
+		want      map[string]interface{}
+	}{
+		{model.EventPostCreated, map[string]interface{}{"postId": float64(post.ID), "userId": float64(author.ID), "title": "first"}},
+		{model.EventPostUpdated, map[string]interface{}{"postId": float64(post.ID), "userId": float64(author.ID), "title": "second", "content": "hello"}},
+		{model.EventCommentCreated, map[string]interface{}{"commentId": float64(comment.ID), "postId": float64(post.ID), "userId": float64(reader.ID), "parentId": nil}},
+	}
+	for _, tt := range tests {
+		var event model.OutboxEvent
+		if err := db.Where("type = ?", tt.eventType).First(&event).Error; err != nil {
+			t.Fatalf("%s: %v", tt.eventType, err)
+		}
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+			t.Fatal(err)
+		}
+		for key, want := range tt.want {
+			if got, ok := data[key]; !ok || got != want {
+				t.Fatalf("%s: %s = %v, want %v", tt.eventType, key, got, want)
+			}
+		}
+		// 未加载的关联不能以零值对象出现在载荷中
+		for _, key := range []string{"author", "commenter", "post", "ID", "DeletedAt"} {
+			if _, ok := data[key]; ok {
+				t.Fatalf("%s: payload contains %q: %s", tt.eventType, key, event.Payload)
+			}
+		}
+	}
+}
@@ -1,35 +1,72 @@
 package service
 
 import (
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gotask/task4/model"
 	"gotask/task4/util"
 )
 
 // PostService 文章服务
 type PostService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	views *viewTracker
 }
 
 func NewPostService(db *gorm.DB) *PostService {
-	return &PostService{db: db}
+	return &PostService{db: db, views: newViewTracker()}
 }
 
-// Create 创建文章
-func (s *PostService) Create(title, content string, userID uint) (*model.Post, error) {
+// Create 创建文章（同时处理正文中的 @提及并关联附件），categoryID 为空表示未分类
+func (s *PostService) Create(title, content string, userID uint, categoryID *uint, tags []string, attachmentIDs []uint) (*model.Post, error) {
 	post := model.Post{
-		Title:   title,
-		Content: content,
-		UserID:  userID,
+		Title:      title,
+		Content:    content,
+		UserID:     userID,
+		CategoryID: categoryID,
 	}
-	if err := s.db.Create(&post).Error; err != nil {
+	err := transaction(s.db, func(tx *gorm.DB) error {
+		if err := checkCategory(tx, categoryID); err != nil {
+			return err
+		}
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		if err := setPostTags(tx, &post, tags); err != nil {
+			return err
+		}
+		if err := refreshHotScore(tx, post.ID); err != nil {
+			return err
+		}
+		if err := linkAttachments(tx, post.ID, userID, attachmentIDs); err != nil {
+			return err
+		}
+		if err := syncMentions(tx, model.MentionSourcePost, post.ID, post.ID, userID, content); err != nil {
+			return err
+		}
+		return recordEvent(tx, model.EventPostCreated, post.ID, postEventData(&post))
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &post, nil
 }
 
-// List 分页查询文章列表（按创建时间倒序）
-func (s *PostService) Page(page, pageSize int) (*util.PageResult, error) {
+// 文章列表排序方式
+const (
+	PostSortLatest   = "latest"    // 最新发布
+	PostSortHot      = "hot"       // 热度（互动数随时间衰减）
+	PostSortTopDay   = "top_day"   // 近一天互动最多
+	PostSortTopWeek  = "top_week"  // 近一周互动最多
+	PostSortTopMonth = "top_month" // 近一个月互动最多
+	PostSortComments = "comments"  // 评论最多
+)
+
+// List 分页查询文章列表，sort 为排序方式（默认按创建时间倒序）
+func (s *PostService) Page(page, pageSize int, sort string) (*util.PageResult, error) {
 	var (
 		posts []model.Post
 		total int64
@@ -38,15 +75,34 @@ func (s *PostService) Page(page, pageSize int) (*util.PageResult, error) {
 	// 计算偏移量
 	offset := (page - 1) * pageSize
 
+	db := s.db.Model(&model.Post{}).Where("posts.hidden = ?", false)
+	switch sort {
+	case PostSortHot:
+		db = db.Order("posts.hot_score DESC, posts.id DESC")
+	case PostSortComments:
+		db = db.Order("posts.comment_count DESC, posts.id DESC")
+	case PostSortTopDay, PostSortTopWeek, PostSortTopMonth:
+		// 按时间窗口内每日计数的加权和排序，只包含窗口内有互动的文章
+		days := map[string]int{PostSortTopDay: 1, PostSortTopWeek: 7, PostSortTopMonth: 30}[sort]
+		since := time.Now().AddDate(0, 0, -days)
+		stats := s.db.Model(&model.PostDailyStat{}).
+			Select("post_id, SUM(views*? + likes*? + comments*?) AS score", viewWeight, likeWeight, commentWeight).
+			Where("day > ?", since).Group("post_id")
+		db = db.Joins("JOIN (?) AS stats ON stats.post_id = posts.id", stats).
+			Where("stats.score > 0").Order("stats.score DESC, posts.id DESC")
+	default:
+		db = db.Order("posts.created_at DESC")
+	}
+
 	// 1. 查询总条数
-	if err := s.db.Model(&model.Post{}).Count(&total).Error; err != nil {
+	if err := db.Count(&total).Error; err != nil {
 		return nil, err
 	}
 
 	// 2. 分页查询文章（预加载作者信息，只返回用户名）
-	if err := s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
-		return db.Select("ID", "Username")
-	}).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
+	if err := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Preload("Category").Preload("Tags").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
 		return nil, err
 	}
 
@@ -57,26 +113,30 @@ func (s *PostService) Page(page, pageSize int) (*util.PageResult, error) {
 func (s *PostService) ListByUserId(userID uint) ([]model.Post, error) {
 	var posts []model.Post
 	if err := s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
-		return db.Select("ID", "Username") // 只返回作者ID和用户名（保护隐私）
-	}).Where("user_id = ?", userID).Find(&posts).Error; err != nil {
+		return db.Select("ID", "Username", "AvatarKey") // 只返回作者ID、用户名和头像（保护隐私）
+	}).Where("user_id = ? AND hidden = ?", userID, false).Find(&posts).Error; err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
-// GetByID 获取文章（包含作者信息）
+// GetByID 获取文章（包含作者信息，属于系列时包含上一篇/下一篇导航）
 func (s *PostService) GetByID(id uint) (*model.Post, error) {
 	var post model.Post
 	if err := s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
-		return db.Select("ID", "Username") // 只返回作者ID和用户名（保护隐私）
-	}).Where("id = ?", id).First(&post).Error; err != nil {
+		return db.Select("ID", "Username", "AvatarKey") // 只返回作者ID、用户名和头像（保护隐私）
+	}).Preload("Category").Preload("Tags").Preload("Attachments").Where("id = ? AND hidden = ?", id, false).First(&post).Error; err != nil {
 		return nil, util.ErrPostNotExist
 	}
+	if err := seriesNavigation(s.db, &post); err != nil {
+		return nil, err
+	}
 	return &post, nil
 }
 
-// Update 修改文章（仅作者可修改）
-func (s *PostService) Update(id uint, title, content string, owerUserId uint) error {
+// Update 修改文章（仅作者可修改），title、content 为空时不修改，categoryID 为 nil 时不修改分类（为 0 时取消分类），
+// tags、attachmentIDs 为 nil 时不修改
+func (s *PostService) Update(id uint, title, content string, owerUserId uint, categoryID *uint, tags []string, attachmentIDs []uint) error {
 	// 检查文章是否存在且属于当前用户
 	var post model.Post
 	if err := s.db.Where("id = ? AND user_id = ?", id, owerUserId).First(&post).Error; err != nil {
@@ -86,9 +146,108 @@ func (s *PostService) Update(id uint, title, content string, owerUserId uint) er
 		return err
 	}
 
-	// 更新文章
-	return s.db.Model(&post).Updates(map[string]interface{}{
-		"title":   title,
-		"content": content,
-	}).Error
-}
\ No newline at end of file
+	// 更新文章，并同步正文中的 @提及（只通知新增的提及）
+	return transaction(s.db, func(tx *gorm.DB) error {
+		updates := make(map[string]interface{})
+		if title != "" {
+			updates["title"] = title
+		}
+		if content != "" {
+			updates["content"] = content
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&post).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if categoryID != nil {
+			if *categoryID == 0 {
+				categoryID = nil
+			}
+			if err := checkCategory(tx, categoryID); err != nil {
+				return err
+			}
+			if err := tx.Model(&post).Update("category_id", categoryID).Error; err != nil {
+				return err
+			}
+		}
+		if tags != nil {
+			if err := setPostTags(tx, &post, tags); err != nil {
+				return err
+			}
+		}
+		if attachmentIDs != nil {
+			if err := linkAttachments(tx, post.ID, owerUserId, attachmentIDs); err != nil {
+				return err
+			}
+		}
+		if content != "" {
+			if err := syncMentions(tx, model.MentionSourcePost, post.ID, post.ID, owerUserId, content); err != nil {
+				return err
+			}
+		}
+		return recordEvent(tx, model.EventPostUpdated, post.ID, postEventData(&post))
+	})
+}
+
+// CommentSettings 文章评论设置（字段为 nil 表示不修改）
+type CommentSettings struct {
+	Status          *string // 评论状态：open/closed/followers
+	AutoCloseDays   *int    // 发布N天后自动关闭评论（0表示不自动关闭）
+	RequireApproval *bool   // 评论需审核后公开
+}
+
+// UpdateCommentSettings 修改文章评论设置（仅作者可修改）
+func (s *PostService) UpdateCommentSettings(id, owerUserId uint, settings CommentSettings) error {
+	var post model.Post
+	if err := s.db.Where("id = ? AND user_id = ?", id, owerUserId).First(&post).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return util.ErrNoPermission
+		}
+		return err
+	}
+
+	updates := make(map[string]interface{})
+	if settings.Status != nil {
+		updates["comment_status"] = *settings.Status
+	}
+	if settings.AutoCloseDays != nil {
+		updates["comment_auto_close_days"] = *settings.AutoCloseDays
+	}
+	if settings.RequireApproval != nil {
+		updates["require_comment_approval"] = *settings.RequireApproval
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.db.Model(&post).Updates(updates).Error
+}
+
+// setPostTags 设置文章的标签（统一为小写并去重，不存在的标签自动创建）
+func setPostTags(tx *gorm.DB, post *model.Post, names []string) error {
+	seen := make(map[string]bool, len(names))
+	var normalized []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+
+	var tags []model.Tag
+	if len(normalized) > 0 {
+		newTags := make([]model.Tag, len(normalized))
+		for i, name := range normalized {
+			newTags[i] = model.Tag{Name: name}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("name IN ?", normalized).Find(&tags).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(post).Association("Tags").Replace(tags)
+}
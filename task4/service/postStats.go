@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gotask/task4/config"
+	"gotask/task4/model"
+)
+
+// 文章计数项（对应 post_daily_stats 的列）
+const (
+	statViews    = "views"
+	statLikes    = "likes"
+	statComments = "comments"
+)
+
+// 热度和排行榜中各项互动的权重
+const (
+	viewWeight    = 1
+	likeWeight    = 5
+	commentWeight = 10
+)
+
+// hotScoreExpr 热度：log10(加权互动数) + 发布时间/45000 秒，
+// 即发布时间每晚 12.5 小时，需要 10 倍的互动才能排在同一位置（时间衰减）
+const hotScoreExpr = "LOG10(GREATEST(view_count*? + like_count*? + comment_count*?, 1)) + (UNIX_TIMESTAMP(created_at) - 1577836800) / 45000"
+
+// RecordView 记录一次文章浏览，同一访客在统计窗口内重复浏览同一文章不重复计数
+func (s *PostService) RecordView(postID uint, viewer string) error {
+	if !s.views.first(fmt.Sprintf("%d:%s", postID, viewer), time.Now()) {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Post{}).Where("id = ?", postID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+			return err
+		}
+		return recordPostActivity(tx, postID, statViews, 1)
+	})
+}
+
+// viewTracker 记录统计窗口内已计数的浏览（文章ID + 访客），过期记录在下次访问时批量清理
+type viewTracker struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	sweptAt time.Time
+}
+
+func newViewTracker() *viewTracker {
+	return &viewTracker{seen: make(map[string]time.Time)}
+}
+
+// first 判断本次浏览是否是窗口内的首次浏览（是则记录）
+func (v *viewTracker) first(key string, now time.Time) bool {
+	window := config.Cfg.StatsConfig.ViewWindow
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.sweptAt) > window {
+		for k, at := range v.seen {
+			if now.Sub(at) > window {
+				delete(v.seen, k)
+			}
+		}
+		v.sweptAt = now
+	}
+	if at, ok := v.seen[key]; ok && now.Sub(at) <= window {
+		return false
+	}
+	v.seen[key] = now
+	return true
+}
+
+// recordPostActivity 累加文章当日的计数并更新热度（文章的总数由调用方更新）
+func recordPostActivity(tx *gorm.DB, postID uint, stat string, delta int) error {
+	row := model.PostDailyStat{PostID: postID, Day: statDay(time.Now())}
+	switch stat {
+	case statViews:
+		row.Views = delta
+	case statLikes:
+		row.Likes = delta
+	case statComments:
+		row.Comments = delta
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{stat: gorm.Expr(stat+" + ?", delta)}),
+	}).Create(&row).Error; err != nil {
+		return err
+	}
+	return refreshHotScore(tx, postID)
+}
+
+// recordFollowerActivity 累加用户当日的净增粉丝数
+func recordFollowerActivity(tx *gorm.DB, userID uint, delta int) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"followers": gorm.Expr("followers + ?", delta)}),
+	}).Create(&model.UserDailyStat{UserID: userID, Day: statDay(time.Now()), Followers: delta}).Error
+}
+
+// statDay 计数所属的日期（本地时间当天零点）
+func statDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// refreshHotScore 根据文章当前的计数重新计算热度
+func refreshHotScore(tx *gorm.DB, postID uint) error {
+	return tx.Model(&model.Post{}).Where("id = ?", postID).
+		UpdateColumn("hot_score", gorm.Expr(hotScoreExpr, viewWeight, likeWeight, commentWeight)).Error
+}
+
+// BackfillPostCounters 按已公开且未被隐藏的评论回填文章评论数，并重新计算所有文章的热度
+// （升级到带计数列的版本时执行一次，之后计数随互动增量更新）
+func BackfillPostCounters(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		approved := tx.Model(&model.Comment{}).Select("COUNT(*)").
+			Where("comments.post_id = posts.id AND comments.status = ? AND comments.hidden = ?", model.CommentApproved, false)
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Post{}).
+			UpdateColumn("comment_count", approved).Error; err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Post{}).
+			UpdateColumn("hot_score", gorm.Expr(hotScoreExpr, viewWeight, likeWeight, commentWeight)).Error
+	})
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gotask/task4/model"
+)
+
+func TestBackfillPostCounters(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	busy := createTestPost(t, db, author.ID, "busy")
+	quiet := createTestPost(t, db, author.ID, "quiet")
+
+	comments := []model.Comment{
+		{PostID: busy.ID, Status: model.CommentApproved},
+		{PostID: busy.ID, Status: model.CommentApproved},
+		{PostID: busy.ID, Status: model.CommentApproved, Hidden: true},
+		{PostID: busy.ID, Status: model.CommentPending},
+		{PostID: busy.ID, Status: model.CommentRejected},
+		{PostID: quiet.ID, Status: model.CommentPending},
+	}
+	for i := range comments {
+		comments[i].UserID = author.ID
+		comments[i].Content = "comment"
+		if err := db.Create(&comments[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 模拟升级前的数据：计数列为默认值
+	if err := db.Model(&model.Post{}).Where("id IN ?", []uint{busy.ID, quiet.ID}).
+		UpdateColumns(map[string]interface{}{"comment_count": 0, "hot_score": 0}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BackfillPostCounters(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		post *model.Post
+		want int
+	}{
+		{busy, 2},
+		{quiet, 0},
+	}
+	for _, tt := range tests {
+		var got model.Post
+		if err := db.First(&got, tt.post.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.CommentCount != tt.want {
+			t.Errorf("%s: comment_count = %d, want %d", got.Title, got.CommentCount, tt.want)
+		}
+		if got.HotScore <= 0 {
+			t.Errorf("%s: hot_score = %v, want recomputed", got.Title, got.HotScore)
+		}
+	}
+	var busyPost, quietPost model.Post
+	db.First(&busyPost, busy.ID)
+	db.First(&quietPost, quiet.ID)
+	if busyPost.HotScore <= quietPost.HotScore {
+		t.Errorf("hot_score busy %v <= quiet %v", busyPost.HotScore, quietPost.HotScore)
+	}
+}
+
+func TestViewTrackerWindow(t *testing.T) {
+	testConfig()
+	v := newViewTracker()
+	start := time.Now()
+	tests := []struct {
+		name string
+		key  string
+		at   time.Duration
+		want bool
+	}{
+		{"first view", "1:10.0.0.1", 0, true},
+		{"reload", "1:10.0.0.1", time.Minute, false},
+		{"other visitor", "1:10.0.0.2", time.Minute, true},
+		{"other post", "2:10.0.0.1", time.Minute, true},
+		{"within window", "1:10.0.0.1", 30 * time.Minute, false},
+		{"after window", "1:10.0.0.1", 31 * time.Minute, true},
+		{"after window again", "1:10.0.0.1", 32 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		if got := v.first(tt.key, start.Add(tt.at)); got != tt.want {
+			t.Errorf("%s: first(%q) = %v, want %v", tt.name, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRecordViewDedupesVisitor(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	post := createTestPost(t, db, author.ID, "hello")
+	s := NewPostService(db)
+
+	for _, viewer := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2", "10.0.0.1"} {
+		if err := s.RecordView(post.ID, viewer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got model.Post
+	if err := db.First(&got, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.ViewCount != 2 {
+		t.Fatalf("view_count = %d, want 2", got.ViewCount)
+	}
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// 相关度各项的权重及最低相关度
+const (
+	relatedTagWeight       = 0.4
+	relatedTextWeight      = 0.4
+	relatedCommenterWeight = 0.2
+	relatedMinScore        = 0.05
+)
+
+// RelatedService 相关文章推荐：综合相同标签、正文 TF-IDF 相似度和共同评论者计算，结果按文章缓存
+type RelatedService struct {
+	db *gorm.DB
+}
+
+func NewRelatedService(db *gorm.DB) *RelatedService {
+	return &RelatedService{db: db}
+}
+
+// List 查询文章的相关文章，按相关度倒序。只读取缓存（失效的缓存在后台重新计算前仍返回旧结果）
+func (s *RelatedService) List(ctx context.Context, postID uint) ([]model.Post, error) {
+	db := s.db.WithContext(ctx)
+	var post model.Post
+	if err := db.Select("id").Where("id = ? AND hidden = ?", postID, false).First(&post).Error; err != nil {
+		return nil, util.ErrPostNotExist
+	}
+
+	var related []model.RelatedPost
+	if err := db.Where("post_id = ?", postID).Order("score DESC").Find(&related).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(related))
+	for i, r := range related {
+		ids[i] = r.RelatedID
+	}
+	var posts []model.Post
+	if err := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Preload("Tags").Where("id IN ? AND hidden = ?", ids, false).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	// 按相关度排序（IN 查询不保证顺序）
+	rank := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		rank[id] = i
+	}
+	sort.Slice(posts, func(i, j int) bool { return rank[posts[i].ID] < rank[posts[j].ID] })
+	return posts, nil
+}
+
+// HandlePostChanged 文章发布或修改后使相关文章缓存失效（发件箱订阅者）：
+// 该文章本身、推荐了该文章的文章，以及与其有相同标签的文章由后台任务重新计算
+func (s *RelatedService) HandlePostChanged(ctx context.Context, event *model.OutboxEvent) error {
+	db := s.db.WithContext(ctx)
+	postID := event.AggregateID
+	referrers := db.Model(&model.RelatedPost{}).Select("post_id").Where("related_id = ?", postID)
+	tagIDs := db.Table("post_tags").Select("tag_id").Where("post_id = ?", postID)
+	sameTag := db.Table("post_tags").Select("post_id").Where("tag_id IN (?)", tagIDs)
+	return db.Unscoped().Model(&model.Post{}).
+		Where("id = ? OR id IN (?) OR id IN (?)", postID, referrers, sameTag).
+		UpdateColumn("related_computed_at", nil).Error
+}
+
+// RefreshStale 重新计算缓存失效或过期的文章的相关文章（由后台任务单线程定时执行），返回计算的文章数
+func (s *RelatedService) RefreshStale(ctx context.Context) (int, error) {
+	db := s.db.WithContext(ctx)
+	var posts []model.Post
+	if err := db.Select("id", "user_id", "title", "content").
+		Where("hidden = ? AND (related_computed_at IS NULL OR related_computed_at < ?)",
+			false, time.Now().Add(-config.Cfg.RelatedConfig.TTL)).
+		Order("related_computed_at, id").Limit(config.Cfg.RelatedConfig.BatchSize).
+		Find(&posts).Error; err != nil {
+		return 0, err
+	}
+	for i := range posts {
+		if err := s.refresh(db, &posts[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(posts), nil
+}
+
+// refresh 重新计算文章的相关文章并写入缓存：按 (文章, 相关文章) 唯一索引更新相关度，再删除不再相关的记录
+func (s *RelatedService) refresh(db *gorm.DB, post *model.Post) error {
+	related, err := s.compute(db, post)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("post_id = ?", post.ID)
+		if len(related) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "post_id"}, {Name: "related_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"score"}),
+			}).Create(&related).Error; err != nil {
+				return err
+			}
+			ids := make([]uint, len(related))
+			for i, r := range related {
+				ids[i] = r.RelatedID
+			}
+			stale = stale.Where("related_id NOT IN ?", ids)
+		}
+		if err := stale.Delete(&model.RelatedPost{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(post).UpdateColumn("related_computed_at", time.Now()).Error
+	})
+}
+
+// compute 计算相关文章：候选为有相同标签的文章、共同评论者评论过的文章和最近发布的文章
+func (s *RelatedService) compute(db *gorm.DB, post *model.Post) ([]model.RelatedPost, error) {
+	cfg := config.Cfg.RelatedConfig
+
+	// 当前文章的标签和评论者（不含作者本人）
+	var tagIDs []uint
+	if err := db.Table("post_tags").Where("post_id = ?", post.ID).Pluck("tag_id", &tagIDs).Error; err != nil {
+		return nil, err
+	}
+	var commenters []uint
+	if err := db.Model(&model.Comment{}).Distinct("user_id").
+		Where("post_id = ? AND status = ? AND user_id <> ?", post.ID, model.CommentApproved, post.UserID).
+		Pluck("user_id", &commenters).Error; err != nil {
+		return nil, err
+	}
+
+	// 候选文章
+	candidateIDs := make(map[uint]bool)
+	var ids []uint
+	if len(tagIDs) > 0 {
+		if err := db.Table("post_tags").Distinct("post_id").Where("tag_id IN ?", tagIDs).
+			Limit(cfg.CorpusSize).Pluck("post_id", &ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			candidateIDs[id] = true
+		}
+	}
+	if len(commenters) > 0 {
+		ids = nil
+		if err := db.Model(&model.Comment{}).Distinct("post_id").
+			Where("user_id IN ? AND status = ?", commenters, model.CommentApproved).
+			Limit(cfg.CorpusSize).Pluck("post_id", &ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			candidateIDs[id] = true
+		}
+	}
+	ids = nil
+	if err := db.Model(&model.Post{}).Where("hidden = ?", false).Order("created_at DESC").
+		Limit(cfg.CorpusSize).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		candidateIDs[id] = true
+	}
+	delete(candidateIDs, post.ID)
+	ids = ids[:0]
+	for id := range candidateIDs {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var candidates []model.Post
+	if err := db.Select("id", "title", "content").Where("id IN ? AND hidden = ?", ids, false).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	// 候选文章的标签
+	var postTags []struct {
+		PostID uint
+		TagID  uint
+	}
+	if err := db.Table("post_tags").Select("post_id", "tag_id").Where("post_id IN ?", ids).Scan(&postTags).Error; err != nil {
+		return nil, err
+	}
+	candidateTags := make(map[uint][]uint)
+	for _, pt := range postTags {
+		candidateTags[pt.PostID] = append(candidateTags[pt.PostID], pt.TagID)
+	}
+
+	// 候选文章的评论者数量和共同评论者数量
+	type commenterCount struct {
+		PostID uint
+		Count  int
+	}
+	var totals, shared []commenterCount
+	if len(commenters) > 0 {
+		if err := db.Model(&model.Comment{}).Select("post_id, COUNT(DISTINCT user_id) AS count").
+			Where("post_id IN ? AND status = ?", ids, model.CommentApproved).
+			Group("post_id").Scan(&totals).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Model(&model.Comment{}).Select("post_id, COUNT(DISTINCT user_id) AS count").
+			Where("post_id IN ? AND status = ? AND user_id IN ?", ids, model.CommentApproved, commenters).
+			Group("post_id").Scan(&shared).Error; err != nil {
+			return nil, err
+		}
+	}
+	commenterTotal := make(map[uint]int, len(totals))
+	for _, c := range totals {
+		commenterTotal[c.PostID] = c.Count
+	}
+
+	// 正文 TF-IDF 向量（语料为当前文章和候选文章）
+	docs := make([][]string, len(candidates)+1)
+	docs[0] = postTokens(post)
+	for i := range candidates {
+		docs[i+1] = postTokens(&candidates[i])
+	}
+	vectors := tfidfVectors(docs)
+
+	scores := make(map[uint]float64, len(candidates))
+	for i, candidate := range candidates {
+		scores[candidate.ID] = relatedTagWeight*jaccard(tagIDs, candidateTags[candidate.ID]) +
+			relatedTextWeight*cosine(vectors[0], vectors[i+1])
+	}
+	for _, c := range shared {
+		if total := commenterTotal[c.PostID]; total > 0 {
+			scores[c.PostID] += relatedCommenterWeight * float64(c.Count) / math.Sqrt(float64(len(commenters)*total))
+		}
+	}
+
+	var related []model.RelatedPost
+	for id, score := range scores {
+		if score >= relatedMinScore {
+			related = append(related, model.RelatedPost{PostID: post.ID, RelatedID: id, Score: score})
+		}
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].RelatedID > related[j].RelatedID
+	})
+	if len(related) > cfg.Limit {
+		related = related[:cfg.Limit]
+	}
+	return related, nil
+}
+
+// postTokens 文章的词项（标题权重加倍）
+func postTokens(post *model.Post) []string {
+	title := tokenize(post.Title)
+	return append(append(title, title...), tokenize(post.Content)...)
+}
+
+// tokenize 分词：英文和数字按单词切分（至少2个字符），中文按相邻两字切分
+func tokenize(text string) []string {
+	var (
+		tokens  []string
+		word    []rune
+		prevHan rune
+	)
+	flush := func() {
+		if len(word) >= 2 {
+			tokens = append(tokens, string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		if unicode.Is(unicode.Han, r) {
+			flush()
+			if prevHan != 0 {
+				tokens = append(tokens, string([]rune{prevHan, r}))
+			}
+			prevHan = r
+			continue
+		}
+		prevHan = 0
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// tfidfVectors 计算每篇文档的 TF-IDF 向量
+func tfidfVectors(docs [][]string) []map[string]float64 {
+	df := make(map[string]int)
+	counts := make([]map[string]int, len(docs))
+	for i, doc := range docs {
+		counts[i] = make(map[string]int)
+		for _, term := range doc {
+			counts[i][term]++
+		}
+		for term := range counts[i] {
+			df[term]++
+		}
+	}
+	n := float64(len(docs))
+	vectors := make([]map[string]float64, len(docs))
+	for i, doc := range docs {
+		vectors[i] = make(map[string]float64, len(counts[i]))
+		for term, count := range counts[i] {
+			idf := math.Log((n+1)/float64(df[term]+1)) + 1
+			vectors[i][term] = float64(count) / float64(len(doc)) * idf
+		}
+	}
+	return vectors
+}
+
+// cosine 余弦相似度
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, v := range a {
+		normA += v * v
+		dot += v * b[term]
+	}
+	for _, v := range b {
+		normB += v * v
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// jaccard 两个集合的 Jaccard 相似度
+func jaccard(a, b []uint) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[uint]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	shared := 0
+	for _, v := range b {
+		if set[v] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"gotask/task4/model"
+)
+
+func TestRelatedListServesCacheAndRefreshesInBackground(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	golang := createTestPost(t, db, author.ID, "golang channels")
+	other := createTestPost(t, db, author.ID, "golang goroutines")
+	createTestPost(t, db, author.ID, "gardening tomatoes")
+	s := NewRelatedService(db)
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		do      func() error
+		refresh int  // 后台任务本次计算的文章数
+		want    uint // 期望排在第一的相关文章（0 表示没有结果）
+	}{
+		{"not computed yet", func() error { return nil }, -1, 0},
+		{"background refresh", nil, 3, other.ID},
+		{"nothing stale", nil, 0, other.ID},
+		{"invalidated serves stale rows", func() error {
+			return s.HandlePostChanged(ctx, &model.OutboxEvent{AggregateID: golang.ID})
+		}, -1, other.ID},
+		{"recomputed without duplicates", nil, 2, other.ID}, // 该文章和推荐了它的文章
+	}
+	for _, step := range steps {
+		if step.do != nil {
+			if err := step.do(); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+		if step.refresh >= 0 {
+			n, err := s.RefreshStale(ctx)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if n != step.refresh {
+				t.Fatalf("%s: refreshed %d posts, want %d", step.name, n, step.refresh)
+			}
+		}
+		posts, err := s.List(ctx, golang.ID)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if step.want == 0 {
+			if len(posts) != 0 {
+				t.Fatalf("%s: got %d related posts, want none", step.name, len(posts))
+			}
+			continue
+		}
+		if len(posts) == 0 || posts[0].ID != step.want {
+			t.Fatalf("%s: related = %+v, want first %d", step.name, posts, step.want)
+		}
+	}
+
+	var rows, pairs int64
+	db.Model(&model.RelatedPost{}).Where("post_id = ?", golang.ID).Count(&rows)
+	db.Model(&model.RelatedPost{}).Where("post_id = ?", golang.ID).Distinct("related_id").Count(&pairs)
+	if rows != pairs {
+		t.Fatalf("related_posts has %d rows for %d pairs", rows, pairs)
+	}
+}
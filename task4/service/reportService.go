@@ -0,0 +1,246 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// systemOperator 系统自动操作（如举报达到阈值自动隐藏）在审计日志中的操作人
+var systemOperator = Operator{UserID: 0, IP: ""}
+
+// ReportService 内容举报服务
+type ReportService struct {
+	db *gorm.DB
+}
+
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// ReportedItem 按内容聚合的举报
+type ReportedItem struct {
+	TargetType     string         `json:"targetType"`
+	TargetID       uint           `json:"targetId"`
+	ReportCount    int64          `json:"reportCount"`
+	LastReportedAt time.Time      `json:"lastReportedAt"`
+	Reasons        map[string]int `json:"reasons" gorm:"-"` // 各举报原因的次数
+	Hidden         bool           `json:"hidden" gorm:"-"`  // 内容当前是否已隐藏
+}
+
+// Create 举报内容，同一内容的待处理举报达到阈值时自动隐藏
+func (s *ReportService) Create(reporterID uint, targetType string, targetID uint, reason, detail string) (*model.Report, error) {
+	report := model.Report{
+		ReporterID: reporterID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Detail:     detail,
+		Status:     model.ReportOpen,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := targetExists(tx, targetType, targetID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&model.Report{}).
+			Where("reporter_id = ? AND target_type = ? AND target_id = ?", reporterID, targetType, targetID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return util.ErrReportExist
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, model.ReportOpen).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count < int64(config.Cfg.ModerationConfig.ReportThreshold) {
+			return nil
+		}
+		hidden, err := setHidden(tx, targetType, targetID, true)
+		if err != nil || !hidden {
+			return err
+		}
+		return writeAudit(tx, systemOperator, "report.auto_hide", targetType, targetID, map[string]interface{}{"reports": count})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Queue 分页查询按内容聚合的举报（按举报次数倒序）
+func (s *ReportService) Queue(status string, page, pageSize int) (*util.PageResult, error) {
+	var (
+		items []ReportedItem
+		total int64
+	)
+	grouped := func() *gorm.DB {
+		return s.db.Model(&model.Report{}).
+			Select("target_type, target_id, COUNT(*) AS report_count, MAX(created_at) AS last_reported_at").
+			Where("status = ?", status).
+			Group("target_type, target_id")
+	}
+
+	if err := s.db.Table("(?) AS t", grouped()).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := grouped().Order("report_count DESC, last_reported_at DESC").
+		Offset(offset).Limit(pageSize).Scan(&items).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		var reasons []struct {
+			Reason string
+			Count  int
+		}
+		if err := s.db.Model(&model.Report{}).Select("reason, COUNT(*) AS count").
+			Where("target_type = ? AND target_id = ? AND status = ?", item.TargetType, item.TargetID, status).
+			Group("reason").Scan(&reasons).Error; err != nil {
+			return nil, err
+		}
+		item.Reasons = make(map[string]int, len(reasons))
+		for _, r := range reasons {
+			item.Reasons[r.Reason] = r.Count
+		}
+		hidden, err := isHidden(s.db, item.TargetType, item.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		item.Hidden = hidden
+	}
+	return util.CalcPageResult(items, total, page, pageSize), nil
+}
+
+// ListByTarget 查询某个内容的全部举报明细
+func (s *ReportService) ListByTarget(targetType string, targetID uint) ([]model.Report, error) {
+	var reports []model.Report
+	if err := s.db.Preload("Reporter", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("created_at DESC").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Resolve 确认违规：内容保持隐藏，关闭该内容的所有待处理举报
+func (s *ReportService) Resolve(op Operator, targetType string, targetID uint, note string) error {
+	return s.handle(op, targetType, targetID, model.ReportResolved, true, note)
+}
+
+// Dismiss 驳回举报：内容恢复展示，关闭该内容的所有待处理举报
+func (s *ReportService) Dismiss(op Operator, targetType string, targetID uint, note string) error {
+	return s.handle(op, targetType, targetID, model.ReportDismissed, false, note)
+}
+
+// handle 处理某个内容的全部待处理举报并写入审计日志
+func (s *ReportService) handle(op Operator, targetType string, targetID uint, status string, hide bool, note string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.Report{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, model.ReportOpen).
+			Updates(map[string]interface{}{
+				"status":     status,
+				"handled_by": op.UserID,
+				"handled_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return util.ErrReportNotExist
+		}
+		if _, err := setHidden(tx, targetType, targetID, hide); err != nil {
+			return err
+		}
+		return writeAudit(tx, op, "report."+status, targetType, targetID, map[string]interface{}{
+			"reports": result.RowsAffected,
+			"note":    note,
+		})
+	})
+}
+
+// targetExists 检查被举报的内容存在
+func targetExists(tx *gorm.DB, targetType string, targetID uint) error {
+	switch targetType {
+	case model.ReportTargetPost:
+		if err := tx.Select("id").First(&model.Post{}, targetID).Error; err != nil {
+			return util.ErrPostNotExist
+		}
+	case model.ReportTargetComment:
+		if err := tx.Select("id").First(&model.Comment{}, targetID).Error; err != nil {
+			return util.ErrCommentNotExist
+		}
+	default:
+		return util.ErrInvalidParam
+	}
+	return nil
+}
+
+// setHidden 修改内容的隐藏状态，返回状态是否发生变化
+func setHidden(tx *gorm.DB, targetType string, targetID uint, hidden bool) (bool, error) {
+	var m interface{}
+	switch targetType {
+	case model.ReportTargetPost:
+		m = &model.Post{}
+	case model.ReportTargetComment:
+		m = &model.Comment{}
+	default:
+		return false, util.ErrInvalidParam
+	}
+	result := tx.Model(m).Where("id = ? AND hidden <> ?", targetID, hidden).Update("hidden", hidden)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	if targetType == model.ReportTargetPost {
+		return true, invalidateSitemap(tx, "id = ?", targetID)
+	}
+
+	// 已公开的评论被隐藏或恢复时同步文章的评论数和当日评论计数
+	var comment model.Comment
+	if err := tx.Select("id", "post_id", "status").First(&comment, targetID).Error; err != nil {
+		return false, err
+	}
+	if comment.Status != model.CommentApproved {
+		return true, nil
+	}
+	expr, delta := "comment_count + 1", 1
+	if hidden {
+		expr, delta = "comment_count - 1", -1
+	}
+	if err := tx.Model(&model.Post{}).Where("id = ? AND (comment_count > 0 OR ?)", comment.PostID, !hidden).
+		UpdateColumn("comment_count", gorm.Expr(expr)).Error; err != nil {
+		return false, err
+	}
+	return true, recordPostActivity(tx, comment.PostID, statComments, delta)
+}
+
+// isHidden 查询内容当前是否隐藏（内容已被删除时视为隐藏）
+func isHidden(db *gorm.DB, targetType string, targetID uint) (bool, error) {
+	var hidden []bool
+	table := "posts"
+	if targetType == model.ReportTargetComment {
+		table = "comments"
+	}
+	if err := db.Table(table).Where("id = ? AND deleted_at IS NULL", targetID).Pluck("hidden", &hidden).Error; err != nil {
+		return false, err
+	}
+	if len(hidden) == 0 {
+		return true, nil
+	}
+	return hidden[0], nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// SeriesService 文章系列服务
+type SeriesService struct {
+	db *gorm.DB
+}
+
+func NewSeriesService(db *gorm.DB) *SeriesService {
+	return &SeriesService{db: db}
+}
+
+// Create 创建系列
+func (s *SeriesService) Create(userID uint, title, description string) (*model.Series, error) {
+	series := model.Series{UserID: userID, Title: title, Description: description}
+	if err := s.db.Create(&series).Error; err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// Update 修改系列标题和简介（仅作者）
+func (s *SeriesService) Update(id, userID uint, title, description string) error {
+	series, err := s.owned(s.db, id, userID)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(series).Updates(map[string]interface{}{
+		"title":       title,
+		"description": description,
+	}).Error
+}
+
+// Delete 删除系列（仅作者），系列中的文章保留
+func (s *SeriesService) Delete(id, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		series, err := s.owned(tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Post{}).Where("series_id = ?", id).
+			UpdateColumns(map[string]interface{}{"series_id": nil, "series_position": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(series).Error
+	})
+}
+
+// SetPosts 按顺序设置系列中的文章（仅作者，文章须为作者本人的；已在其他系列中的文章会移到该系列）；
+// 只修改系列字段，不更新文章的修改时间（sitemap 中的 lastmod 不变）
+func (s *SeriesService) SetPosts(id, userID uint, postIDs []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.owned(tx, id, userID); err != nil {
+			return err
+		}
+		if len(postIDs) > 0 {
+			var count int64
+			if err := tx.Model(&model.Post{}).Where("id IN ? AND user_id = ?", postIDs, userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count != int64(len(postIDs)) {
+				return util.NewErrno(util.ErrPostNotExist, "只能添加自己的文章")
+			}
+		}
+
+		unlink := tx.Unscoped().Model(&model.Post{}).Where("series_id = ?", id)
+		if len(postIDs) > 0 {
+			unlink = unlink.Where("id NOT IN ?", postIDs)
+		}
+		if err := unlink.UpdateColumns(map[string]interface{}{"series_id": nil, "series_position": 0}).Error; err != nil {
+			return err
+		}
+		for i, postID := range postIDs {
+			if err := tx.Model(&model.Post{}).Where("id = ?", postID).
+				UpdateColumns(map[string]interface{}{"series_id": id, "series_position": i + 1}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get 查询系列及其中的文章（按顺序，不含被隐藏的文章和正文）
+func (s *SeriesService) Get(id uint) (*model.Series, error) {
+	var series model.Series
+	if err := s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Username", "AvatarKey")
+	}).Preload("Posts", func(db *gorm.DB) *gorm.DB {
+		return db.Select("ID", "Title", "UserID", "SeriesID", "SeriesPosition", "CreatedAt", "UpdatedAt").
+			Where("hidden = ?", false).Order("series_position")
+	}).First(&series, id).Error; err != nil {
+		return nil, util.ErrSeriesNotExist
+	}
+	return &series, nil
+}
+
+// ListByUser 查询用户的系列（按创建时间倒序）
+func (s *SeriesService) ListByUser(userID uint) ([]model.Series, error) {
+	var series []model.Series
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// owned 查询属于该用户的系列
+func (s *SeriesService) owned(db *gorm.DB, id, userID uint) (*model.Series, error) {
+	var series model.Series
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, util.ErrSeriesNotExist
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// seriesNavigation 填充文章所在系列的导航信息（上一篇/下一篇，跳过被隐藏的文章）
+func seriesNavigation(db *gorm.DB, post *model.Post) error {
+	if post.SeriesID == nil {
+		return nil
+	}
+	var series model.Series
+	if err := db.Select("id", "title").First(&series, *post.SeriesID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	var refs []model.SeriesPostRef
+	if err := db.Model(&model.Post{}).Select("id", "title").
+		Where("series_id = ? AND hidden = ?", series.ID, false).
+		Order("series_position").Scan(&refs).Error; err != nil {
+		return err
+	}
+
+	nav := model.SeriesNav{ID: series.ID, Title: series.Title, Total: len(refs)}
+	for i, ref := range refs {
+		if ref.ID != post.ID {
+			continue
+		}
+		nav.Position = i + 1
+		if i > 0 {
+			nav.Prev = &refs[i-1]
+		}
+		if i+1 < len(refs) {
+			nav.Next = &refs[i+1]
+		}
+	}
+	post.Series = &nav
+	return nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// seriesPostIDs 系列中的文章 ID（按顺序）
+func seriesPostIDs(t *testing.T, s *SeriesService, id uint) []uint {
+	t.Helper()
+	series, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, 0, len(series.Posts))
+	for _, post := range series.Posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func TestSeriesSetPosts(t *testing.T) {
+	db := newTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	var posts []*model.Post
+	for _, title := range []string{"a", "b", "c"} {
+		posts = append(posts, createTestPost(t, db, alice.ID, title))
+	}
+	bobPost := createTestPost(t, db, bob.ID, "d")
+	s := NewSeriesService(db)
+	first, err := s.Create(alice.ID, "first", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Create(alice.ID, "second", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updatedAt := posts[0].UpdatedAt
+
+	steps := []struct {
+		name       string
+		seriesID   uint
+		userID     uint
+		postIDs    []uint
+		wantErr    *util.Errno
+		wantFirst  []uint
+		wantSecond []uint
+	}{
+		{"ordered", first.ID, alice.ID, []uint{posts[2].ID, posts[0].ID, posts[1].ID}, nil,
+			[]uint{posts[2].ID, posts[0].ID, posts[1].ID}, []uint{}},
+		{"reorder and drop", first.ID, alice.ID, []uint{posts[0].ID, posts[2].ID}, nil,
+			[]uint{posts[0].ID, posts[2].ID}, []uint{}},
+		{"move to another series", second.ID, alice.ID, []uint{posts[2].ID, posts[1].ID}, nil,
+			[]uint{posts[0].ID}, []uint{posts[2].ID, posts[1].ID}},
+		{"post of another user", first.ID, alice.ID, []uint{posts[0].ID, bobPost.ID}, util.ErrPostNotExist,
+			[]uint{posts[0].ID}, []uint{posts[2].ID, posts[1].ID}},
+		{"unknown post", first.ID, alice.ID, []uint{posts[0].ID, 999}, util.ErrPostNotExist,
+			[]uint{posts[0].ID}, []uint{posts[2].ID, posts[1].ID}},
+		{"series of another user", first.ID, bob.ID, []uint{bobPost.ID}, util.ErrSeriesNotExist,
+			[]uint{posts[0].ID}, []uint{posts[2].ID, posts[1].ID}},
+		{"clear", second.ID, alice.ID, nil, nil,
+			[]uint{posts[0].ID}, []uint{}},
+	}
+	for _, step := range steps {
+		err := s.SetPosts(step.seriesID, step.userID, step.postIDs)
+		var code int
+		if errno, ok := err.(*util.Errno); ok {
+			code = errno.Code
+		} else if err != nil {
+			t.Fatalf("%s: SetPosts() error = %v", step.name, err)
+		}
+		if (err == nil) != (step.wantErr == nil) || (step.wantErr != nil && code != step.wantErr.Code) {
+			t.Fatalf("%s: SetPosts() error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if got := seriesPostIDs(t, s, first.ID); fmt.Sprint(got) != fmt.Sprint(step.wantFirst) {
+			t.Fatalf("%s: first = %v, want %v", step.name, got, step.wantFirst)
+		}
+		if got := seriesPostIDs(t, s, second.ID); fmt.Sprint(got) != fmt.Sprint(step.wantSecond) {
+			t.Fatalf("%s: second = %v, want %v", step.name, got, step.wantSecond)
+		}
+	}
+
+	// 调整系列不修改文章的修改时间
+	var post model.Post
+	db.First(&post, posts[0].ID)
+	if !post.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("updated_at = %v, want %v", post.UpdatedAt, updatedAt)
+	}
+
+	// 删除系列后文章保留且不再属于任何系列
+	if err := s.Delete(first.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&post, posts[0].ID)
+	if post.SeriesID != nil || post.SeriesPosition != 0 {
+		t.Fatalf("after delete: series_id = %v, position = %d", post.SeriesID, post.SeriesPosition)
+	}
+}
+
+func TestSeriesNavigation(t *testing.T) {
+	db := newTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	var posts []*model.Post
+	for _, title := range []string{"a", "b", "c", "d"} {
+		posts = append(posts, createTestPost(t, db, alice.ID, title))
+	}
+	s := NewSeriesService(db)
+	series, err := s.Create(alice.ID, "guide", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPosts(series.ID, alice.ID, []uint{posts[0].ID, posts[1].ID, posts[2].ID}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(posts[1]).UpdateColumn("hidden", true)
+
+	tests := []struct {
+		name         string
+		post         *model.Post
+		wantNav      bool
+		wantPosition int
+		wantPrev     string
+		wantNext     string
+	}{
+		{"first", posts[0], true, 1, "", "c"},
+		{"last skips hidden", posts[2], true, 2, "a", ""},
+		{"not in series", posts[3], false, 0, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var post model.Post
+			db.First(&post, tt.post.ID)
+			if err := seriesNavigation(db, &post); err != nil {
+				t.Fatal(err)
+			}
+			if (post.Series != nil) != tt.wantNav {
+				t.Fatalf("series = %+v, want nav %v", post.Series, tt.wantNav)
+			}
+			if post.Series == nil {
+				return
+			}
+			nav := post.Series
+			if nav.ID != series.ID || nav.Title != "guide" || nav.Total != 2 || nav.Position != tt.wantPosition {
+				t.Fatalf("nav = %+v, want position %d of 2", nav, tt.wantPosition)
+			}
+			var prev, next string
+			if nav.Prev != nil {
+				prev = nav.Prev.Title
+			}
+			if nav.Next != nil {
+				next = nav.Next.Title
+			}
+			if prev != tt.wantPrev || next != tt.wantNext {
+				t.Fatalf("prev = %q, next = %q, want %q, %q", prev, next, tt.wantPrev, tt.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// 最近活跃时间的更新间隔（避免每个请求都写库）
+const sessionTouchInterval = time.Minute
+
+// ClientInfo 登录客户端信息（用于记录会话）
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// SessionService 登录会话服务
+type SessionService struct {
+	db *gorm.DB
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// Issue 为用户创建会话并签发对应的 token
+func (s *SessionService) Issue(user *model.User, client ClientInfo) (string, error) {
+	return s.issue(user, client, config.Cfg.JWTConfig.ExpireDuration, nil)
+}
+
+// IssueImpersonation 为管理员签发代登录目标用户的短期 token
+func (s *SessionService) IssueImpersonation(user *model.User, adminID uint, client ClientInfo) (string, error) {
+	return s.issue(user, client, config.Cfg.AdminConfig.ImpersonationTTL, &adminID)
+}
+
+// issue 创建会话记录并签发 token（会话与 token 的过期时间一致）
+func (s *SessionService) issue(user *model.User, client ClientInfo, ttl time.Duration, impersonatorID *uint) (string, error) {
+	now := time.Now()
+	device := client.Device
+	if device == "" {
+		device = deviceFromUserAgent(client.UserAgent)
+	}
+	session := model.Session{
+		UserID:         user.ID,
+		TokenID:        util.RandomHex(16),
+		Device:         truncate(device, 100),
+		IP:             client.IP,
+		UserAgent:      truncate(client.UserAgent, 255),
+		LastSeenAt:     now,
+		ExpiresAt:      now.Add(ttl),
+		ImpersonatorID: impersonatorID,
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return "", err
+	}
+	var imp uint
+	if impersonatorID != nil {
+		imp = *impersonatorID
+	}
+	return util.GenerateToken(user.ID, user.Username, session.TokenID, session.ExpiresAt, imp)
+}
+
+// Validate 校验 token 对应的会话仍然有效、用户未被封禁，并按间隔刷新最近活跃时间和 IP
+func (s *SessionService) Validate(tokenID string, userID uint, ip string) (*model.Session, error) {
+	var session model.Session
+	if err := s.db.Preload("User").Where("token_id = ? AND user_id = ?", tokenID, userID).First(&session).Error; err != nil {
+		return nil, util.ErrSessionNotExist
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, util.ErrNoPermission
+	}
+	if err := session.User.Blocked(); err != nil {
+		return nil, err
+	}
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval || session.IP != ip {
+		if err := s.db.Model(&session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip":           ip,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &session, nil
+}
+
+// ListByUser 查询用户当前有效的会话（按最近活跃时间倒序）
+func (s *SessionService) ListByUser(userID uint, currentTokenID string) ([]model.Session, error) {
+	var sessions []model.Session
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].TokenID == currentTokenID
+	}
+	return sessions, nil
+}
+
+// Revoke 吊销用户的某个会话（仅本人可操作），对应 token 立即失效
+func (s *SessionService) Revoke(id, userID uint) error {
+	result := s.db.Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return util.ErrSessionNotExist
+	}
+	return nil
+}
+
+// RevokeByTokenID 吊销 token 对应的会话（退出登录）
+func (s *SessionService) RevokeByTokenID(tokenID string) error {
+	return s.db.Model(&model.Session{}).
+		Where("token_id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllByUser 吊销用户的全部会话（注销账号、封禁等场景）
+func (s *SessionService) RevokeAllByUser(userID uint) error {
+	return s.db.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// deviceFromUserAgent 根据 User-Agent 粗略识别设备名称
+func deviceFromUserAgent(ua string) string {
+	var platform, browser string
+	switch {
+	case strings.Contains(ua, "iPhone"):
+		platform = "iPhone"
+	case strings.Contains(ua, "iPad"):
+		platform = "iPad"
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+	switch {
+	case platform != "" && browser != "":
+		return browser + " on " + platform
+	case platform != "":
+		return platform
+	case browser != "":
+		return browser
+	}
+	return "Unknown"
+}
+
+// truncate 按字节截断字符串，避免超出字段长度
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// issueTestSession 签发 token 并返回其中的会话 tokenID
+func issueTestSession(t *testing.T, s *SessionService, user *model.User, client ClientInfo) string {
+	t.Helper()
+	token, err := s.Issue(user, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := util.ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["userId"] != user.ID {
+		t.Fatalf("userId = %v, want %d", claims["userId"], user.ID)
+	}
+	return claims["tokenId"].(string)
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	s := NewSessionService(db)
+	admins := NewAdminService(db, s)
+
+	phone := issueTestSession(t, s, alice, ClientInfo{IP: "10.0.0.1", UserAgent: "Mozilla/5.0 (iPhone) Safari/604.1"})
+	laptop := issueTestSession(t, s, alice, ClientInfo{Device: "work laptop", IP: "10.0.0.2"})
+	other := issueTestSession(t, s, bob, ClientInfo{})
+
+	sessions, err := s.ListByUser(alice.ID, laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("alice has %d sessions, want 2", len(sessions))
+	}
+	devices := map[string]bool{}
+	for _, session := range sessions {
+		devices[session.Device] = session.Current
+	}
+	if current, ok := devices["Safari on iPhone"]; !ok || current {
+		t.Fatalf("devices = %v, want non-current Safari on iPhone", devices)
+	}
+	if current, ok := devices["work laptop"]; !ok || !current {
+		t.Fatalf("devices = %v, want current work laptop", devices)
+	}
+	sessionID := func(tokenID string) uint {
+		var session model.Session
+		if err := db.Where("token_id = ?", tokenID).First(&session).Error; err != nil {
+			t.Fatal(err)
+		}
+		return session.ID
+	}
+
+	steps := []struct {
+		name    string
+		change  func() error
+		tokenID string
+		userID  uint
+		wantErr *util.Errno
+	}{
+		{"valid", nil, phone, alice.ID, nil},
+		{"token of another user", nil, other, alice.ID, util.ErrSessionNotExist},
+		{"unknown token", nil, "missing", alice.ID, util.ErrSessionNotExist},
+		{"revoke by another user", func() error {
+			if err := s.Revoke(sessionID(phone), bob.ID); err != util.ErrSessionNotExist {
+				t.Fatalf("Revoke() by bob error = %v, want ErrSessionNotExist", err)
+			}
+			return nil
+		}, phone, alice.ID, nil},
+		{"revoked", func() error { return s.Revoke(sessionID(phone), alice.ID) }, phone, alice.ID, util.ErrNoPermission},
+		{"revoke twice", func() error {
+			if err := s.Revoke(sessionID(phone), alice.ID); err != util.ErrSessionNotExist {
+				t.Fatalf("second Revoke() error = %v, want ErrSessionNotExist", err)
+			}
+			return nil
+		}, laptop, alice.ID, nil},
+		{"logout", func() error { return s.RevokeByTokenID(laptop) }, laptop, alice.ID, util.ErrNoPermission},
+		{"expired", func() error {
+			return db.Model(&model.Session{}).Where("token_id = ?", other).
+				UpdateColumn("expires_at", time.Now().Add(-time.Second)).Error
+		}, other, bob.ID, util.ErrNoPermission},
+		{"banned user", func() error {
+			db.Model(&model.Session{}).Where("token_id = ?", other).UpdateColumn("expires_at", time.Now().Add(time.Hour))
+			return admins.Ban(Operator{UserID: alice.ID}, bob.ID, "spam")
+		}, other, bob.ID, util.ErrUserBanned},
+		{"suspended user", func() error {
+			if err := admins.Unban(Operator{UserID: alice.ID}, bob.ID); err != nil {
+				return err
+			}
+			return admins.Suspend(Operator{UserID: alice.ID}, bob.ID, time.Hour, "spam")
+		}, other, bob.ID, util.ErrUserSuspended},
+		{"suspension over", func() error {
+			return db.Model(bob).UpdateColumn("suspended_until", time.Now().Add(-time.Second)).Error
+		}, other, bob.ID, nil},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			if err := step.change(); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+		_, err := s.Validate(step.tokenID, step.userID, "10.0.0.1")
+		var code int
+		if errno, ok := err.(*util.Errno); ok {
+			code = errno.Code
+		} else if err != nil {
+			t.Fatalf("%s: Validate() error = %v", step.name, err)
+		}
+		if (err == nil) != (step.wantErr == nil) || (step.wantErr != nil && code != step.wantErr.Code) {
+			t.Fatalf("%s: Validate() error = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+
+	sessions, err = s.ListByUser(alice.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Fatalf("alice has %d active sessions after revoke and logout, want 0", len(sessions))
+	}
+}
+
+func TestSessionValidateTouchesLastSeen(t *testing.T) {
+	db := newTestDB(t)
+	alice := createTestUser(t, db, "alice")
+	s := NewSessionService(db)
+	tokenID := issueTestSession(t, s, alice, ClientInfo{IP: "10.0.0.1"})
+	stale := time.Now().Add(-2 * sessionTouchInterval)
+
+	tests := []struct {
+		name       string
+		lastSeenAt time.Time
+		ip         string
+		wantTouch  bool
+	}{
+		{"recent, same ip", time.Now(), "10.0.0.1", false},
+		{"recent, new ip", time.Now(), "10.0.0.2", true},
+		{"stale", stale, "10.0.0.2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Model(&model.Session{}).Where("token_id = ?", tokenID).UpdateColumn("last_seen_at", tt.lastSeenAt)
+			if _, err := s.Validate(tokenID, alice.ID, tt.ip); err != nil {
+				t.Fatal(err)
+			}
+			var session model.Session
+			db.Where("token_id = ?", tokenID).First(&session)
+			if touched := session.LastSeenAt.After(tt.lastSeenAt); touched != tt.wantTouch {
+				t.Fatalf("last_seen_at touched = %v, want %v", touched, tt.wantTouch)
+			}
+			if session.IP != tt.ip {
+				t.Fatalf("ip = %s, want %s", session.IP, tt.ip)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+const sitemapXmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// sitemapURLSet sitemap 文件
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapIndex sitemap 索引文件
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapEntry 分片中的一个地址（文章ID或作者ID及其修改时间）
+type sitemapEntry struct {
+	ID        uint
+	UpdatedAt time.Time
+}
+
+// SitemapService 站点地图服务：公开的文章和有公开文章的作者主页按ID区间拆分为多个 sitemap 文件，
+// 生成结果保存在数据库中，文章变化时只标记所在的分片过期，由后台任务重新生成
+type SitemapService struct {
+	db *gorm.DB
+}
+
+func NewSitemapService(db *gorm.DB) *SitemapService {
+	return &SitemapService{db: db}
+}
+
+// Index 生成 sitemap 索引（只读取已保存的分片，不包含没有地址的分片）
+func (s *SitemapService) Index(ctx context.Context) ([]byte, error) {
+	var chunks []model.SitemapChunk
+	if err := s.db.WithContext(ctx).Omit("content").
+		Where("chunk_size = ? AND url_count > 0", config.Cfg.SitemapConfig.ChunkSize).
+		Order("kind DESC, number").Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+
+	index := sitemapIndex{Xmlns: sitemapXmlns, Sitemaps: make([]sitemapURL, 0, len(chunks))}
+	for _, chunk := range chunks {
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{
+			Loc:     fmt.Sprintf("%s/sitemaps/%s-%d.xml", config.Cfg.SitemapConfig.BaseURL, chunk.Kind, chunk.Number),
+			LastMod: sitemapTime(chunk.LastMod),
+		})
+	}
+	return marshalSitemap(index)
+}
+
+// File 获取已保存的 sitemap 文件，name 如 posts-0.xml
+func (s *SitemapService) File(ctx context.Context, name string) ([]byte, error) {
+	kind, number, ok := strings.Cut(strings.TrimSuffix(name, ".xml"), "-")
+	if !ok || (kind != model.SitemapPosts && kind != model.SitemapAuthors) {
+		return nil, util.ErrSitemapNotExist
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 || strconv.Itoa(n) != number {
+		return nil, util.ErrSitemapNotExist
+	}
+	var chunk model.SitemapChunk
+	if err := s.db.WithContext(ctx).Where("kind = ? AND number = ? AND chunk_size = ?", kind, n, config.Cfg.SitemapConfig.ChunkSize).
+		Limit(1).Find(&chunk).Error; err != nil {
+		return nil, err
+	}
+	if chunk.URLCount == 0 {
+		return nil, util.ErrSitemapNotExist
+	}
+	return chunk.Content, nil
+}
+
+// HandlePostChanged 文章新建或修改后标记所在的分片过期（发件箱订阅者）
+func (s *SitemapService) HandlePostChanged(ctx context.Context, event *model.OutboxEvent) error {
+	return invalidateSitemap(s.db.WithContext(ctx), "id = ?", event.AggregateID)
+}
+
+// RefreshStale 重新生成不存在、已过期或分片大小已修改的分片（定时任务调用），返回生成的分片数
+func (s *SitemapService) RefreshStale(ctx context.Context) (int, error) {
+	db := s.db.WithContext(ctx)
+	size := config.Cfg.SitemapConfig.ChunkSize
+	var maxIDs struct {
+		PostID uint
+		UserID uint
+	}
+	if err := db.Model(&model.Post{}).Where("hidden = ?", false).
+		Select("COALESCE(MAX(id), 0) AS post_id, COALESCE(MAX(user_id), 0) AS user_id").Scan(&maxIDs).Error; err != nil {
+		return 0, err
+	}
+	var chunks []model.SitemapChunk
+	if err := db.Omit("content").Find(&chunks).Error; err != nil {
+		return 0, err
+	}
+
+	// 已保存的分片中过期的都需要重新生成（包括超出当前ID范围、重新生成后为空的分片）
+	stale := make(map[sitemapKey]bool)
+	stored := make(map[sitemapKey]bool, len(chunks))
+	for _, chunk := range chunks {
+		key := sitemapKey{chunk.Kind, chunk.Number}
+		stored[key] = true
+		if chunk.ChunkSize != size || (chunk.StaleAt != nil && !chunk.StaleAt.Before(chunk.GeneratedAt)) {
+			stale[key] = true
+		}
+	}
+	for _, part := range []struct {
+		kind  string
+		maxID uint
+	}{{model.SitemapPosts, maxIDs.PostID}, {model.SitemapAuthors, maxIDs.UserID}} {
+		for n := 0; n*size < int(part.maxID); n++ {
+			if key := (sitemapKey{part.kind, n}); !stored[key] {
+				stale[key] = true
+			}
+		}
+	}
+
+	refreshed := 0
+	for key := range stale {
+		if err := ctx.Err(); err != nil {
+			return refreshed, err
+		}
+		if _, err := s.generate(ctx, key.kind, key.number); err != nil {
+			return refreshed, err
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// sitemapKey 分片的唯一标识
+type sitemapKey struct {
+	kind   string
+	number int
+}
+
+// generate 重新生成分片并保存（生成期间再次标记过期的分片下次仍会重新生成）
+func (s *SitemapService) generate(ctx context.Context, kind string, number int) (*model.SitemapChunk, error) {
+	db := s.db.WithContext(ctx)
+	size := config.Cfg.SitemapConfig.ChunkSize
+	chunk := model.SitemapChunk{Kind: kind, Number: number, ChunkSize: size, GeneratedAt: time.Now()}
+	from, to := number*size+1, (number+1)*size
+
+	var entries []sitemapEntry
+	pattern := config.Cfg.SitemapConfig.PostURL
+	if kind == model.SitemapAuthors {
+		pattern = config.Cfg.SitemapConfig.AuthorURL
+	}
+	if err := sitemapQuery(db, kind, from, to).Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	urlSet := sitemapURLSet{Xmlns: sitemapXmlns, URLs: make([]sitemapURL, 0, len(entries))}
+	for _, e := range entries {
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{
+			Loc:     config.Cfg.SitemapConfig.BaseURL + fmt.Sprintf(pattern, e.ID),
+			LastMod: sitemapTime(&e.UpdatedAt),
+		})
+		if chunk.LastMod == nil || e.UpdatedAt.After(*chunk.LastMod) {
+			updatedAt := e.UpdatedAt
+			chunk.LastMod = &updatedAt
+		}
+	}
+	content, err := marshalSitemap(urlSet)
+	if err != nil {
+		return nil, err
+	}
+	chunk.URLCount = len(entries)
+	chunk.Content = content
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"chunk_size", "url_count", "last_mod", "content", "generated_at"}),
+	}).Create(&chunk).Error; err != nil {
+		return nil, err
+	}
+	return &chunk, nil
+}
+
+// sitemapQuery 查询分片中的地址（ID 在 [from, to] 之间）及其修改时间
+func sitemapQuery(db *gorm.DB, kind string, from, to int) *gorm.DB {
+	query := db.Model(&model.Post{}).Where("posts.hidden = ?", false)
+	if kind == model.SitemapPosts {
+		return query.Select("id, updated_at").Where("id BETWEEN ? AND ?", from, to).Order("id")
+	}
+	// 作者主页的修改时间取其最近修改的文章；已注销用户的占位账号和被永久封禁的作者不收录
+	return query.Select("posts.user_id AS id, MAX(posts.updated_at) AS updated_at").
+		Joins("JOIN users ON users.id = posts.user_id AND users.deleted_at IS NULL").
+		Where("users.role <> ? AND users.banned_at IS NULL", model.RoleGhost).
+		Where("posts.user_id BETWEEN ? AND ?", from, to).Group("posts.user_id").Order("posts.user_id")
+}
+
+// invalidateSitemap 标记文章所在的文章分片和作者分片过期，query 为文章的查询条件
+// （文章被删除、隐藏或转移作者时需在修改前调用，转移后再对新作者调用一次）
+func invalidateSitemap(tx *gorm.DB, query interface{}, args ...interface{}) error {
+	size := config.Cfg.SitemapConfig.ChunkSize
+	postChunks := tx.Unscoped().Model(&model.Post{}).Select("FLOOR((id - 1) / ?)", size).Where(query, args...)
+	authorChunks := tx.Unscoped().Model(&model.Post{}).Select("FLOOR((user_id - 1) / ?)", size).Where(query, args...)
+	return tx.Model(&model.SitemapChunk{}).
+		Where("(kind = ? AND number IN (?)) OR (kind = ? AND number IN (?))",
+			model.SitemapPosts, postChunks, model.SitemapAuthors, authorChunks).
+		UpdateColumn("stale_at", time.Now()).Error
+}
+
+// sitemapTime sitemap 中的时间格式（W3C Datetime）
+func sitemapTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func marshalSitemap(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
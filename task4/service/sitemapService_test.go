@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gotask/task4/config"
+	"gotask/task4/model"
+)
+
+func TestSitemapServesStoredChunks(t *testing.T) {
+	db := newTestDB(t)
+	config.Cfg.SitemapConfig = config.SitemapConfig{BaseURL: "https://blog.example.com", PostURL: "/posts/%d", AuthorURL: "/users/%d", ChunkSize: 2}
+	defer testConfig()
+	author := createTestUser(t, db, "author")
+	var posts []*model.Post
+	for _, title := range []string{"a", "b", "c"} {
+		posts = append(posts, createTestPost(t, db, author.ID, title))
+	}
+	s := NewSitemapService(db)
+	ctx := context.Background()
+	// SQLite 中 MAX(updated_at) 返回字符串无法扫描为时间，作者分片预先写入并保持有效，只测试文章分片
+	authors := model.SitemapChunk{Kind: model.SitemapAuthors, ChunkSize: 2, URLCount: 1, Content: []byte("<urlset/>"), GeneratedAt: time.Now().Add(time.Hour)}
+	if err := db.Create(&authors).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name          string
+		change        func()
+		refresh       bool
+		wantRefreshed int
+		wantIndex     []string // 索引中的分片
+		wantMissing   string   // 不应出现在 posts-0.xml 中的地址
+	}{
+		{"not generated yet", nil, false, 0, []string{"authors-0.xml"}, ""},
+		{"generated", nil, true, 2, []string{"posts-0.xml", "posts-1.xml", "authors-0.xml"}, ""},
+		{"nothing stale", nil, true, 0, []string{"posts-0.xml", "posts-1.xml", "authors-0.xml"}, ""},
+		{"hidden post served until refresh", func() {
+			if err := invalidateSitemap(db, "id = ?", posts[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			db.Model(posts[0]).UpdateColumn("hidden", true)
+		}, false, 0, []string{"posts-0.xml", "posts-1.xml", "authors-0.xml"}, ""},
+		{"stale chunk regenerated", nil, true, 1, []string{"posts-0.xml", "posts-1.xml", "authors-0.xml"}, "/posts/1<"},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+		if step.refresh {
+			n, err := s.RefreshStale(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != step.wantRefreshed {
+				t.Fatalf("%s: refreshed = %d, want %d", step.name, n, step.wantRefreshed)
+			}
+		}
+		index, err := s.Index(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Count(string(index), "<sitemap>"); got != len(step.wantIndex) {
+			t.Fatalf("%s: index has %d sitemaps, want %d:\n%s", step.name, got, len(step.wantIndex), index)
+		}
+		for _, name := range step.wantIndex {
+			if !strings.Contains(string(index), "/sitemaps/"+name) {
+				t.Fatalf("%s: index missing %s:\n%s", step.name, name, index)
+			}
+		}
+		if step.wantMissing != "" {
+			file, err := s.File(ctx, "posts-0.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(file), step.wantMissing) {
+				t.Fatalf("%s: posts-0.xml still contains %s:\n%s", step.name, step.wantMissing, file)
+			}
+		}
+	}
+}
+
+func TestSitemapAuthorsExcludeGhostAndBanned(t *testing.T) {
+	db := newTestDB(t)
+	config.Cfg.SitemapConfig = config.SitemapConfig{BaseURL: "https://blog.example.com", PostURL: "/posts/%d", AuthorURL: "/users/%d", ChunkSize: 100}
+	defer testConfig()
+	admin := createTestUser(t, db, "admin")
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	ghost := createTestUser(t, db, "ghost")
+	db.Model(ghost).UpdateColumn("role", model.RoleGhost)
+	for _, u := range []*model.User{alice, bob, ghost} {
+		createTestPost(t, db, u.ID, u.Username)
+	}
+	chunk := model.SitemapChunk{Kind: model.SitemapAuthors, ChunkSize: 100, Content: []byte("<urlset/>"), GeneratedAt: time.Now().Add(-time.Second)}
+	if err := db.Create(&chunk).Error; err != nil {
+		t.Fatal(err)
+	}
+	admins := NewAdminService(db, NewSessionService(db))
+	op := Operator{UserID: admin.ID}
+
+	steps := []struct {
+		name      string
+		change    func() error
+		wantIDs   []uint
+		wantStale bool
+	}{
+		{"ghost excluded", nil, []uint{alice.ID, bob.ID}, false},
+		{"banned author excluded", func() error { return admins.Ban(op, bob.ID, "spam") }, []uint{alice.ID}, true},
+		{"unbanned author restored", func() error { return admins.Unban(op, bob.ID) }, []uint{alice.ID, bob.ID}, true},
+	}
+	for _, step := range steps {
+		db.Model(&chunk).UpdateColumn("stale_at", nil)
+		if step.change != nil {
+			if err := step.change(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// SQLite 中 MAX(updated_at) 无法扫描为时间，只取作者 ID
+		var rows []struct{ ID uint }
+		if err := sitemapQuery(db, model.SitemapAuthors, 1, 100).Scan(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		var ids []uint
+		for _, r := range rows {
+			ids = append(ids, r.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(step.wantIDs) {
+			t.Fatalf("%s: authors = %v, want %v", step.name, ids, step.wantIDs)
+		}
+		var got model.SitemapChunk
+		db.First(&got, chunk.ID)
+		if (got.StaleAt != nil) != step.wantStale {
+			t.Fatalf("%s: stale_at = %v, want stale %v", step.name, got.StaleAt, step.wantStale)
+		}
+	}
+}
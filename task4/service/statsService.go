@@ -0,0 +1,178 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// StatsService 作者数据统计（基于每日计数，不扫描评论等明细表）
+type StatsService struct {
+	db *gorm.DB
+}
+
+func NewStatsService(db *gorm.DB) *StatsService {
+	return &StatsService{db: db}
+}
+
+// DailyStat 单日统计
+type DailyStat struct {
+	Day      string `json:"day,omitempty"` // 日期（yyyy-MM-dd）
+	Views    int    `json:"views"`
+	Likes    int    `json:"likes"`
+	Comments int    `json:"comments"`
+}
+
+// AuthorDailyStat 作者单日汇总统计（含粉丝数）
+type AuthorDailyStat struct {
+	DailyStat
+	Followers      int `json:"followers"`      // 净增粉丝数
+	TotalFollowers int `json:"totalFollowers"` // 当日结束时的粉丝总数（合计中为当前粉丝总数）
+}
+
+// PostStat 单篇文章的统计
+type PostStat struct {
+	PostID        uint        `json:"postId"`
+	Title         string      `json:"title"`
+	Views         int         `json:"views"`    // 统计区间内的浏览数
+	Likes         int         `json:"likes"`    // 统计区间内的净增点赞数
+	Comments      int         `json:"comments"` // 统计区间内的评论数
+	TotalViews    int         `json:"totalViews"`
+	TotalLikes    int         `json:"totalLikes"`
+	TotalComments int         `json:"totalComments"`
+	Daily         []DailyStat `json:"daily,omitempty"` // 按天统计（查询单篇文章时返回）
+}
+
+// AuthorStats 作者统计
+type AuthorStats struct {
+	From   string            `json:"from"`
+	To     string            `json:"to"`
+	Totals AuthorDailyStat   `json:"totals"` // 统计区间内的合计（不含 day）
+	Daily  []AuthorDailyStat `json:"daily"`  // 按天汇总（无数据的日期为 0）
+	Posts  []PostStat        `json:"posts"`  // 每篇文章的统计（按区间内浏览数倒序）
+}
+
+// statRow 每日计数查询结果
+type statRow struct {
+	PostID   uint
+	Day      time.Time
+	Views    int
+	Likes    int
+	Comments int
+}
+
+// AuthorStats 查询作者最近 days 天的统计，postID 不为 0 时额外返回该文章的每日统计
+func (s *StatsService) AuthorStats(userID uint, days int, postID uint) (*AuthorStats, error) {
+	to := statDay(time.Now())
+	from := to.AddDate(0, 0, 1-days)
+	postIDs := s.db.Model(&model.Post{}).Select("id").Where("user_id = ?", userID)
+
+	// 按天汇总
+	var dailyRows []statRow
+	if err := s.db.Model(&model.PostDailyStat{}).
+		Select("day, SUM(views) AS views, SUM(likes) AS likes, SUM(comments) AS comments").
+		Where("post_id IN (?) AND day >= ?", postIDs, from).Group("day").Scan(&dailyRows).Error; err != nil {
+		return nil, err
+	}
+	var followerRows []model.UserDailyStat
+	if err := s.db.Where("user_id = ? AND day >= ?", userID, from).Find(&followerRows).Error; err != nil {
+		return nil, err
+	}
+	var followers int64
+	if err := s.db.Model(&model.Follow{}).Where("followee_id = ?", userID).Count(&followers).Error; err != nil {
+		return nil, err
+	}
+
+	stats := AuthorStats{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly)}
+	filled := fillDays(from, days, dailyRows)
+	stats.Daily = make([]AuthorDailyStat, len(filled))
+	index := make(map[string]int, len(filled))
+	for i, d := range filled {
+		stats.Daily[i].DailyStat = d
+		index[d.Day] = i
+	}
+	for _, row := range followerRows {
+		if i, ok := index[row.Day.Format(time.DateOnly)]; ok {
+			stats.Daily[i].Followers = row.Followers
+		}
+	}
+	// 从当前粉丝总数倒推每天结束时的粉丝总数
+	total := int(followers)
+	for i := len(stats.Daily) - 1; i >= 0; i-- {
+		stats.Daily[i].TotalFollowers = total
+		total -= stats.Daily[i].Followers
+	}
+	stats.Totals.TotalFollowers = int(followers)
+	for _, d := range stats.Daily {
+		stats.Totals.Views += d.Views
+		stats.Totals.Likes += d.Likes
+		stats.Totals.Comments += d.Comments
+		stats.Totals.Followers += d.Followers
+	}
+
+	// 每篇文章的区间统计和累计计数
+	var posts []model.Post
+	if err := s.db.Select("id", "title", "view_count", "like_count", "comment_count").
+		Where("user_id = ?", userID).Order("created_at DESC").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	var postRows []statRow
+	if err := s.db.Model(&model.PostDailyStat{}).
+		Select("post_id, SUM(views) AS views, SUM(likes) AS likes, SUM(comments) AS comments").
+		Where("post_id IN (?) AND day >= ?", postIDs, from).Group("post_id").Scan(&postRows).Error; err != nil {
+		return nil, err
+	}
+	byPost := make(map[uint]statRow, len(postRows))
+	for _, row := range postRows {
+		byPost[row.PostID] = row
+	}
+	found := postID == 0
+	stats.Posts = make([]PostStat, len(posts))
+	for i, post := range posts {
+		row := byPost[post.ID]
+		stats.Posts[i] = PostStat{
+			PostID:        post.ID,
+			Title:         post.Title,
+			Views:         row.Views,
+			Likes:         row.Likes,
+			Comments:      row.Comments,
+			TotalViews:    post.ViewCount,
+			TotalLikes:    post.LikeCount,
+			TotalComments: post.CommentCount,
+		}
+		if post.ID != postID {
+			continue
+		}
+		found = true
+		var rows []statRow
+		if err := s.db.Model(&model.PostDailyStat{}).Where("post_id = ? AND day >= ?", postID, from).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		stats.Posts[i].Daily = fillDays(from, days, rows)
+	}
+	if !found {
+		return nil, util.ErrPostNotExist
+	}
+	// 按区间内浏览数倒序（浏览数相同时保持发布时间倒序）
+	sort.SliceStable(stats.Posts, func(i, j int) bool { return stats.Posts[i].Views > stats.Posts[j].Views })
+	return &stats, nil
+}
+
+// fillDays 把每日计数填充为连续的日期序列（无数据的日期为 0）
+func fillDays(from time.Time, days int, rows []statRow) []DailyStat {
+	byDay := make(map[string]statRow, len(rows))
+	for _, row := range rows {
+		byDay[row.Day.Format(time.DateOnly)] = row
+	}
+	daily := make([]DailyStat, days)
+	for i := range daily {
+		day := from.AddDate(0, 0, i).Format(time.DateOnly)
+		row := byDay[day]
+		daily[i] = DailyStat{Day: day, Views: row.Views, Likes: row.Likes, Comments: row.Comments}
+	}
+	return daily
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+func TestAuthorStats(t *testing.T) {
+	db := newTestDB(t)
+	author := createTestUser(t, db, "author")
+	other := createTestUser(t, db, "other")
+	fans := []*model.User{createTestUser(t, db, "fan1"), createTestUser(t, db, "fan2"), createTestUser(t, db, "fan3")}
+	first := createTestPost(t, db, author.ID, "first")
+	second := createTestPost(t, db, author.ID, "second")
+	foreign := createTestPost(t, db, other.ID, "foreign")
+
+	today := statDay(time.Now())
+	day := func(offset int) time.Time { return today.AddDate(0, 0, offset) }
+	rows := []interface{}{
+		&model.PostDailyStat{PostID: first.ID, Day: day(-2), Views: 5, Likes: 1},
+		&model.PostDailyStat{PostID: first.ID, Day: day(0), Views: 2, Comments: 1},
+		&model.PostDailyStat{PostID: second.ID, Day: day(0), Views: 10},
+		&model.PostDailyStat{PostID: foreign.ID, Day: day(0), Views: 100},
+		&model.PostDailyStat{PostID: first.ID, Day: day(-5), Views: 50}, // 统计区间之外
+		&model.UserDailyStat{UserID: author.ID, Day: day(-2), Followers: 2},
+		&model.UserDailyStat{UserID: author.ID, Day: day(0), Followers: 1},
+	}
+	for _, fan := range fans {
+		rows = append(rows, &model.Follow{FollowerID: fan.ID, FolloweeID: author.ID})
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewStatsService(db)
+
+	t.Run("buckets", func(t *testing.T) {
+		stats, err := s.AuthorStats(author.ID, 3, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []AuthorDailyStat{
+			{DailyStat{Day: day(-2).Format(time.DateOnly), Views: 5, Likes: 1}, 2, 2},
+			{DailyStat{Day: day(-1).Format(time.DateOnly)}, 0, 2},
+			{DailyStat{Day: day(0).Format(time.DateOnly), Views: 12, Comments: 1}, 1, 3},
+		}
+		if len(stats.Daily) != len(want) {
+			t.Fatalf("daily = %+v, want %d days", stats.Daily, len(want))
+		}
+		for i := range want {
+			if stats.Daily[i] != want[i] {
+				t.Fatalf("daily[%d] = %+v, want %+v", i, stats.Daily[i], want[i])
+			}
+		}
+		wantTotals := AuthorDailyStat{DailyStat{Views: 17, Likes: 1, Comments: 1}, 3, 3}
+		if stats.Totals != wantTotals {
+			t.Fatalf("totals = %+v, want %+v", stats.Totals, wantTotals)
+		}
+		if len(stats.Posts) != 2 || stats.Posts[0].PostID != second.ID || stats.Posts[1].Daily != nil {
+			t.Fatalf("posts = %+v, want second then first without daily", stats.Posts)
+		}
+	})
+
+	t.Run("post filter", func(t *testing.T) {
+		stats, err := s.AuthorStats(author.ID, 3, first.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range stats.Posts {
+			if (p.PostID == first.ID) != (p.Daily != nil) {
+				t.Fatalf("post %d daily = %+v", p.PostID, p.Daily)
+			}
+			if p.PostID != first.ID {
+				continue
+			}
+			views := []int{5, 0, 2}
+			for i, d := range p.Daily {
+				if d.Views != views[i] {
+					t.Fatalf("first daily[%d].views = %d, want %d", i, d.Views, views[i])
+				}
+			}
+		}
+	})
+
+	t.Run("not owned post", func(t *testing.T) {
+		for _, postID := range []uint{foreign.ID, 9999} {
+			if _, err := s.AuthorStats(author.ID, 3, postID); err != util.ErrPostNotExist {
+				t.Fatalf("postId %d: err = %v, want ErrPostNotExist", postID, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gotask/task4/config"
+	"gotask/task4/model"
+)
+
+var registerTestDriver sync.Once
+
+// newTestDB 创建测试用的内存数据库（SQLite），并补充服务中用到的 MySQL 函数
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	registerTestDriver.Do(func() {
+		sql.Register("sqlite3_mysql", &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("LOG10", func(v interface{}) float64 {
+				return math.Log10(toFloat(v))
+			}, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("GREATEST", func(values ...interface{}) float64 {
+				max := math.Inf(-1)
+				for _, v := range values {
+					max = math.Max(max, toFloat(v))
+				}
+				return max
+			}, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("FLOOR", func(v interface{}) int64 {
+				return int64(math.Floor(toFloat(v)))
+			}, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("UNIX_TIMESTAMP", func(s string) int64 {
+				for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano} {
+					if t, err := time.Parse(layout, s); err == nil {
+						return t.Unix()
+					}
+				}
+				return 0
+			}, true)
+		}})
+	})
+	testConfig()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: "sqlite3_mysql", DSN: dsn}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库只在连接存续期间存在，且 SQLite 不支持并发写
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(
+		&model.User{}, &model.Post{}, &model.Comment{}, &model.UserIdentity{}, &model.Session{},
+		&model.AccountDeletion{}, &model.AuditLog{}, &model.Follow{}, &model.Report{}, &model.Mention{},
+		&model.Notification{}, &model.Like{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.OutboxEvent{},
+		&model.Attachment{}, &model.Category{}, &model.Series{}, &model.Tag{}, &model.RelatedPost{},
+		&model.PostDailyStat{}, &model.UserDailyStat{}, &model.ImportRecord{}, &model.SitemapChunk{},
+		&model.OAuthLogin{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// toFloat 将 SQLite 传入的整数或浮点参数转换为 float64
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// testConfig 测试使用的配置（与 config.Init 的默认值一致）
+func testConfig() {
+	config.Cfg.JWTConfig.SecretKey = "test"
+	config.Cfg.JWTConfig.SigningMethod = "HS256"
+	config.Cfg.JWTConfig.ExpireDuration = time.Hour
+	config.Cfg.AdminConfig.ImpersonationTTL = 30 * time.Minute
+	config.Cfg.WebhookConfig = config.WebhookConfig{
+		Timeout:        5 * time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: 30 * time.Second,
+		RetryMaxDelay:  time.Hour,
+		BatchSize:      50,
+	}
+	config.Cfg.OutboxConfig.BatchSize = 100
+	config.Cfg.OutboxConfig.RetryBaseDelay = 5 * time.Second
+	config.Cfg.OutboxConfig.RetryMaxDelay = 10 * time.Minute
+	config.Cfg.OutboxConfig.MaxAttempts = 3
+	config.Cfg.ImageConfig = config.ImageConfig{
+		Widths:      []int{160, 480, 1024},
+		Formats:     []string{"jpeg"},
+		Quality:     82,
+		MaxPixels:   40_000_000,
+		AvatarSizes: []int{80, 40, 200},
+	}
+	config.Cfg.StorageConfig.MaxSize = 10 << 20
+	config.Cfg.ImportConfig.MaxUncompressed = 1 << 20
+	config.Cfg.SitemapConfig.ChunkSize = 50000
+	config.Cfg.StatsConfig.ViewWindow = 30 * time.Minute
+	config.Cfg.RelatedConfig = config.RelatedConfig{Limit: 5, TTL: 24 * time.Hour, CorpusSize: 500, BatchSize: 50}
+	config.Cfg.ModerationConfig = config.ModerationConfig{
+		MaxLinks:        2,
+		BlockedWords:    []string{"spam"},
+		NewAccountAge:   24 * time.Hour,
+		DuplicateWindow: 24 * time.Hour,
+		ReportThreshold: 5,
+	}
+}
+
+// createTestUser 创建测试用户
+func createTestUser(t *testing.T, db *gorm.DB, username string) *model.User {
+	t.Helper()
+	user := model.User{Username: username, Email: username + "@example.com", Password: "password"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+// createTestPost 创建测试文章
+func createTestPost(t *testing.T, db *gorm.DB, userID uint, title string) *model.Post {
+	t.Helper()
+	post := model.Post{Title: title, Content: title + " content", UserID: userID}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &post
+}
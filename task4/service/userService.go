@@ -1,6 +1,8 @@
 package service
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 	"gotask/task4/model"
 	"gotask/task4/util"
@@ -8,16 +10,20 @@ import (
 
 // UserService 用户服务
 type UserService struct {
-	db *gorm.DB // 数据库增删改查
+	db             *gorm.DB // 数据库增删改查
+	sessionService *SessionService
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(db *gorm.DB, sessionService *SessionService) *UserService {
+	return &UserService{db: db, sessionService: sessionService}
 }
 
 // Register 用户注册
 func (s *UserService) Register(username, email, password string) error {
-	// 检查用户是否已存在
+	// 检查用户是否已存在（占位账号的用户名保留，不允许注册）
+	if username == model.GhostUsername {
+		return util.NewErrno(util.ErrUserExist, "username=%s", username)
+	}
 	var user model.User
 	if err := s.db.Where("username = ?", username).First(&user).Error; err == nil {
 		return util.NewErrno(util.ErrUserExist, "username=%s", username)
@@ -35,10 +41,10 @@ func (s *UserService) Register(username, email, password string) error {
 	return s.db.Create(&user).Error
 }
 
-// Login 用户登录（返回token）
-func (s *UserService) Login(username, password string) (string, error) {
+// Login 用户登录（创建会话并返回token）
+func (s *UserService) Login(username, password string, client ClientInfo) (string, error) {
 	var user model.User
-	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := s.db.Where("username = ? AND role <> ?", username, model.RoleGhost).First(&user).Error; err != nil {
 		return "", util.ErrUserNotExist
 	}
 
@@ -47,6 +53,36 @@ func (s *UserService) Login(username, password string) (string, error) {
 		return "", util.ErrInvalidPass
 	}
 
-	// 生成JWT token
-	return util.GenerateToken(user.ID, user.Username)
+	// 检查账号状态
+	if err := user.Blocked(); err != nil {
+		return "", err
+	}
+	if user.PasswordResetToken != "" {
+		return "", util.ErrPasswordReset
+	}
+
+	// 创建会话并生成JWT token
+	return s.sessionService.Issue(&user, client)
+}
+
+// ResetPassword 使用管理员下发的重置令牌设置新密码
+func (s *UserService) ResetPassword(token, newPassword string) error {
+	var user model.User
+	if err := s.db.Where("password_reset_token = ?", util.HashToken(token)).First(&user).Error; err != nil {
+		return util.ErrResetTokenInvalid
+	}
+	if user.PasswordResetExpires == nil || time.Now().After(*user.PasswordResetExpires) {
+		return util.ErrResetTokenInvalid
+	}
+
+	// 使用 map 更新不会触发 BeforeSave 加密，这里手动加密
+	hash, err := util.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&user).Updates(map[string]interface{}{
+		"password":               hash,
+		"password_reset_token":   "",
+		"password_reset_expires": nil,
+	}).Error
 }
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+	"gotask/task4/config"
+	"gotask/task4/model"
+	"gotask/task4/util"
+)
+
+// WebhookService Webhook 注册与投递服务
+type WebhookService struct {
+	db     *gorm.DB
+	client *http.Client
+}
+
+func NewWebhookService(db *gorm.DB) *WebhookService {
+	return &WebhookService{
+		db:     db,
+		client: newWebhookClient(config.Cfg.WebhookConfig),
+	}
+}
+
+// newWebhookClient 投递用的 HTTP 客户端：每次建立连接时检查解析出的 IP，
+// 拒绝连接内网、本机和链路本地地址（包括重定向和 DNS 重绑定），不使用环境变量中的代理
+func newWebhookClient(cfg config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("webhook: address %s is not allowed", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.Timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+	}
+}
+
+// carrierGradeNAT 运营商级 NAT 地址段（100.64.0.0/10），net.IP 未将其视为私有地址
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP 是否为公网地址（排除本机、内网、链路本地如 169.254.169.254 云元数据地址、组播和未指定地址）
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || carrierGradeNAT.Contains(ip))
+}
+
+// checkWebhookURL 校验回调地址：非管理员只能使用 https，地址为 IP 时不能是内网地址（域名在投递时检查）
+func checkWebhookURL(rawURL string, admin bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return util.NewErrno(util.ErrInvalidParam, "回调地址无效")
+	}
+	if u.Scheme != "https" && (!admin || u.Scheme != "http") {
+		return util.NewErrno(util.ErrInvalidParam, "回调地址必须使用 https")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !publicIP(ip) && !config.Cfg.WebhookConfig.AllowPrivateNetworks {
+		return util.NewErrno(util.ErrInvalidParam, "回调地址不能是内网地址")
+	}
+	return nil
+}
+
+// WebhookPayload 投递的请求体
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Create 注册 Webhook，返回 Webhook 和签名密钥（密钥只在创建时返回），admin 表示注册人是管理员
+func (s *WebhookService) Create(userID uint, admin, global bool, url string, events []string, description string) (*model.Webhook, string, error) {
+	if err := checkWebhookURL(url, admin); err != nil {
+		return nil, "", err
+	}
+	webhook := model.Webhook{
+		UserID:      userID,
+		Global:      global,
+		URL:         url,
+		Secret:      util.RandomHex(32),
+		Events:      strings.Join(events, ","),
+		Description: description,
+		Active:      true,
+	}
+	if err := s.db.Create(&webhook).Error; err != nil {
+		return nil, "", err
+	}
+	return &webhook, webhook.Secret, nil
+}
+
+// ListByUser 查询用户注册的 Webhook
+func (s *WebhookService) ListByUser(userID uint) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// SetActive 启用或停用 Webhook（仅注册人）
+func (s *WebhookService) SetActive(id, userID uint, active bool) error {
+	result := s.db.Model(&model.Webhook{}).Where("id = ? AND user_id = ?", id, userID).Update("active", active)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return util.ErrWebhookNotExist
+	}
+	return nil
+}
+
+// Delete 删除 Webhook（仅注册人），未投递的记录不再投递
+func (s *WebhookService) Delete(id, userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return util.ErrWebhookNotExist
+		}
+		return tx.Model(&model.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", id, model.DeliveryPending).
+			Updates(map[string]interface{}{"status": model.DeliveryFailed, "next_attempt_at": nil, "error": "webhook deleted"}).Error
+	})
+}
+
+// Deliveries 分页查询 Webhook 的投递记录（仅注册人），响应内容只返回给管理员
+func (s *WebhookService) Deliveries(id, userID uint, admin bool, page, pageSize int) (*util.PageResult, error) {
+	if err := s.db.Select("id").Where("id = ? AND user_id = ?", id, userID).First(&model.Webhook{}).Error; err != nil {
+		return nil, util.ErrWebhookNotExist
+	}
+	var (
+		deliveries []model.WebhookDelivery
+		total      int64
+	)
+	db := s.db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", id)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	offset := (page - 1) * pageSize
+	if err := db.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	if !admin {
+		for i := range deliveries {
+			deliveries[i].ResponseBody = ""
+		}
+	}
+	return util.CalcPageResult(deliveries, total, page, pageSize), nil
+}
+
+// Redeliver 以原请求体重新投递（生成新的投递记录，仅注册人）
+func (s *WebhookService) Redeliver(deliveryID, userID uint) (*model.WebhookDelivery, error) {
+	var original model.WebhookDelivery
+	if err := s.db.Joins("Webhook").Where("webhook_deliveries.id = ? AND Webhook.user_id = ?", deliveryID, userID).
+		First(&original).Error; err != nil {
+		return nil, util.ErrDeliveryNotExist
+	}
+	now := time.Now()
+	delivery := model.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+		OutboxEventID: original.OutboxEventID,
+		RedeliveryOf:  &original.ID,
+	}
+	if err := s.db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// DeliverDue 投递到期的记录（定时任务调用），返回处理的条数
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	cfg := config.Cfg.WebhookConfig
+	var due []model.WebhookDelivery
+	if err := s.db.Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, time.Now()).
+		Order("next_attempt_at").Limit(cfg.BatchSize).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	for i := range due {
+		delivery := &due[i]
+		// 抢占投递：把下次投递时间推后到请求超时之后，多实例部署时避免重复投递
+		lease := time.Now().Add(cfg.Timeout + time.Minute)
+		result := s.db.Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, model.DeliveryPending, delivery.NextAttemptAt).
+			Update("next_attempt_at", lease)
+		if result.Error != nil {
+			return handled, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := s.deliver(ctx, delivery); err != nil {
+			return handled, err
+		}
+		handled++
+	}
+	return handled, nil
+}
+
+// deliver 发送一次请求并记录结果，失败时按指数退避安排重试
+func (s *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) error {
+	cfg := config.Cfg.WebhookConfig
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts + 1,
+		"last_attempt_at": now,
+		"response_status": 0,
+		"response_body":   "",
+		"error":           "",
+	}
+
+	webhook := delivery.Webhook
+	var deliverErr error
+	if webhook.ID == 0 || !webhook.Active {
+		deliverErr = fmt.Errorf("webhook disabled")
+	} else {
+		status, body, err := s.send(ctx, &webhook, delivery)
+		updates["response_status"] = status
+		updates["response_body"] = body
+		switch {
+		case err != nil:
+			deliverErr = err
+		case status < 200 || status >= 300:
+			deliverErr = fmt.Errorf("unexpected status %d", status)
+		}
+	}
+
+	switch {
+	case deliverErr == nil:
+		updates["status"] = model.DeliverySuccess
+		updates["next_attempt_at"] = nil
+	case delivery.Attempts+1 >= cfg.MaxAttempts || webhook.ID == 0 || !webhook.Active:
+		updates["status"] = model.DeliveryFailed
+		updates["next_attempt_at"] = nil
+		updates["error"] = truncate(deliverErr.Error(), 500)
+	default:
+		updates["next_attempt_at"] = now.Add(retryDelay(cfg.RetryBaseDelay, cfg.RetryMaxDelay, delivery.Attempts+1))
+		updates["error"] = truncate(deliverErr.Error(), 500)
+	}
+	return s.db.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+}
+
+// send 发送签名后的请求，返回响应状态码和（截断后的）响应内容
+func (s *WebhookService) send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return resp.StatusCode, string(body), nil
+}
+
+// SignWebhook 计算签名：HMAC-SHA256(secret, "<timestamp>.<body>") 的十六进制。
+// 接收方应使用相同算法校验，并拒绝时间戳过旧的请求以防重放
+func SignWebhook(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay 第 attempts 次失败后的重试间隔（指数退避，有上限）
+func retryDelay(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// HandleEvent 发件箱订阅者：把事件写入订阅了该事件的 Webhook 投递队列
+// （全站 Webhook，以及事件所属文章作者注册的 Webhook）
+func (s *WebhookService) HandleEvent(ctx context.Context, event *model.OutboxEvent) error {
+	// 文章事件的 userId 即作者；评论事件需查询所属文章的作者
+	var data struct {
+		UserID uint `json:"userId"`
+		PostID uint `json:"postId"`
+	}
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return err
+	}
+	db := s.db.WithContext(ctx)
+	ownerID := data.UserID
+	if event.Type == model.EventCommentCreated {
+		var post model.Post
+		err := db.Unscoped().Select("id", "user_id").First(&post, data.PostID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // 文章已随账号注销被删除
+		}
+		if err != nil {
+			return err
+		}
+		ownerID = post.UserID
+	}
+
+	var webhooks []model.Webhook
+	if err := db.Where("active = ? AND (global = ? OR user_id = ?)", true, true, ownerID).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	payload, err := json.Marshal(WebhookPayload{Event: event.Type, CreatedAt: event.CreatedAt, Data: json.RawMessage(event.Payload)})
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, webhook := range webhooks {
+			if !subscribes(webhook.Events, event.Type) {
+				continue
+			}
+			// 事件重复投递时不重复入队
+			var count int64
+			if err := tx.Model(&model.WebhookDelivery{}).
+				Where("webhook_id = ? AND outbox_event_id = ? AND redelivery_of IS NULL", webhook.ID, event.ID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := tx.Create(&model.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				Event:         event.Type,
+				Payload:       string(payload),
+				Status:        model.DeliveryPending,
+				NextAttemptAt: &now,
+				OutboxEventID: event.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// subscribes 判断 Webhook 是否订阅了事件
+func subscribes(events, event string) bool {
+	for _, e := range strings.Split(events, ",") {
+		if e == model.WebhookAllEvents || e == event {
+			return true
+		}
+	}
+	return false
+}
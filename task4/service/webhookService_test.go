@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotask/task4/config"
+	"gotask/task4/model"
+)
+
+func TestCheckWebhookURL(t *testing.T) {
+	testConfig()
+	tests := []struct {
+		name    string
+		url     string
+		admin   bool
+		wantErr bool
+	}{
+		{"https", "https://hooks.example.com/blog", false, false},
+		{"http for user", "http://hooks.example.com/blog", false, true},
+		{"http for admin", "http://hooks.example.com/blog", true, false},
+		{"other scheme", "ftp://hooks.example.com/blog", true, true},
+		{"loopback", "https://127.0.0.1/hook", false, true},
+		{"private", "https://10.0.0.8/hook", true, true},
+		{"cloud metadata", "https://169.254.169.254/latest/meta-data", false, true},
+		{"ipv6 loopback", "https://[::1]/hook", false, true},
+		{"ipv4-mapped loopback", "https://[::ffff:127.0.0.1]/hook", false, true},
+		{"carrier-grade nat", "https://100.64.1.1/hook", false, true},
+		{"public ip", "https://93.184.216.34/hook", false, false},
+		{"no host", "https:///hook", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWebhookURL(tt.url, tt.admin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkWebhookURL(%q, %v) = %v, wantErr %v", tt.url, tt.admin, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"192.168.1.1", false},
+		{"172.16.0.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("publicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookClientRejectsPrivateAddresses(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal secret"))
+	}))
+	defer internal.Close()
+	// 公网地址重定向到内网地址时同样被拒绝（这里以本机地址模拟重定向目标）
+	redirect := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer redirect.Close()
+
+	tests := []struct {
+		name         string
+		allowPrivate bool
+		url          string
+		wantErr      bool
+	}{
+		{"blocked", false, internal.URL, true},
+		{"blocked redirect", false, redirect.URL, true},
+		{"allowed for local testing", true, internal.URL, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newWebhookClient(config.WebhookConfig{Timeout: 5 * time.Second, AllowPrivateNetworks: tt.allowPrivate})
+			resp, err := client.Get(tt.url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GET %s: err = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "not allowed") {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveriesHideResponseBody(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner")
+	webhook := model.Webhook{UserID: owner.ID, URL: "https://hooks.example.com", Secret: "s", Events: "*", Active: true}
+	if err := db.Create(&webhook).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.WebhookDelivery{
+		WebhookID: webhook.ID, Event: model.EventPostCreated, Payload: "{}", Status: model.DeliverySuccess,
+		ResponseStatus: 200, ResponseBody: "response",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewWebhookService(db)
+	for _, tt := range []struct {
+		admin bool
+		want  string
+	}{{false, ""}, {true, "response"}} {
+		page, err := s.Deliveries(webhook.ID, owner.ID, tt.admin, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deliveries := page.List.([]model.WebhookDelivery)
+		if len(deliveries) != 1 || deliveries[0].ResponseBody != tt.want || deliveries[0].ResponseStatus != 200 {
+			t.Fatalf("admin=%v: deliveries = %+v, want body %q", tt.admin, deliveries, tt.want)
+		}
+	}
+}
+
+func TestWebhookDeliverToBlockedAddressFails(t *testing.T) {
+	db := newTestDB(t)
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer server.Close()
+
+	owner := createTestUser(t, db, "owner")
+	// 模拟注册时为公网域名、投递时解析到内网地址的情况（注册时的检查无法覆盖）
+	webhook := model.Webhook{UserID: owner.ID, URL: server.URL, Secret: "s", Events: "*", Active: true}
+	if err := db.Create(&webhook).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(-time.Second)
+	delivery := model.WebhookDelivery{WebhookID: webhook.ID, Event: model.EventPostCreated, Payload: "{}",
+		Status: model.DeliveryPending, NextAttemptAt: &now}
+	if err := db.Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewWebhookService(db).DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 0 {
+		t.Fatalf("request reached private address")
+	}
+	if err := db.First(&delivery, delivery.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Attempts != 1 || !strings.Contains(delivery.Error, "not allowed") {
+		t.Fatalf("delivery = %+v, want blocked attempt", delivery)
+	}
+}
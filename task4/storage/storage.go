@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"gotask/task4/config"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage 文件存储接口（key 由调用方生成，只包含字母、数字、/ . - _）
+type Storage interface {
+	// Put 写入文件，size 为内容长度
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// List 列出 key 以 prefix 开头的文件（按 key 排序）
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Object 存储中的文件
+type Object struct {
+	Key  string
+	Size int64
+}
+
+// New 按配置创建存储后端
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocalStorage(cfg.LocalDir)
+	case "s3":
+		return NewS3Storage(cfg.S3), nil
+	}
+	return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Driver)
+}
@@ -14,15 +14,43 @@ func (e *Errno) Error() string {
 
 // 定义常见错误码
 var (
-	ErrSuccess       = &Errno{Code: 200, Msg: "success"}
-	ErrInvalidParam  = &Errno{Code: 1001, Msg: "请求参数错误"}
-	ErrUserExist     = &Errno{Code: 2001, Msg: "用户已存在"}
-	ErrEmailExist    = &Errno{Code: 2002, Msg: "邮箱已存在"}
-	ErrUserNotExist  = &Errno{Code: 2003, Msg: "用户不存在"}
-	ErrInvalidPass   = &Errno{Code: 2003, Msg: "密码错误"}
-	ErrPostNotExist  = &Errno{Code: 3001, Msg: "文章不存在"}
-	ErrNoPermission  = &Errno{Code: 4001, Msg: "没有权限"}
-	ErrInternalError = &Errno{Code: 5001, Msg: "服务器内部错误"}
+	ErrSuccess           = &Errno{Code: 200, Msg: "success"}
+	ErrInvalidParam      = &Errno{Code: 1001, Msg: "请求参数错误"}
+	ErrUserExist         = &Errno{Code: 2001, Msg: "用户已存在"}
+	ErrEmailExist        = &Errno{Code: 2002, Msg: "邮箱已存在"}
+	ErrUserNotExist      = &Errno{Code: 2003, Msg: "用户不存在"}
+	ErrInvalidPass       = &Errno{Code: 2003, Msg: "密码错误"}
+	ErrOAuthState        = &Errno{Code: 2004, Msg: "登录状态已失效，请重新登录"}
+	ErrOAuthFailed       = &Errno{Code: 2005, Msg: "第三方登录失败"}
+	ErrSessionNotExist   = &Errno{Code: 2006, Msg: "会话不存在"}
+	ErrDeletionNotExist  = &Errno{Code: 2007, Msg: "未申请注销账号"}
+	ErrUserBanned        = &Errno{Code: 2008, Msg: "账号已被封禁"}
+	ErrUserSuspended     = &Errno{Code: 2009, Msg: "账号已被临时封禁"}
+	ErrPasswordReset     = &Errno{Code: 2010, Msg: "需要重置密码后才能登录"}
+	ErrResetTokenInvalid = &Errno{Code: 2011, Msg: "重置令牌无效或已过期"}
+	ErrFollowSelf        = &Errno{Code: 2012, Msg: "不能关注自己"}
+	ErrOAuthLinkRequired = &Errno{Code: 2013, Msg: "该邮箱已注册，请登录后在账号设置中绑定第三方账号"}
+	ErrOAuthLinked       = &Errno{Code: 2014, Msg: "该第三方账号已绑定其他用户"}
+	ErrPostNotExist      = &Errno{Code: 3001, Msg: "文章不存在"}
+	ErrCommentClosed     = &Errno{Code: 3002, Msg: "文章已关闭评论"}
+	ErrCommentFollowers  = &Errno{Code: 3003, Msg: "仅关注作者的用户可以评论"}
+	ErrCommentNotExist   = &Errno{Code: 3004, Msg: "评论不存在"}
+	ErrReportExist       = &Errno{Code: 3005, Msg: "已经举报过该内容"}
+	ErrReportNotExist    = &Errno{Code: 3006, Msg: "没有待处理的举报"}
+	ErrWebhookNotExist   = &Errno{Code: 3007, Msg: "Webhook 不存在"}
+	ErrDeliveryNotExist  = &Errno{Code: 3008, Msg: "投递记录不存在"}
+	ErrFileTooLarge      = &Errno{Code: 3009, Msg: "文件过大"}
+	ErrFileType          = &Errno{Code: 3010, Msg: "不支持的文件类型"}
+	ErrAttachmentInvalid = &Errno{Code: 3011, Msg: "附件不存在或不属于当前用户"}
+	ErrCategoryNotExist  = &Errno{Code: 3012, Msg: "分类不存在"}
+	ErrCategoryExist     = &Errno{Code: 3013, Msg: "同级分类名称已存在"}
+	ErrCategoryParent    = &Errno{Code: 3014, Msg: "不能把分类移动到自身或其子分类下"}
+	ErrCategoryNotEmpty  = &Errno{Code: 3015, Msg: "分类下还有子分类"}
+	ErrSeriesNotExist    = &Errno{Code: 3016, Msg: "系列不存在"}
+	ErrSitemapNotExist   = &Errno{Code: 3017, Msg: "站点地图不存在"}
+	ErrOutboxNotFailed   = &Errno{Code: 3018, Msg: "事件不存在或未转发失败"}
+	ErrNoPermission      = &Errno{Code: 4001, Msg: "没有权限"}
+	ErrInternalError     = &Errno{Code: 5001, Msg: "服务器内部错误"}
 )
 
 // NewErrno 格式化错误消息
@@ -10,23 +10,45 @@ import (
 
 // JWTClaims JWT载荷
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID         uint   `json:"user_id"`
+	Username       string `json:"username"`
+	ImpersonatorID uint   `json:"imp,omitempty"` // 代登录的管理员ID（普通登录为空）
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username string) (string, error) {
+// GenerateToken 生成JWT token（tokenID 写入 jti，对应一条登录会话；impersonatorID 非零表示管理员代登录）
+func GenerateToken(userID uint, username, tokenID string, expiresAt time.Time, impersonatorID uint) (string, error) {
 	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
+		UserID:         userID,
+		Username:       username,
+		ImpersonatorID: impersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Cfg.JWTConfig.ExpireDuration)),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.Cfg.JWTConfig.SecretKey))
+	return signToken(claims)
+}
+
+// signToken 使用当前签发密钥签名（非对称算法会在头部写入 kid）
+func signToken(claims jwt.Claims) (string, error) {
+	if keyring == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(config.Cfg.JWTConfig.SecretKey))
+	}
+	key := keyring.signingKey()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.Private)
+}
+
+// verificationKey 返回验签密钥（HS256 使用共享密钥，非对称算法按 kid 查找公钥）
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if keyring == nil {
+		return []byte(config.Cfg.JWTConfig.SecretKey), nil
+	}
+	return keyring.verificationKey(token)
 }
 
 // ParseToken 解析JWT token，返回用户信息
@@ -34,9 +56,8 @@ func ParseToken(tokenString string) (map[string]interface{}, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&JWTClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.Cfg.JWTConfig.SecretKey), nil
-		},
+		verificationKey,
+		jwt.WithValidMethods([]string{config.Cfg.JWTConfig.SigningMethod}),
 	)
 	retData := make(map[string]interface{})
 	if err != nil {
@@ -45,6 +66,8 @@ func ParseToken(tokenString string) (map[string]interface{}, error) {
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
 		retData["userId"] = claims.UserID
 		retData["username"] = claims.Username
+		retData["tokenId"] = claims.ID
+		retData["impersonatorId"] = claims.ImpersonatorID
 		return retData, nil
 	}
 	return retData, errors.New("invalid token")
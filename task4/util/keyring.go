@@ -0,0 +1,392 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gotask/task4/config"
+)
+
+// JWKSMaxAge JWKS 响应允许外部验签方缓存的时间
+const JWKSMaxAge = 5 * time.Minute
+
+// signingKey 一把签名密钥（私钥用于签发，公钥用于验签和 JWKS 发布）
+type signingKey struct {
+	Kid       string
+	Alg       string
+	Private   crypto.Signer
+	CreatedAt time.Time
+	NotBefore time.Time // 开始签发的时间（轮换生成的密钥先发布，到点后才签发）
+}
+
+// Keyring 非对称签名密钥环：已到签发时间的最新密钥负责签发，未过保留期的旧密钥继续用于验签
+type Keyring struct {
+	mu      sync.RWMutex
+	dir     string
+	alg     string
+	rotate  time.Duration // 轮换周期
+	retain  time.Duration // 停止签发后继续保留用于验签的时间（=令牌有效期）
+	publish time.Duration // 新密钥提前发布的时间（其他实例重新加载 + 外部验签方刷新 JWKS 缓存）
+	keys    map[string]*signingKey
+}
+
+// 全局密钥环（HS256 模式下为 nil）
+var keyring *Keyring
+
+// InitKeyring 根据配置初始化密钥环：加载目录中的密钥，没有可用密钥时生成一把
+func InitKeyring() error {
+	jwtCfg := config.Cfg.JWTConfig
+	if jwtCfg.SigningMethod == "HS256" {
+		keyring = nil
+		return nil
+	}
+	if err := os.MkdirAll(jwtCfg.KeyDir, 0o700); err != nil {
+		return err
+	}
+	k := &Keyring{
+		dir:     jwtCfg.KeyDir,
+		alg:     jwtCfg.SigningMethod,
+		rotate:  jwtCfg.RotationInterval,
+		retain:  jwtCfg.ExpireDuration,
+		publish: jwtCfg.KeyReloadInterval + JWKSMaxAge,
+	}
+	if err := k.RotateIfDue(); err != nil {
+		return err
+	}
+	keyring = k
+	return nil
+}
+
+// RotateKeysIfDue 定时任务入口：检查全局密钥环是否需要轮换
+func RotateKeysIfDue() error {
+	if keyring == nil {
+		return nil
+	}
+	return keyring.RotateIfDue()
+}
+
+// RotateIfDue 重新加载密钥目录（多实例共享目录时可感知其他实例的轮换），
+// 当前签发密钥临近轮换周期时提前生成下一把密钥，并清理已过保留期的旧密钥
+func (k *Keyring) RotateIfDue() error {
+	return k.rotateAt(time.Now())
+}
+
+// rotateAt 按指定时间执行一次轮换检查
+func (k *Keyring) rotateAt(now time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.load(); err != nil {
+		return err
+	}
+	active := k.current(now)
+	switch {
+	case active == nil:
+		// 没有可用密钥（首次启动或更换了算法）：生成后立即签发
+		if err := k.add(now, now); err != nil {
+			return err
+		}
+	case !k.hasPending(now) && now.Sub(active.NotBefore) >= k.rotate-k.publish:
+		// 下一把密钥提前一个发布期写入目录：各实例重新加载、外部验签方刷新 JWKS 后才开始签发，
+		// 避免新令牌在其他地方出现 unknown kid
+		notBefore := active.NotBefore.Add(k.rotate)
+		if earliest := now.Add(k.publish); notBefore.Before(earliest) {
+			notBefore = earliest
+		}
+		if err := k.add(now, notBefore); err != nil {
+			return err
+		}
+	}
+	k.prune(now)
+	return nil
+}
+
+// add 生成并保存一把新密钥
+func (k *Keyring) add(now, notBefore time.Time) error {
+	key, err := generateSigningKey(k.alg)
+	if err != nil {
+		return err
+	}
+	key.CreatedAt = now
+	key.NotBefore = notBefore
+	if err := k.save(key); err != nil {
+		return err
+	}
+	k.keys[key.Kid] = key
+	return nil
+}
+
+// current 返回指定时间应使用的签发密钥：当前算法下已到签发时间的最新密钥
+func (k *Keyring) current(now time.Time) *signingKey {
+	var active *signingKey
+	for _, key := range k.keys {
+		if key.Alg != k.alg || key.NotBefore.After(now) {
+			continue
+		}
+		if active == nil || key.NotBefore.After(active.NotBefore) {
+			active = key
+		}
+	}
+	return active
+}
+
+// hasPending 是否已有发布但尚未开始签发的密钥
+func (k *Keyring) hasPending(now time.Time) bool {
+	for _, key := range k.keys {
+		if key.Alg == k.alg && key.NotBefore.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// load 从目录读取所有 PEM 私钥文件
+func (k *Keyring) load() error {
+	files, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]*signingKey, len(files))
+	for _, file := range files {
+		key, err := readSigningKey(file)
+		if err != nil {
+			return fmt.Errorf("读取签名密钥 %s 失败: %v", file, err)
+		}
+		keys[key.Kid] = key
+	}
+	k.keys = keys
+	return nil
+}
+
+// save 以 PKCS#8 PEM 格式写入密钥，创建时间、签发时间和算法记录在 PEM 头中
+func (k *Keyring) save(key *signingKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	block := &pem.Block{
+		Type: "PRIVATE KEY",
+		Headers: map[string]string{
+			"Kid":        key.Kid,
+			"Alg":        key.Alg,
+			"Created-At": key.CreatedAt.UTC().Format(time.RFC3339),
+			"Not-Before": key.NotBefore.UTC().Format(time.RFC3339),
+		},
+		Bytes: der,
+	}
+	return os.WriteFile(filepath.Join(k.dir, key.Kid+".pem"), pem.EncodeToMemory(block), 0o600)
+}
+
+// prune 删除已停止签发且超过保留期的密钥（此时用它签发的令牌均已过期）；
+// 保留期从后继密钥开始签发算起，密钥因停机等原因晚于轮换周期停止签发时也不会提前删除
+func (k *Keyring) prune(now time.Time) {
+	for kid, key := range k.keys {
+		var successor *signingKey
+		for _, other := range k.keys {
+			if other.NotBefore.After(key.NotBefore) && (successor == nil || other.NotBefore.Before(successor.NotBefore)) {
+				successor = other
+			}
+		}
+		if successor != nil && now.Sub(successor.NotBefore) > k.retain {
+			delete(k.keys, kid)
+			os.Remove(filepath.Join(k.dir, kid+".pem"))
+		}
+	}
+}
+
+// signingKey 返回当前签发密钥
+func (k *Keyring) signingKey() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current(time.Now())
+}
+
+// verificationKey 根据令牌头中的 kid 查找验签公钥
+func (k *Keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %q", kid)
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, errors.New("signing method mismatch")
+	}
+	return key.Private.Public(), nil
+}
+
+// JWK 单个公钥（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // OKP 公钥
+}
+
+// JWKSet 公钥集合
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回所有可用于验签的公钥（按创建时间倒序，HS256 模式下为空集合）
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if keyring == nil {
+		return set
+	}
+	keyring.mu.RLock()
+	keys := make([]*signingKey, 0, len(keyring.keys))
+	for _, key := range keyring.keys {
+		keys = append(keys, key)
+	}
+	keyring.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+
+	for _, key := range keys {
+		jwk := JWK{Kid: key.Kid, Use: "sig", Alg: key.Alg}
+		switch pub := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// ParseJWK 将 JWK 转换为验签公钥（用于校验其他服务签发的令牌）
+func ParseJWK(jwk JWK) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+}
+
+// generateSigningKey 生成指定算法的新密钥
+func generateSigningKey(alg string) (*signingKey, error) {
+	var (
+		priv crypto.Signer
+		err  error
+	)
+	switch alg {
+	case "RS256":
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing method: %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	kid, err := keyID(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &signingKey{Kid: kid, Alg: alg, Private: priv, CreatedAt: now, NotBefore: now}, nil
+}
+
+// readSigningKey 读取单个 PEM 密钥文件
+func readSigningKey(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key")
+	}
+	createdAt, err := time.Parse(time.RFC3339, block.Headers["Created-At"])
+	if err != nil {
+		return nil, err
+	}
+	// 旧版本写入的密钥没有 Not-Before，创建后即签发
+	notBefore := createdAt
+	if v := block.Headers["Not-Before"]; v != "" {
+		if notBefore, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, err
+		}
+	}
+	kid := block.Headers["Kid"]
+	if kid == "" {
+		kid = strings.TrimSuffix(filepath.Base(file), ".pem")
+	}
+	alg := block.Headers["Alg"]
+	if alg == "" {
+		switch priv.(type) {
+		case *rsa.PrivateKey:
+			alg = "RS256"
+		case ed25519.PrivateKey:
+			alg = "EdDSA"
+		}
+	}
+	return &signingKey{Kid: kid, Alg: alg, Private: priv, CreatedAt: createdAt, NotBefore: notBefore}, nil
+}
+
+// keyID 用公钥 DER 的 SHA-256 摘要生成 kid
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
@@ -0,0 +1,198 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeyring(t *testing.T, alg string) *Keyring {
+	t.Helper()
+	return &Keyring{
+		dir:     t.TempDir(),
+		alg:     alg,
+		rotate:  30 * 24 * time.Hour,
+		retain:  24 * time.Hour,
+		publish: 15 * time.Minute,
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	k := newTestKeyring(t, "EdDSA")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := k.rotateAt(t0); err != nil {
+		t.Fatal(err)
+	}
+	first := k.current(t0)
+	if first == nil || len(k.keys) != 1 {
+		t.Fatalf("bootstrap: keys = %d, want 1 active key", len(k.keys))
+	}
+
+	steps := []struct {
+		name    string
+		at      time.Time
+		keys    int
+		current string // first/second
+	}{
+		{"before publish", t0.Add(k.rotate - k.publish - time.Second), 1, "first"},
+		{"published", t0.Add(k.rotate - k.publish), 2, "first"},
+		{"still published", t0.Add(k.rotate - time.Second), 2, "first"},
+		{"rotated", t0.Add(k.rotate), 2, "second"},
+		{"retained", t0.Add(k.rotate + k.retain), 2, "second"},
+		{"pruned", t0.Add(k.rotate + k.retain + time.Second), 1, "second"},
+	}
+	var second *signingKey
+	for _, step := range steps {
+		if err := k.rotateAt(step.at); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if len(k.keys) != step.keys {
+			t.Fatalf("%s: keys = %d, want %d", step.name, len(k.keys), step.keys)
+		}
+		for _, key := range k.keys {
+			if key.Kid != first.Kid {
+				second = key
+			}
+		}
+		want := first
+		if step.current == "second" {
+			want = second
+		}
+		if got := k.current(step.at); got == nil || got.Kid != want.Kid {
+			t.Fatalf("%s: current key is not the %s key", step.name, step.current)
+		}
+	}
+	if !second.NotBefore.Equal(t0.Add(k.rotate)) {
+		t.Fatalf("second key not before = %v, want %v", second.NotBefore, t0.Add(k.rotate))
+	}
+	if _, err := os.Stat(filepath.Join(k.dir, first.Kid+".pem")); !os.IsNotExist(err) {
+		t.Fatalf("pruned key file still exists: %v", err)
+	}
+}
+
+func TestKeyringPruneAfterLateRotation(t *testing.T) {
+	k := newTestKeyring(t, "EdDSA")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := k.rotateAt(t0); err != nil {
+		t.Fatal(err)
+	}
+	first := k.current(t0)
+
+	// 停机三个轮换周期后恢复：旧密钥继续签发到新密钥发布期结束
+	restart := t0.Add(3 * k.rotate)
+	if err := k.rotateAt(restart); err != nil {
+		t.Fatal(err)
+	}
+	if got := k.current(restart); got.Kid != first.Kid {
+		t.Fatal("old key should keep signing until the new key is published")
+	}
+	switchAt := restart.Add(k.publish)
+	for _, at := range []time.Time{switchAt, switchAt.Add(k.retain)} {
+		if err := k.rotateAt(at); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := k.keys[first.Kid]; !ok {
+			t.Fatalf("old key pruned at %v, before tokens it signed expired", at)
+		}
+	}
+	if err := k.rotateAt(switchAt.Add(k.retain + time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := k.keys[first.Kid]; ok {
+		t.Fatal("old key not pruned after retention")
+	}
+}
+
+func TestKeyringReloadFromDir(t *testing.T) {
+	k := newTestKeyring(t, "EdDSA")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := k.rotateAt(t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.rotateAt(t0.Add(k.rotate - k.publish)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 共享目录的另一个实例加载后得到相同的签发密钥和待签发密钥
+	peer := &Keyring{dir: k.dir, alg: k.alg, rotate: k.rotate, retain: k.retain, publish: k.publish}
+	if err := peer.rotateAt(t0.Add(k.rotate - k.publish + time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(peer.keys) != 2 {
+		t.Fatalf("peer keys = %d, want 2", len(peer.keys))
+	}
+	for _, at := range []time.Time{t0, t0.Add(k.rotate)} {
+		if peer.current(at).Kid != k.current(at).Kid {
+			t.Fatalf("peer signs with a different key at %v", at)
+		}
+	}
+}
+
+func TestKeyringUnknownKid(t *testing.T) {
+	k := newTestKeyring(t, "EdDSA")
+	if err := k.rotateAt(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := generateSigningKey("EdDSA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func(key *signingKey) string {
+		token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), jwt.MapClaims{"sub": "1"})
+		token.Header["kid"] = key.Kid
+		s, err := token.SignedString(key.Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	if _, err := jwt.Parse(signed(k.signingKey()), k.verificationKey); err != nil {
+		t.Fatalf("own token: %v", err)
+	}
+	_, err = jwt.Parse(signed(foreign), k.verificationKey)
+	if err == nil || !strings.Contains(err.Error(), "unknown kid") {
+		t.Fatalf("foreign token: err = %v, want unknown kid", err)
+	}
+}
+
+func TestJWKSRoundTrip(t *testing.T) {
+	defer func(saved *Keyring) { keyring = saved }(keyring)
+
+	for _, alg := range []string{"RS256", "EdDSA"} {
+		k := newTestKeyring(t, alg)
+		t0 := time.Now().Add(-k.rotate)
+		if err := k.rotateAt(t0); err != nil {
+			t.Fatal(err)
+		}
+		if err := k.rotateAt(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		keyring = k
+
+		set := JWKS()
+		if len(set.Keys) != 2 {
+			t.Fatalf("%s: jwks keys = %d, want 2 (active and published)", alg, len(set.Keys))
+		}
+		for _, jwk := range set.Keys {
+			pub, err := ParseJWK(jwk)
+			if err != nil {
+				t.Fatalf("%s: parse jwk: %v", alg, err)
+			}
+			key := k.keys[jwk.Kid]
+			token := jwt.NewWithClaims(jwt.GetSigningMethod(alg), jwt.MapClaims{"sub": "1"})
+			signed, err := token.SignedString(key.Private)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return pub, nil },
+				jwt.WithValidMethods([]string{alg})); err != nil {
+				t.Fatalf("%s: verify with jwk %s: %v", alg, jwk.Kid, err)
+			}
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package util
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // HashPassword 加密密码（bcrypt算法）
 func HashPassword(password string) (string, error) {
@@ -12,3 +17,9 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(hash, password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
+
+// HashToken 计算一次性令牌的摘要（数据库只保存摘要，令牌本身只下发一次）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,13 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RandomHex 生成 n 字节随机数的十六进制字符串（用于 state、临时密码等）
+func RandomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}